@@ -0,0 +1,86 @@
+// Package randomx - bench.go defines the self-test and benchmark results
+// shared by randomx-bench and the workers that tune from its report
+package randomx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// BenchConfig configures one Benchmark run. The cache (and dataset in full
+// mode) is built once and shared by every flag and thread combination.
+type BenchConfig struct {
+	Key            []byte        // Seed to initialize (default "randomx-bench")
+	FullDataset    bool          // Hash on the ~2 GB dataset instead of light mode
+	LargePages     bool          // Request large pages, falling back if unavailable
+	DatasetThreads int           // Dataset init threads (0 = all CPUs)
+	VMFlags        []Flag        // VM flag combinations to try (FlagJIT, FlagHardAES, ...)
+	Threads        []int         // Thread counts to try
+	Duration       time.Duration // Hashing time per combination
+	// OnResult is called as each combination finishes
+	OnResult func(result BenchResult)
+}
+
+// BenchResult is the measured hashrate of one configuration
+type BenchResult struct {
+	Mode       string        `json:"mode"`  // "light" or "full"
+	Flags      Flag          `json:"flags"` // Effective VM flags
+	FlagNames  string        `json:"flag_names"`
+	Threads    int           `json:"threads"`
+	LargePages bool          `json:"large_pages"` // Large pages were obtained
+	SetupTime  time.Duration `json:"setup_ns"`    // Cache (and dataset) init time
+	Hashes     uint64        `json:"hashes"`
+	Duration   time.Duration `json:"duration_ns"`
+	Hashrate   float64       `json:"hashrate"` // H/s
+	Error      string        `json:"error,omitempty"`
+}
+
+// BenchReport is the output of randomx-bench
+type BenchReport struct {
+	Time             time.Time     `json:"time"`
+	OS               string        `json:"os"`
+	Arch             string        `json:"arch"`
+	CPUs             int           `json:"cpus"`
+	RecommendedFlags Flag          `json:"recommended_flags"` // GetFlags() on this CPU
+	SelfTest         string        `json:"self_test"`         // "ok" or the first failure
+	Results          []BenchResult `json:"results"`
+}
+
+// Best returns the fastest successful result for the given mode
+func (r *BenchReport) Best(fullDataset bool) (BenchResult, bool) {
+	mode := BenchMode(fullDataset)
+	var best BenchResult
+	found := false
+	for _, res := range r.Results {
+		if res.Mode != mode || res.Error != "" {
+			continue
+		}
+		if !found || res.Hashrate > best.Hashrate {
+			best, found = res, true
+		}
+	}
+	return best, found
+}
+
+// LoadBenchReport reads a JSON report written by randomx-bench
+func LoadBenchReport(path string) (*BenchReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report BenchReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parse bench report: %w", err)
+	}
+	return &report, nil
+}
+
+// BenchMode names the hashing mode in a BenchResult
+func BenchMode(fullDataset bool) string {
+	if fullDataset {
+		return "full"
+	}
+	return "light"
+}
@@ -0,0 +1,204 @@
+//go:build cgo
+
+// Package randomx - bench_cgo.go runs the self-test and benchmark on the C
+// library
+package randomx
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// referenceVectors are the light-mode test vectors from the RandomX
+// reference implementation
+var referenceVectors = []struct {
+	key   string
+	input string
+	hash  string
+}{
+	{
+		key:   "test key 000",
+		input: "This is a test",
+		hash:  "639183aae1bf4c9a35884cb46b09cad9175f04efd7684e7262a0ac1c2f0b4e3f",
+	},
+	{
+		key:   "test key 000",
+		input: "Lorem ipsum dolor sit amet",
+		hash:  "300a0adb47603dedb42228ccb2b211104f4da45af709cd7547cd049e9489c969",
+	},
+	{
+		key:   "test key 000",
+		input: "sed do eiusmod tempor incididunt ut labore et dolore magna aliqua",
+		hash:  "c36d4ed4191e617309867ed66a443be4075014e2b061bcdaf9ce7b721d2b77a8",
+	},
+}
+
+// SelfTest checks that hashing with flags reproduces the reference vectors.
+// Run it for every flag combination a miner will use: a JIT or AES code path
+// that miscompiles on a CPU produces wrong hashes, not errors.
+func SelfTest(flags Flag) error {
+	flags &^= FlagFullMem
+	cache, err := NewCache(flags)
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	key := ""
+	var vm *VM
+	for _, tv := range referenceVectors {
+		if tv.key != key {
+			if vm != nil {
+				vm.Close()
+				vm = nil
+			}
+			if err := cache.Init([]byte(tv.key)); err != nil {
+				return err
+			}
+			key = tv.key
+		}
+		if vm == nil {
+			if vm, err = NewVM(cache, nil, flags); err != nil {
+				return err
+			}
+		}
+		hash := vm.CalculateHash([]byte(tv.input))
+		if got := hex.EncodeToString(hash[:]); got != tv.hash {
+			vm.Close()
+			return fmt.Errorf("flags %s: hash of %q = %s, want %s", flags, tv.input, got, tv.hash)
+		}
+	}
+	if vm != nil {
+		vm.Close()
+	}
+	return nil
+}
+
+// Benchmark measures the hashrate of every VMFlags and Threads combination
+// on one cache (and dataset). Combinations whose VMs cannot be created are
+// reported with Error set; only failing to build the memory is an error.
+func Benchmark(cfg BenchConfig) ([]BenchResult, error) {
+	if len(cfg.Key) == 0 {
+		cfg.Key = []byte("randomx-bench")
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+
+	var memFlags Flag
+	if cfg.LargePages {
+		memFlags |= FlagLargePages
+	}
+
+	start := time.Now()
+	cache, err := NewCache(memFlags | GetFlags()&FlagJIT)
+	if err != nil {
+		return nil, err
+	}
+	defer cache.Close()
+	if err := cache.Init(cfg.Key); err != nil {
+		return nil, err
+	}
+	largePages := cache.LargePages()
+
+	var dataset *Dataset
+	if cfg.FullDataset {
+		if dataset, err = NewDataset(memFlags); err != nil {
+			return nil, err
+		}
+		defer dataset.Close()
+		if err := dataset.Init(cache, cfg.DatasetThreads); err != nil {
+			return nil, err
+		}
+		largePages = largePages && dataset.LargePages()
+	}
+	setup := time.Since(start)
+
+	var results []BenchResult
+	for _, flags := range cfg.VMFlags {
+		// VM scratchpads use large pages only if the cache got them
+		flags &^= FlagLargePages
+		if largePages {
+			flags |= FlagLargePages
+		}
+		if cfg.FullDataset {
+			flags |= FlagFullMem
+		}
+		for _, threads := range cfg.Threads {
+			res := BenchResult{
+				Mode:       BenchMode(cfg.FullDataset),
+				Flags:      flags,
+				FlagNames:  flags.String(),
+				Threads:    threads,
+				LargePages: largePages,
+				SetupTime:  setup,
+			}
+			res.Hashes, res.Duration, err = benchHash(cache, dataset, flags, threads, cfg.Duration)
+			if err != nil {
+				res.Error = err.Error()
+			} else if res.Duration > 0 {
+				res.Hashrate = float64(res.Hashes) / res.Duration.Seconds()
+			}
+			if cfg.OnResult != nil {
+				cfg.OnResult(res)
+			}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// benchHash runs threads pipelined VMs for d and counts the hashes. Like
+// the miner it hashes an 80-byte header, bumping the nonce in place.
+func benchHash(cache *Cache, dataset *Dataset, flags Flag, threads int, d time.Duration) (uint64, time.Duration, error) {
+	if threads <= 0 {
+		threads = 1
+	}
+
+	// Create every VM before starting the clock
+	vms := make([]*VM, 0, threads)
+	defer func() {
+		for _, vm := range vms {
+			vm.Close()
+		}
+	}()
+	for i := 0; i < threads; i++ {
+		vm, err := NewVM(cache, dataset, flags)
+		if err != nil {
+			return 0, 0, err
+		}
+		vms = append(vms, vm)
+	}
+
+	var (
+		hashes atomic.Uint64
+		stop   atomic.Bool
+		wg     sync.WaitGroup
+	)
+	start := time.Now()
+	for i, vm := range vms {
+		wg.Add(1)
+		go func(id int, vm *VM) {
+			defer wg.Done()
+			input := make([]byte, 80)
+			input[0] = byte(id)
+			vm.CalculateHashFirst(input)
+			var n uint64
+			for nonce := uint32(1); !stop.Load(); nonce++ {
+				binary.LittleEndian.PutUint32(input[76:], nonce)
+				vm.CalculateHashNext(input)
+				n++
+			}
+			vm.CalculateHashLast()
+			hashes.Add(n + 1)
+		}(i, vm)
+	}
+	time.Sleep(d)
+	stop.Store(true)
+	wg.Wait()
+	return hashes.Load(), time.Since(start), nil
+}
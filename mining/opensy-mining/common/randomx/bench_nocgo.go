@@ -0,0 +1,20 @@
+//go:build !cgo
+
+// Package randomx - bench_nocgo.go stubs the self-test and benchmark in
+// builds without cgo
+package randomx
+
+// GetFlags reports no CPU features without the C library
+func GetFlags() Flag {
+	return FlagDefault
+}
+
+// SelfTest fails with ErrUnavailable without the C library
+func SelfTest(Flag) error {
+	return ErrUnavailable
+}
+
+// Benchmark fails with ErrUnavailable without the C library
+func Benchmark(BenchConfig) ([]BenchResult, error) {
+	return nil, ErrUnavailable
+}
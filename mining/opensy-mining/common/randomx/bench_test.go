@@ -0,0 +1,51 @@
+package randomx
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBenchReportBest(t *testing.T) {
+	report := BenchReport{
+		SelfTest: "ok",
+		Results: []BenchResult{
+			{Mode: "light", Threads: 1, Hashrate: 100},
+			{Mode: "light", Threads: 4, Hashrate: 350},
+			{Mode: "light", Threads: 8, Error: "failed to create VM"},
+			{Mode: "full", Threads: 4, Hashrate: 2000},
+		},
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "bench.json")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBenchReport(path)
+	if err != nil {
+		t.Fatalf("LoadBenchReport: %v", err)
+	}
+	if best, ok := loaded.Best(false); !ok || best.Threads != 4 || best.Hashrate != 350 {
+		t.Errorf("Best(light) = %+v, %v; want 4 threads at 350 H/s", best, ok)
+	}
+	if best, ok := loaded.Best(true); !ok || best.Hashrate != 2000 {
+		t.Errorf("Best(full) = %+v, %v; want 2000 H/s", best, ok)
+	}
+	if _, ok := (&BenchReport{}).Best(false); ok {
+		t.Error("Best on an empty report should find nothing")
+	}
+}
+
+func TestFlagString(t *testing.T) {
+	if got := FlagDefault.String(); got != "default" {
+		t.Errorf("FlagDefault.String() = %q", got)
+	}
+	if got := (FlagJIT | FlagHardAES).String(); got != "hard-aes|jit" {
+		t.Errorf("String() = %q, want hard-aes|jit", got)
+	}
+}
@@ -0,0 +1,331 @@
+//go:build cgo
+
+// Package randomx - cache.go holds reference-counted caches and datasets
+package randomx
+
+/*
+#include <randomx.h>
+*/
+import "C"
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// CacheSize is the size of a RandomX cache in bytes.
+const CacheSize = 256 << 20
+
+// DatasetItemSize is the size of one dataset item in bytes.
+const DatasetItemSize = 64
+
+// ErrInUse is returned when re-initializing a Cache or Dataset that VMs or
+// datasets still reference.
+var ErrInUse = errors.New("randomx: memory still referenced")
+
+// ErrSharedDataset is returned when re-initializing a dataset mapped from a
+// shared file; other processes are reading it.
+var ErrSharedDataset = errors.New("randomx: shared dataset is read-only")
+
+// refCount frees its memory when the last reference is released. The owner
+// holds one reference until Close; every VM or Dataset built on the memory
+// holds another.
+type refCount struct {
+	refs   atomic.Int32
+	closed atomic.Bool
+}
+
+// retain adds a reference, failing if the memory was already freed
+func (r *refCount) retain() bool {
+	for {
+		n := r.refs.Load()
+		if n <= 0 {
+			return false
+		}
+		if r.refs.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// release drops a reference and reports whether it was the last one
+func (r *refCount) release() bool {
+	return r.refs.Add(-1) == 0
+}
+
+// closeOwner drops the owner's reference once; it reports whether that
+// freed the memory
+func (r *refCount) closeOwner() bool {
+	if !r.closed.CompareAndSwap(false, true) {
+		return false
+	}
+	return r.release()
+}
+
+// Cache is a RandomX cache (~256 MB) for one key. VMs and datasets built
+// from it keep it alive, so Close is safe while they are still in use; the
+// memory is freed when the last of them is closed.
+type Cache struct {
+	refCount
+	cache *C.randomx_cache
+	flags Flag
+	key   []byte
+	mu    sync.Mutex
+}
+
+// NewCache allocates a cache. Call Init before building VMs on it. With
+// FlagLargePages it falls back to normal pages if large pages are not
+// available; check LargePages for what was obtained.
+func NewCache(flags Flag) (*Cache, error) {
+	cache := C.randomx_alloc_cache(C.randomx_flags(flags))
+	if cache == nil && flags&FlagLargePages != 0 {
+		flags &^= FlagLargePages
+		cache = C.randomx_alloc_cache(C.randomx_flags(flags))
+	}
+	if cache == nil {
+		return nil, ErrCacheAllocation
+	}
+	c := &Cache{cache: cache, flags: flags}
+	c.refs.Store(1)
+	return c, nil
+}
+
+// Init initializes the cache with key. It fails with ErrInUse while any VM
+// or Dataset references the cache, since they would see it change.
+func (c *Cache) Init(key []byte) error {
+	if len(key) == 0 {
+		return ErrInvalidKey
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.refs.Load() != 1 || c.closed.Load() {
+		return ErrInUse
+	}
+	C.randomx_init_cache(c.cache, unsafe.Pointer(&key[0]), C.size_t(len(key)))
+	c.key = append([]byte(nil), key...)
+	return nil
+}
+
+// Key returns a copy of the key the cache was initialized with.
+func (c *Cache) Key() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]byte(nil), c.key...)
+}
+
+// Flags returns the flags the cache was allocated with, without
+// FlagLargePages if the allocation fell back to normal pages.
+func (c *Cache) Flags() Flag {
+	return c.flags
+}
+
+// LargePages reports whether the cache is backed by large pages.
+func (c *Cache) LargePages() bool {
+	return c.flags&FlagLargePages != 0
+}
+
+// Close releases the caller's reference.
+func (c *Cache) Close() {
+	if c.closeOwner() {
+		c.free()
+	}
+}
+
+func (c *Cache) unref() {
+	if c.release() {
+		c.free()
+	}
+}
+
+func (c *Cache) free() {
+	C.randomx_release_cache(c.cache)
+	c.cache = nil
+}
+
+// Dataset is the full RandomX dataset (~2 GB), reference counted like Cache.
+type Dataset struct {
+	refCount
+	dataset *C.randomx_dataset
+	flags   Flag
+	shared  *sharedFile // Set for datasets mapped by OpenSharedDataset
+	mu      sync.Mutex
+}
+
+// NewDataset allocates a dataset. Call Init before building VMs on it.
+// Like NewCache it falls back to normal pages when large pages fail.
+func NewDataset(flags Flag) (*Dataset, error) {
+	flags |= FlagFullMem
+	dataset := C.randomx_alloc_dataset(C.randomx_flags(flags))
+	if dataset == nil && flags&FlagLargePages != 0 {
+		flags &^= FlagLargePages
+		dataset = C.randomx_alloc_dataset(C.randomx_flags(flags))
+	}
+	if dataset == nil {
+		return nil, ErrDatasetAllocation
+	}
+	d := &Dataset{dataset: dataset, flags: flags}
+	d.refs.Store(1)
+	return d, nil
+}
+
+// Init fills the dataset from cache using numThreads goroutines (0 = all
+// CPUs). Like Cache.Init it fails with ErrInUse while VMs reference the
+// dataset. The cache is only needed during Init.
+func (d *Dataset) Init(cache *Cache, numThreads int) error {
+	if !cache.retain() {
+		return ErrNotInitialized
+	}
+	defer cache.unref()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.shared != nil {
+		return ErrSharedDataset
+	}
+	if d.refs.Load() != 1 || d.closed.Load() {
+		return ErrInUse
+	}
+	initDatasetItems(d.dataset, cache, numThreads)
+	return nil
+}
+
+// initDatasetItems fills dataset from cache, splitting the items across
+// numThreads goroutines (0 = all CPUs)
+func initDatasetItems(dataset *C.randomx_dataset, cache *Cache, numThreads int) {
+	if numThreads <= 0 {
+		numThreads = runtime.NumCPU()
+	}
+
+	itemCount := uint64(C.randomx_dataset_item_count())
+	itemsPerThread := itemCount / uint64(numThreads)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numThreads; i++ {
+		start := uint64(i) * itemsPerThread
+		count := itemsPerThread
+		if i == numThreads-1 {
+			// Last thread handles remaining items
+			count = itemCount - start
+		}
+
+		wg.Add(1)
+		go func(start, count uint64) {
+			defer wg.Done()
+			C.randomx_init_dataset(dataset, cache.cache, C.ulong(start), C.ulong(count))
+		}(start, count)
+	}
+	wg.Wait()
+}
+
+// LargePages reports whether the dataset is backed by large pages.
+func (d *Dataset) LargePages() bool {
+	return d.flags&FlagLargePages != 0
+}
+
+// Size returns the dataset size in bytes.
+func (d *Dataset) Size() uint64 {
+	return uint64(C.randomx_dataset_item_count()) * DatasetItemSize
+}
+
+// Close releases the caller's reference.
+func (d *Dataset) Close() {
+	if d.closeOwner() {
+		d.free()
+	}
+}
+
+func (d *Dataset) unref() {
+	if d.release() {
+		d.free()
+	}
+}
+
+func (d *Dataset) free() {
+	if d.shared != nil {
+		d.shared.close(d.dataset)
+	} else {
+		C.randomx_release_dataset(d.dataset)
+	}
+	d.dataset = nil
+}
+
+// Shared reports whether the dataset is mapped from a file shared with
+// other processes.
+func (d *Dataset) Shared() bool {
+	return d.shared != nil
+}
+
+// NewVM creates a VM on cache and, for full-memory mode, dataset (nil for
+// light mode). The VM holds a reference to both until it is closed.
+func NewVM(cache *Cache, dataset *Dataset, flags Flag) (*VM, error) {
+	if cache == nil && dataset == nil {
+		return nil, ErrNotInitialized
+	}
+
+	var cCache *C.randomx_cache
+	if cache != nil {
+		if !cache.retain() {
+			return nil, ErrNotInitialized
+		}
+		cCache = cache.cache
+	}
+	var cDataset *C.randomx_dataset
+	if dataset != nil {
+		if !dataset.retain() {
+			if cache != nil {
+				cache.unref()
+			}
+			return nil, ErrNotInitialized
+		}
+		cDataset = dataset.dataset
+		flags |= FlagFullMem
+	}
+
+	vm := C.randomx_create_vm(C.randomx_flags(flags), cCache, cDataset)
+	if vm == nil && flags&FlagLargePages != 0 {
+		// The scratchpad could not get large pages
+		vm = C.randomx_create_vm(C.randomx_flags(flags&^FlagLargePages), cCache, cDataset)
+	}
+	if vm == nil {
+		if cache != nil {
+			cache.unref()
+		}
+		if dataset != nil {
+			dataset.unref()
+		}
+		return nil, ErrVMCreation
+	}
+	return &VM{vm: vm, cache: cache, dataset: dataset}, nil
+}
+
+// SetCache switches a light-mode VM to another cache, e.g. after a key
+// change, without recreating it. The old cache's reference is released.
+func (v *VM) SetCache(cache *Cache) error {
+	if !cache.retain() {
+		return ErrNotInitialized
+	}
+	C.randomx_vm_set_cache(v.vm, cache.cache)
+	if v.cache != nil {
+		v.cache.unref()
+	}
+	v.cache = cache
+	return nil
+}
+
+// SetDataset switches a full-memory VM to another dataset. The old
+// dataset's reference is released.
+func (v *VM) SetDataset(dataset *Dataset) error {
+	if !dataset.retain() {
+		return ErrNotInitialized
+	}
+	C.randomx_vm_set_dataset(v.vm, dataset.dataset)
+	if v.dataset != nil {
+		v.dataset.unref()
+	}
+	v.dataset = dataset
+	return nil
+}
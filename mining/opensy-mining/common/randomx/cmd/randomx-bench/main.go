@@ -0,0 +1,200 @@
+// randomx-bench checks that this build's RandomX flags produce correct
+// hashes, then measures the hashrate of each flag and thread combination.
+// The JSON report can be passed to the CoopMine worker with -bench-report.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+)
+
+func main() {
+	var (
+		modes          = flag.String("modes", "light,full", "Modes to benchmark: light, full or both (full needs ~2.3 GB)")
+		threadList     = flag.String("threads", "", "Comma-separated thread counts (default: powers of two up to the CPU count)")
+		duration       = flag.Duration("duration", 10*time.Second, "Hashing time per configuration")
+		largePages     = flag.String("large-pages", "both", "Large pages: on, off or both")
+		datasetThreads = flag.Int("dataset-threads", 0, "Dataset init threads (0 = all CPUs)")
+		format         = flag.String("format", "table", "Output format: table or json")
+		output         = flag.String("o", "", "Write the report to this file instead of stdout")
+		selfTestOnly   = flag.Bool("self-test", false, "Only run the self-test")
+	)
+	flag.Parse()
+
+	threads, err := parseThreads(*threadList)
+	if err != nil {
+		fatalf("invalid -threads: %v", err)
+	}
+	var pageModes []bool
+	switch *largePages {
+	case "on":
+		pageModes = []bool{true}
+	case "off":
+		pageModes = []bool{false}
+	case "both":
+		pageModes = []bool{false, true}
+	default:
+		fatalf("invalid -large-pages %q", *largePages)
+	}
+	var fullModes []bool
+	for _, m := range strings.Split(*modes, ",") {
+		switch strings.TrimSpace(m) {
+		case "light":
+			fullModes = append(fullModes, false)
+		case "full":
+			fullModes = append(fullModes, true)
+		default:
+			fatalf("invalid mode %q", m)
+		}
+	}
+
+	report := randomx.BenchReport{
+		Time:             time.Now().UTC(),
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		CPUs:             runtime.NumCPU(),
+		RecommendedFlags: randomx.GetFlags(),
+	}
+	vmFlags := flagCombinations(report.RecommendedFlags)
+
+	// A wrong hash means the build or CPU is broken; there is no point
+	// measuring how fast it is
+	report.SelfTest = "ok"
+	for _, flags := range vmFlags {
+		if err := randomx.SelfTest(flags); err != nil {
+			report.SelfTest = err.Error()
+			fmt.Fprintf(os.Stderr, "Self-test FAILED: %v\n", err)
+			writeReport(&report, *format, *output)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Self-test ok: %s\n", flags)
+	}
+	if *selfTestOnly {
+		return
+	}
+
+	for _, full := range fullModes {
+		for _, lp := range pageModes {
+			fmt.Fprintf(os.Stderr, "Initializing %s mode (large pages: %v)...\n", randomx.BenchMode(full), lp)
+			results, err := randomx.Benchmark(randomx.BenchConfig{
+				FullDataset:    full,
+				LargePages:     lp,
+				DatasetThreads: *datasetThreads,
+				VMFlags:        vmFlags,
+				Threads:        threads,
+				Duration:       *duration,
+				OnResult: func(r randomx.BenchResult) {
+					fmt.Fprintf(os.Stderr, "  %-5s %-32s threads=%-3d %s\n", r.Mode, r.FlagNames, r.Threads, formatResult(r))
+				},
+			})
+			if err != nil {
+				// Typically not enough memory for the dataset
+				fmt.Fprintf(os.Stderr, "  skipped: %v\n", err)
+				continue
+			}
+			report.Results = append(report.Results, results...)
+		}
+	}
+
+	writeReport(&report, *format, *output)
+}
+
+// flagCombinations returns every subset of the JIT and hardware AES flags
+// this CPU supports, fastest candidates first
+func flagCombinations(recommended randomx.Flag) []randomx.Flag {
+	supported := recommended & (randomx.FlagJIT | randomx.FlagHardAES)
+	combos := []randomx.Flag{supported}
+	for _, f := range []randomx.Flag{randomx.FlagJIT, randomx.FlagHardAES, randomx.FlagJIT | randomx.FlagHardAES} {
+		if f&supported == f && f != supported {
+			combos = append(combos, f)
+		}
+	}
+	if supported != randomx.FlagDefault {
+		combos = append(combos, randomx.FlagDefault)
+	}
+	return combos
+}
+
+// parseThreads parses a thread list, defaulting to 1, 2, 4, ... and the
+// CPU count
+func parseThreads(s string) ([]int, error) {
+	if s == "" {
+		cpus := runtime.NumCPU()
+		var threads []int
+		for n := 1; n < cpus; n *= 2 {
+			threads = append(threads, n)
+		}
+		return append(threads, cpus), nil
+	}
+	var threads []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bad thread count %q", part)
+		}
+		threads = append(threads, n)
+	}
+	sort.Ints(threads)
+	return threads, nil
+}
+
+func writeReport(report *randomx.BenchReport, format, path string) {
+	out := os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			fatalf("create report: %v", err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fatalf("write report: %v", err)
+		}
+		return
+	}
+
+	fmt.Fprintf(out, "RandomX benchmark: %s/%s, %d CPUs, recommended flags %s, self-test %s\n\n",
+		report.OS, report.Arch, report.CPUs, report.RecommendedFlags, report.SelfTest)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODE\tFLAGS\tLARGE PAGES\tTHREADS\tSETUP\tHASHRATE")
+	for _, r := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%v\t%d\t%s\t%s\n",
+			r.Mode, r.FlagNames, r.LargePages, r.Threads, r.SetupTime.Round(time.Millisecond), formatResult(r))
+	}
+	tw.Flush()
+
+	for _, full := range []bool{false, true} {
+		if best, ok := report.Best(full); ok {
+			fmt.Fprintf(out, "\nBest %s: %s, %d threads, large pages %v (%.1f H/s)",
+				best.Mode, best.FlagNames, best.Threads, best.LargePages, best.Hashrate)
+		}
+	}
+	fmt.Fprintln(out)
+}
+
+func formatResult(r randomx.BenchResult) string {
+	if r.Error != "" {
+		return "error: " + r.Error
+	}
+	return fmt.Sprintf("%.1f H/s", r.Hashrate)
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,92 @@
+// Package randomx - hasher.go defines the hashing interfaces used by the pool
+// and CoopMine, so they build and test without the C library
+package randomx
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrPoolClosed is returned when hashing on a closed Hasher or VMPool.
+var ErrPoolClosed = errors.New("randomx: VM pool closed")
+
+// ErrUnavailable is returned by DefaultHasherFactory in builds without cgo.
+var ErrUnavailable = errors.New("randomx: not available, built without cgo")
+
+// Hasher computes RandomX hashes for one seed. It is safe for concurrent
+// use; at most HasherConfig.VMs hashes run at once.
+type Hasher interface {
+	// Hash computes the hash of input, waiting while every VM is busy
+	Hash(ctx context.Context, input []byte) ([HashSize]byte, error)
+	// Pipeline borrows a VM for pipelined hashing until the Pipeline is
+	// closed; Close waits for outstanding pipelines.
+	Pipeline(ctx context.Context) (Pipeline, error)
+	// Stats returns a snapshot of VM usage
+	Stats() VMPoolStats
+	// Allocation reports the memory actually obtained for the seed
+	Allocation() AllocationInfo
+	// Close waits for in-flight hashes and releases the hasher
+	Close()
+}
+
+// Pipeline hashes a stream of inputs on one VM, overlapping each hash with
+// the next input's setup as RandomX's calculate_hash_first/next/last do.
+// Inputs are consumed when passed, so callers may reuse the buffer. A
+// Pipeline is not safe for concurrent use.
+type Pipeline interface {
+	// First starts the pipeline with input
+	First(input []byte)
+	// Next queues input and returns the hash of the previous one
+	Next(input []byte) [HashSize]byte
+	// Last returns the hash of the final queued input
+	Last() [HashSize]byte
+	// Close returns the VM; the pipeline must not be used afterwards
+	Close()
+}
+
+// HasherConfig configures a Hasher built by a HasherFactory
+type HasherConfig struct {
+	VMs            int  // Maximum concurrent hashes (0 = 1)
+	FullDataset    bool // Build the full ~2 GB dataset instead of light mode
+	DatasetThreads int  // Dataset init threads (0 = all CPUs)
+	Flags          Flag // FlagLargePages is tried first, then dropped if unavailable
+	// SharedDatasetDir maps the full dataset from a per-seed file in this
+	// directory (e.g. /dev/shm) shared by every process on the host instead
+	// of allocating a private copy. Empty keeps the dataset private.
+	SharedDatasetDir string
+	// OnWait is called with the time each Hash spent waiting for a VM
+	OnWait func(wait time.Duration)
+}
+
+// HasherFactory builds a Hasher for a seed. Building may take seconds (or
+// minutes for the full dataset), so callers do it off the hot path.
+type HasherFactory interface {
+	NewHasher(seed []byte, cfg HasherConfig) (Hasher, error)
+}
+
+// VMPoolStats is a snapshot of pool usage.
+type VMPoolStats struct {
+	Size     int           // Maximum number of VMs
+	Created  int           // VMs currently allocated
+	InUse    int           // VMs currently borrowed
+	Waits    uint64        // Get calls that had to wait for a VM
+	WaitTime time.Duration // Total time spent waiting
+}
+
+// AllocationInfo reports how RandomX memory was actually allocated. Large
+// pages are requested with FlagLargePages but silently fall back to normal
+// pages when the OS has none to spare (see /proc/sys/vm/nr_hugepages).
+type AllocationInfo struct {
+	Flags               Flag   // Flags the cache was allocated with
+	LargePagesRequested bool   // FlagLargePages was asked for
+	LargePages          bool   // Every allocation got large pages
+	CacheBytes          uint64 // 0 before the cache is initialized
+	DatasetBytes        uint64 // 0 in light mode
+	DatasetShared       bool   // The dataset is mapped from a file other processes share
+}
+
+// Bytes returns the total RandomX memory allocated
+func (a AllocationInfo) Bytes() uint64 {
+	return a.CacheBytes + a.DatasetBytes
+}
@@ -0,0 +1,105 @@
+//go:build cgo
+
+// Package randomx - hasher_cgo.go implements HasherFactory on the C library
+package randomx
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultHasherFactory returns the factory backed by the RandomX library
+func DefaultHasherFactory() HasherFactory {
+	return nativeFactory{}
+}
+
+type nativeFactory struct{}
+
+// NewHasher initializes a context for seed and pools VMs on it
+func (nativeFactory) NewHasher(seed []byte, cfg HasherConfig) (Hasher, error) {
+	flags := cfg.Flags
+	if cfg.FullDataset {
+		flags |= FlagFullMem
+	}
+	ctx, err := NewContext(flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create context: %w", err)
+	}
+	if err := ctx.InitCache(seed); err != nil {
+		ctx.Close()
+		return nil, fmt.Errorf("failed to init cache: %w", err)
+	}
+	if cfg.FullDataset {
+		initDataset := ctx.InitDataset
+		if cfg.SharedDatasetDir != "" {
+			initDataset = func(threads int) error {
+				return ctx.InitSharedDataset(cfg.SharedDatasetDir, threads)
+			}
+		}
+		if err := initDataset(cfg.DatasetThreads); err != nil {
+			ctx.Close()
+			return nil, fmt.Errorf("failed to init dataset: %w", err)
+		}
+	}
+
+	vms := NewVMPool(ctx, cfg.VMs)
+	vms.OnWait = cfg.OnWait
+	return &nativeHasher{ctx: ctx, vms: vms}, nil
+}
+
+// nativeHasher hashes on a VMPool over its own Context
+type nativeHasher struct {
+	ctx *Context
+	vms *VMPool
+}
+
+func (h *nativeHasher) Hash(ctx context.Context, input []byte) ([HashSize]byte, error) {
+	return h.vms.CalculateHash(ctx, input)
+}
+
+func (h *nativeHasher) Pipeline(ctx context.Context) (Pipeline, error) {
+	vm, err := h.vms.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &vmPipeline{vm: vm, pool: h.vms}, nil
+}
+
+func (h *nativeHasher) Stats() VMPoolStats {
+	return h.vms.Stats()
+}
+
+func (h *nativeHasher) Allocation() AllocationInfo {
+	return h.ctx.AllocationInfo()
+}
+
+// Close waits for borrowed VMs, then releases the context
+func (h *nativeHasher) Close() {
+	h.vms.Close()
+	h.ctx.Close()
+}
+
+// vmPipeline runs the batch API on a VM borrowed from a VMPool
+type vmPipeline struct {
+	vm   *VM
+	pool *VMPool
+}
+
+func (p *vmPipeline) First(input []byte) {
+	p.vm.CalculateHashFirst(input)
+}
+
+func (p *vmPipeline) Next(input []byte) [HashSize]byte {
+	return p.vm.CalculateHashNext(input)
+}
+
+func (p *vmPipeline) Last() [HashSize]byte {
+	return p.vm.CalculateHashLast()
+}
+
+func (p *vmPipeline) Close() {
+	if p.vm != nil {
+		p.pool.Put(p.vm)
+		p.vm = nil
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !cgo
+
+// Package randomx - hasher_nocgo.go stands in for the C library in builds
+// without cgo
+package randomx
+
+// DefaultHasherFactory returns a factory whose hashers fail with
+// ErrUnavailable. Tests inject randomxtest.FakeHasherFactory instead.
+func DefaultHasherFactory() HasherFactory {
+	return unavailableFactory{}
+}
+
+type unavailableFactory struct{}
+
+func (unavailableFactory) NewHasher([]byte, HasherConfig) (Hasher, error) {
+	return nil, ErrUnavailable
+}
@@ -0,0 +1,107 @@
+// Package randomx - params.go holds the OpenSY RandomX parameters, flags and
+// errors shared by the cgo bindings and pure-Go hashers
+package randomx
+
+import (
+	"errors"
+	"strings"
+)
+
+// HashSize is the size of a RandomX hash output in bytes.
+const HashSize = 32
+
+// KeySize is the recommended size for the RandomX key (seed).
+const KeySize = 32
+
+// OpenSY-specific constants
+const (
+	// KeyBlockInterval is how often the RandomX key changes in OpenSY.
+	// This is 32 blocks, NOT 2048 like Monero.
+	KeyBlockInterval = 32
+)
+
+// Flag represents RandomX initialization flags.
+type Flag uint32
+
+const (
+	// FlagDefault uses the default configuration.
+	FlagDefault Flag = 0
+
+	// FlagLargePages uses large memory pages if available.
+	FlagLargePages Flag = 1 << 0
+
+	// FlagHardAES uses hardware AES instructions if available.
+	FlagHardAES Flag = 1 << 1
+
+	// FlagFullMem allocates the full 2GB dataset for faster hashing.
+	// Required for efficient mining, optional for validation.
+	FlagFullMem Flag = 1 << 2
+
+	// FlagJIT enables JIT compilation for faster execution.
+	FlagJIT Flag = 1 << 3
+
+	// FlagSecure disables JIT for security (slower but safer).
+	FlagSecure Flag = 1 << 4
+
+	// FlagArgon2SSSE3 uses SSSE3 for Argon2.
+	FlagArgon2SSSE3 Flag = 1 << 5
+
+	// FlagArgon2AVX2 uses AVX2 for Argon2.
+	FlagArgon2AVX2 Flag = 1 << 6
+
+	// FlagArgon2 selects Argon2 implementation automatically.
+	FlagArgon2 Flag = 1 << 7
+)
+
+// Errors returned by the RandomX package.
+var (
+	ErrCacheAllocation   = errors.New("randomx: failed to allocate cache")
+	ErrDatasetAllocation = errors.New("randomx: failed to allocate dataset")
+	ErrVMCreation        = errors.New("randomx: failed to create VM")
+	ErrNotInitialized    = errors.New("randomx: context not initialized")
+	ErrInvalidKey        = errors.New("randomx: invalid key")
+)
+
+// Utility functions for OpenSY
+
+// GetKeyBlockHeight calculates the key block height for a given block height.
+// OpenSY uses a 32-block interval (not 2048 like Monero).
+func GetKeyBlockHeight(blockHeight int64) int64 {
+	if blockHeight < KeyBlockInterval {
+		return 0 // Use genesis for early blocks
+	}
+	return ((blockHeight / KeyBlockInterval) - 1) * KeyBlockInterval
+}
+
+// NeedsKeyUpdate returns true if the key needs to be updated when
+// moving from oldHeight to newHeight.
+func NeedsKeyUpdate(oldHeight, newHeight int64) bool {
+	return GetKeyBlockHeight(oldHeight) != GetKeyBlockHeight(newHeight)
+}
+
+var flagNames = []struct {
+	flag Flag
+	name string
+}{
+	{FlagLargePages, "large-pages"},
+	{FlagHardAES, "hard-aes"},
+	{FlagFullMem, "full-mem"},
+	{FlagJIT, "jit"},
+	{FlagSecure, "secure"},
+	{FlagArgon2SSSE3, "argon2-ssse3"},
+	{FlagArgon2AVX2, "argon2-avx2"},
+}
+
+// String returns the set flags joined with "|", or "default" if none are set.
+func (f Flag) String() string {
+	var names []string
+	for _, fn := range flagNames {
+		if f&fn.flag != 0 {
+			names = append(names, fn.name)
+		}
+	}
+	if len(names) == 0 {
+		return "default"
+	}
+	return strings.Join(names, "|")
+}
@@ -1,3 +1,5 @@
+//go:build cgo
+
 // Package randomx provides Go bindings for the RandomX proof-of-work algorithm.
 //
 // RandomX is a CPU-friendly, ASIC-resistant proof-of-work algorithm used by
@@ -25,80 +27,25 @@ package randomx
 */
 import "C"
 import (
-	"errors"
-	"runtime"
 	"sync"
 	"unsafe"
 )
 
-// HashSize is the size of a RandomX hash output in bytes.
-const HashSize = 32
-
-// KeySize is the recommended size for the RandomX key (seed).
-const KeySize = 32
-
-// OpenSY-specific constants
-const (
-	// KeyBlockInterval is how often the RandomX key changes in OpenSY.
-	// This is 32 blocks, NOT 2048 like Monero.
-	KeyBlockInterval = 32
-)
-
-// Flag represents RandomX initialization flags.
-type Flag uint32
-
-const (
-	// FlagDefault uses the default configuration.
-	FlagDefault Flag = 0
-
-	// FlagLargePages uses large memory pages if available.
-	FlagLargePages Flag = 1 << 0
-
-	// FlagHardAES uses hardware AES instructions if available.
-	FlagHardAES Flag = 1 << 1
-
-	// FlagFullMem allocates the full 2GB dataset for faster hashing.
-	// Required for efficient mining, optional for validation.
-	FlagFullMem Flag = 1 << 2
-
-	// FlagJIT enables JIT compilation for faster execution.
-	FlagJIT Flag = 1 << 3
-
-	// FlagSecure disables JIT for security (slower but safer).
-	FlagSecure Flag = 1 << 4
-
-	// FlagArgon2SSSE3 uses SSSE3 for Argon2.
-	FlagArgon2SSSE3 Flag = 1 << 5
-
-	// FlagArgon2AVX2 uses AVX2 for Argon2.
-	FlagArgon2AVX2 Flag = 1 << 6
-
-	// FlagArgon2 selects Argon2 implementation automatically.
-	FlagArgon2 Flag = 1 << 7
-)
-
 // GetFlags returns the recommended flags for the current CPU.
 func GetFlags() Flag {
 	return Flag(C.randomx_get_flags())
 }
 
-// Errors returned by the RandomX package.
-var (
-	ErrCacheAllocation   = errors.New("randomx: failed to allocate cache")
-	ErrDatasetAllocation = errors.New("randomx: failed to allocate dataset")
-	ErrVMCreation        = errors.New("randomx: failed to create VM")
-	ErrNotInitialized    = errors.New("randomx: context not initialized")
-	ErrInvalidKey        = errors.New("randomx: invalid key")
-)
-
 // Context holds the RandomX cache and optional dataset.
 // It is NOT thread-safe for initialization but multiple VMs can
 // be created from it for concurrent hashing.
+//
+// VMs keep the cache and dataset they were built on alive, so InitCache,
+// InitDataset and Close never free memory a VM is still using.
 type Context struct {
 	flags   Flag
-	cache   *C.randomx_cache
-	dataset *C.randomx_dataset
-	key     []byte
+	cache   *Cache
+	dataset *Dataset
 	mu      sync.RWMutex
 }
 
@@ -119,33 +66,27 @@ func NewContext(flags Flag) (*Context, error) {
 //
 // For OpenSY, the key changes every 32 blocks.
 func (c *Context) InitCache(key []byte) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if len(key) == 0 {
 		return ErrInvalidKey
 	}
 
-	// Free existing cache if any
-	if c.cache != nil {
-		C.randomx_release_cache(c.cache)
-		c.cache = nil
+	cache, err := NewCache(c.flags)
+	if err != nil {
+		return err
 	}
-
-	// Allocate new cache
-	c.cache = C.randomx_alloc_cache(C.randomx_flags(c.flags))
-	if c.cache == nil {
-		return ErrCacheAllocation
+	if err := cache.Init(key); err != nil {
+		cache.Close()
+		return err
 	}
 
-	// Initialize cache with key
-	keyPtr := (*C.char)(unsafe.Pointer(&key[0]))
-	C.randomx_init_cache(c.cache, unsafe.Pointer(keyPtr), C.size_t(len(key)))
-
-	// Store key copy
-	c.key = make([]byte, len(key))
-	copy(c.key, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
+	// Existing VMs keep the old cache alive until they are closed
+	if c.cache != nil {
+		c.cache.Close()
+	}
+	c.cache = cache
 	return nil
 }
 
@@ -155,60 +96,54 @@ func (c *Context) InitCache(key []byte) error {
 // numThreads specifies how many threads to use for dataset generation.
 // If 0, uses runtime.NumCPU().
 func (c *Context) InitDataset(numThreads int) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if c.cache == nil {
+	c.mu.RLock()
+	cache := c.cache
+	c.mu.RUnlock()
+	if cache == nil {
 		return ErrNotInitialized
 	}
 
-	if numThreads <= 0 {
-		numThreads = runtime.NumCPU()
+	dataset, err := NewDataset(c.flags)
+	if err != nil {
+		return err
 	}
-
-	// Free existing dataset if any
-	if c.dataset != nil {
-		C.randomx_release_dataset(c.dataset)
-		c.dataset = nil
+	if err := dataset.Init(cache, numThreads); err != nil {
+		dataset.Close()
+		return err
 	}
+	c.setDataset(dataset)
+	return nil
+}
 
-	// Allocate dataset
-	c.dataset = C.randomx_alloc_dataset(C.randomx_flags(c.flags))
-	if c.dataset == nil {
-		return ErrDatasetAllocation
+// InitSharedDataset maps the dataset for the current key from a file in dir
+// shared with other processes on the host, building it if none has (see
+// OpenSharedDataset).
+func (c *Context) InitSharedDataset(dir string, numThreads int) error {
+	c.mu.RLock()
+	cache := c.cache
+	c.mu.RUnlock()
+	if cache == nil {
+		return ErrNotInitialized
 	}
 
-	// Get dataset item count
-	itemCount := C.randomx_dataset_item_count()
-
-	// Initialize dataset in parallel
-	itemsPerThread := uint64(itemCount) / uint64(numThreads)
-
-	var wg sync.WaitGroup
-	for i := 0; i < numThreads; i++ {
-		wg.Add(1)
-		startItem := uint64(i) * itemsPerThread
-		count := itemsPerThread
-		if i == numThreads-1 {
-			// Last thread handles remaining items
-			count = uint64(itemCount) - startItem
-		}
-
-		go func(start, cnt uint64) {
-			defer wg.Done()
-			C.randomx_init_dataset(
-				c.dataset,
-				c.cache,
-				C.ulong(start),
-				C.ulong(cnt),
-			)
-		}(startItem, count)
+	dataset, err := OpenSharedDataset(dir, cache, numThreads)
+	if err != nil {
+		return err
 	}
-	wg.Wait()
-
+	c.setDataset(dataset)
 	return nil
 }
 
+func (c *Context) setDataset(dataset *Dataset) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dataset != nil {
+		c.dataset.Close()
+	}
+	c.dataset = dataset
+}
+
 // CreateVM creates a new virtual machine for hashing.
 // Each goroutine should have its own VM.
 func (c *Context) CreateVM() (*VM, error) {
@@ -219,20 +154,8 @@ func (c *Context) CreateVM() (*VM, error) {
 		return nil, ErrNotInitialized
 	}
 
-	var vm *C.randomx_vm
-	if c.dataset != nil {
-		// Full dataset mode (faster, for mining)
-		vm = C.randomx_create_vm(C.randomx_flags(c.flags), c.cache, c.dataset)
-	} else {
-		// Light mode (slower, for validation)
-		vm = C.randomx_create_vm(C.randomx_flags(c.flags), c.cache, nil)
-	}
-
-	if vm == nil {
-		return nil, ErrVMCreation
-	}
-
-	return &VM{vm: vm, ctx: c}, nil
+	// Full dataset mode (faster, for mining) or light mode (for validation)
+	return NewVM(c.cache, c.dataset, c.flags)
 }
 
 // CalculateHash computes a RandomX hash using light mode (no dataset).
@@ -253,12 +176,10 @@ func (c *Context) GetKey() []byte {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	if c.key == nil {
+	if c.cache == nil {
 		return nil
 	}
-	result := make([]byte, len(c.key))
-	copy(result, c.key)
-	return result
+	return c.cache.Key()
 }
 
 // HasDataset returns true if the full dataset is initialized.
@@ -268,27 +189,52 @@ func (c *Context) HasDataset() bool {
 	return c.dataset != nil
 }
 
-// Close releases all resources held by the context.
+// AllocationInfo reports the flags and memory the context actually
+// obtained, including whether requested large pages were granted.
+func (c *Context) AllocationInfo() AllocationInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	info := AllocationInfo{
+		Flags:               c.flags,
+		LargePagesRequested: c.flags&FlagLargePages != 0,
+	}
+	if c.cache == nil {
+		return info
+	}
+	info.Flags = c.cache.Flags()
+	info.CacheBytes = CacheSize
+	info.LargePages = c.cache.LargePages()
+	if c.dataset != nil {
+		info.DatasetBytes = c.dataset.Size()
+		info.DatasetShared = c.dataset.Shared()
+		info.LargePages = info.LargePages && c.dataset.LargePages()
+	}
+	return info
+}
+
+// Close releases the context's references to its cache and dataset. The
+// memory is freed once every VM created from it is closed too.
 func (c *Context) Close() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if c.dataset != nil {
-		C.randomx_release_dataset(c.dataset)
+		c.dataset.Close()
 		c.dataset = nil
 	}
 	if c.cache != nil {
-		C.randomx_release_cache(c.cache)
+		c.cache.Close()
 		c.cache = nil
 	}
-	c.key = nil
 }
 
 // VM is a RandomX virtual machine for computing hashes.
 // It is NOT thread-safe; each goroutine should have its own VM.
 type VM struct {
-	vm  *C.randomx_vm
-	ctx *Context
+	vm      *C.randomx_vm
+	cache   *Cache   // Referenced until Close
+	dataset *Dataset // Referenced until Close; nil in light mode
 }
 
 // CalculateHash computes the RandomX hash of the input.
@@ -373,27 +319,19 @@ func (v *VM) CalculateHashLast() [HashSize]byte {
 	return hash
 }
 
-// Close releases the VM resources.
+// Close releases the VM and its references to the cache and dataset.
 func (v *VM) Close() {
-	if v.vm != nil {
-		C.randomx_destroy_vm(v.vm)
-		v.vm = nil
+	if v.vm == nil {
+		return
 	}
-}
-
-// Utility functions for OpenSY
-
-// GetKeyBlockHeight calculates the key block height for a given block height.
-// OpenSY uses a 32-block interval (not 2048 like Monero).
-func GetKeyBlockHeight(blockHeight int64) int64 {
-	if blockHeight < KeyBlockInterval {
-		return 0 // Use genesis for early blocks
+	C.randomx_destroy_vm(v.vm)
+	v.vm = nil
+	if v.cache != nil {
+		v.cache.unref()
+		v.cache = nil
+	}
+	if v.dataset != nil {
+		v.dataset.unref()
+		v.dataset = nil
 	}
-	return ((blockHeight / KeyBlockInterval) - 1) * KeyBlockInterval
-}
-
-// NeedsKeyUpdate returns true if the key needs to be updated when
-// moving from oldHeight to newHeight.
-func NeedsKeyUpdate(oldHeight, newHeight int64) bool {
-	return GetKeyBlockHeight(oldHeight) != GetKeyBlockHeight(newHeight)
 }
@@ -1,35 +1,23 @@
+//go:build cgo
+
 package randomx
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/hex"
+	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 // Test vectors from RandomX reference implementation
-var testVectors = []struct {
-	key   string
-	input string
-	hash  string
-}{
-	{
-		key:   "test key 000",
-		input: "This is a test",
-		hash:  "639183aae1bf4c9a35884cb46b09cad9175f04efd7684e7262a0ac1c2f0b4e3f",
-	},
-	{
-		key:   "test key 000",
-		input: "Lorem ipsum dolor sit amet",
-		hash:  "300a0adb47603dedb42228ccb2b211104f4da45af709cd7547cd049e9489c969",
-	},
-	{
-		key:   "test key 000",
-		input: "sed do eiusmod tempor incididunt ut labore et dolore magna aliqua",
-		hash:  "c36d4ed4191e617309867ed66a443be4075014e2b061bcdaf9ce7b721d2b77a8",
-	},
-}
+var testVectors = referenceVectors
 
 func TestGetFlags(t *testing.T) {
 	flags := GetFlags()
@@ -252,6 +240,223 @@ func TestConcurrentVMs(t *testing.T) {
 	wg.Wait()
 }
 
+func TestVMPool(t *testing.T) {
+	ctx, err := NewContext(FlagDefault)
+	if err != nil {
+		t.Fatalf("NewContext failed: %v", err)
+	}
+	defer ctx.Close()
+
+	if err := ctx.InitCache([]byte(testVectors[0].key)); err != nil {
+		t.Fatalf("InitCache failed: %v", err)
+	}
+
+	pool := NewVMPool(ctx, 2)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hash, err := pool.CalculateHash(context.Background(), []byte(testVectors[0].input))
+			if err != nil {
+				t.Errorf("CalculateHash failed: %v", err)
+				return
+			}
+			if got := hex.EncodeToString(hash[:]); got != testVectors[0].hash {
+				t.Errorf("hash mismatch: got %s", got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := pool.Stats(); stats.Created > 2 || stats.InUse != 0 {
+		t.Errorf("pool exceeded its bound or leaked VMs: %+v", stats)
+	}
+
+	vm, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close returned while a VM was borrowed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	pool.Put(vm)
+	<-closed
+
+	if _, err := pool.Get(context.Background()); err != ErrPoolClosed {
+		t.Errorf("Get after Close: got %v, want ErrPoolClosed", err)
+	}
+}
+
+func TestCacheLifetime(t *testing.T) {
+	cache, err := NewCache(GetFlags())
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	if err := cache.Init([]byte(testVectors[0].key)); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	vm, err := NewVM(cache, nil, cache.Flags())
+	if err != nil {
+		t.Fatalf("NewVM failed: %v", err)
+	}
+	if err := cache.Init([]byte(testVectors[1].key)); err != ErrInUse {
+		t.Errorf("Init while a VM holds the cache: got %v, want ErrInUse", err)
+	}
+
+	// The VM keeps the cache alive after its owner closes it
+	cache.Close()
+	cache.Close() // Idempotent
+	if cache.cache == nil {
+		t.Fatal("cache freed while a VM still references it")
+	}
+	want := vm.CalculateHash([]byte(testVectors[0].input))
+
+	// Swapping seeds releases the old cache once the VM moves off it
+	next, err := NewCache(GetFlags())
+	if err != nil {
+		t.Fatalf("NewCache failed: %v", err)
+	}
+	defer next.Close()
+	next.Init([]byte(testVectors[0].key))
+	if err := vm.SetCache(next); err != nil {
+		t.Fatalf("SetCache failed: %v", err)
+	}
+	if cache.cache != nil {
+		t.Error("old cache not freed after its last VM moved off it")
+	}
+	if got := vm.CalculateHash([]byte(testVectors[0].input)); got != want {
+		t.Error("hash changed after swapping to a cache with the same key")
+	}
+
+	vm.Close()
+	if _, err := NewVM(cache, nil, cache.Flags()); err != ErrNotInitialized {
+		t.Errorf("NewVM on freed cache: got %v, want ErrNotInitialized", err)
+	}
+}
+
+func TestSharedDataset(t *testing.T) {
+	dir := t.TempDir()
+	newCache := func(key string) *Cache {
+		cache, err := NewCache(FlagDefault)
+		if err != nil {
+			t.Fatalf("NewCache failed: %v", err)
+		}
+		t.Cleanup(cache.Close)
+		if err := cache.Init([]byte(key)); err != nil {
+			t.Fatalf("Init failed: %v", err)
+		}
+		return cache
+	}
+
+	cache := newCache(testVectors[0].key)
+	first, err := OpenSharedDataset(dir, cache, 2)
+	if err != nil {
+		t.Fatalf("OpenSharedDataset failed: %v", err)
+	}
+	path := SharedDatasetPath(dir, []byte(testVectors[0].key))
+	built, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("dataset file missing: %v", err)
+	}
+	if !first.Shared() || built.Size() != int64(first.Size()) {
+		t.Errorf("Shared() = %v, file %d bytes, Size() = %d", first.Shared(), built.Size(), first.Size())
+	}
+	if err := first.Init(cache, 1); err != ErrSharedDataset {
+		t.Errorf("Init on a shared dataset = %v, want ErrSharedDataset", err)
+	}
+
+	// A second process with the same seed maps the existing file
+	second, err := OpenSharedDataset(dir, newCache(testVectors[0].key), 2)
+	if err != nil {
+		t.Fatalf("second OpenSharedDataset failed: %v", err)
+	}
+	if again, _ := os.Stat(path); !os.SameFile(built, again) {
+		t.Error("second open rebuilt the dataset")
+	}
+	vm, err := NewVM(cache, second, FlagDefault)
+	if err != nil {
+		t.Fatalf("NewVM on shared dataset failed: %v", err)
+	}
+	vm.CalculateHash([]byte(testVectors[0].input))
+	vm.Close()
+
+	// Rotating seeds keeps the old file while it is mapped...
+	next, err := OpenSharedDataset(dir, newCache("next key"), 2)
+	if err != nil {
+		t.Fatalf("OpenSharedDataset for next seed failed: %v", err)
+	}
+	defer next.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("mapped dataset removed on rotation: %v", err)
+	}
+
+	// ...and removes it once nobody has it open, along with files left by a
+	// builder that crashed. A build still holding its lock is left alone.
+	first.Close()
+	second.Close()
+	crashed := path + ".123.tmp"
+	os.WriteFile(crashed, []byte("partial"), 0o644)
+	building := SharedDatasetPath(dir, []byte("building")) + ".456.tmp"
+	os.WriteFile(building, []byte("partial"), 0o644)
+	lock, err := os.Create(strings.TrimSuffix(building, ".456.tmp") + ".lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := OpenSharedDataset(dir, newCache("next key"), 2)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	rotated.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("stale dataset not removed: %v", err)
+	}
+	if _, err := os.Stat(crashed); !os.IsNotExist(err) {
+		t.Errorf("crashed build's temporary file not removed: %v", err)
+	}
+	if _, err := os.Stat(building); err != nil {
+		t.Errorf("in-progress build's temporary file removed: %v", err)
+	}
+}
+
+func TestAllocationInfo(t *testing.T) {
+	ctx, err := NewContext(FlagLargePages)
+	if err != nil {
+		t.Fatalf("NewContext failed: %v", err)
+	}
+	defer ctx.Close()
+
+	if info := ctx.AllocationInfo(); info.Bytes() != 0 || !info.LargePagesRequested {
+		t.Errorf("before InitCache: %+v", info)
+	}
+	if err := ctx.InitCache([]byte(testVectors[0].key)); err != nil {
+		t.Fatalf("InitCache failed: %v", err)
+	}
+
+	// Whether large pages were granted depends on the host; the report
+	// must match the flags actually in use either way
+	info := ctx.AllocationInfo()
+	if info.CacheBytes != CacheSize || info.DatasetBytes != 0 {
+		t.Errorf("light mode sizes: %+v", info)
+	}
+	if info.LargePages != (info.Flags&FlagLargePages != 0) {
+		t.Errorf("LargePages %v disagrees with flags %b", info.LargePages, info.Flags)
+	}
+	t.Logf("large pages requested, obtained: %v", info.LargePages)
+}
+
 func TestGetKeyBlockHeight(t *testing.T) {
 	tests := []struct {
 		height   int64
@@ -329,6 +534,36 @@ func BenchmarkCalculateHash(b *testing.B) {
 	}
 }
 
+// BenchmarkCalculateHashBatch hashes consecutive nonces with the pipelined
+// API, mutating the input in place as the miner does
+func BenchmarkCalculateHashBatch(b *testing.B) {
+	ctx, err := NewContext(FlagDefault)
+	if err != nil {
+		b.Fatalf("NewContext failed: %v", err)
+	}
+	defer ctx.Close()
+
+	if err := ctx.InitCache([]byte("benchmark key")); err != nil {
+		b.Fatalf("InitCache failed: %v", err)
+	}
+
+	vm, err := ctx.CreateVM()
+	if err != nil {
+		b.Fatalf("CreateVM failed: %v", err)
+	}
+	defer vm.Close()
+
+	input := make([]byte, 80)
+
+	b.ResetTimer()
+	vm.CalculateHashFirst(input)
+	for i := 1; i < b.N; i++ {
+		binary.LittleEndian.PutUint32(input[76:], uint32(i))
+		vm.CalculateHashNext(input)
+	}
+	vm.CalculateHashLast()
+}
+
 func BenchmarkCalculateHashParallel(b *testing.B) {
 	ctx, err := NewContext(FlagDefault)
 	if err != nil {
@@ -0,0 +1,206 @@
+// Package randomxtest provides a deterministic pure-Go stand-in for RandomX
+// so the pool and CoopMine can be built and tested with CGO_ENABLED=0.
+//
+// FakeHasherFactory implements randomx.HasherFactory with SHA-256. Hashes
+// are not RandomX hashes and must never be used against a real node, but
+// they are stable across runs and their difficulty can be tuned so tests
+// find shares (or blocks) on the first nonce:
+//
+//	cfg := stratum.DefaultJobManagerConfig()
+//	cfg.Hashers = randomxtest.FakeHasherFactory{Difficulty: 1000}
+//	... every share at difficulty <= 1000 now validates ...
+package randomxtest
+
+import (
+	"context"
+	"crypto/sha256"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+)
+
+// FakeHasherFactory builds FakeHashers
+type FakeHasherFactory struct {
+	// Difficulty divides every hash, read as a little-endian 256-bit
+	// number like RandomX output, so each hash meets any difficulty up to
+	// this one. 0 or 1 leaves the SHA-256 output unscaled.
+	Difficulty uint64
+}
+
+// NewHasher implements randomx.HasherFactory. Dataset and flag settings are
+// ignored.
+func (f FakeHasherFactory) NewHasher(seed []byte, cfg randomx.HasherConfig) (randomx.Hasher, error) {
+	size := cfg.VMs
+	if size <= 0 {
+		size = 1
+	}
+	h := &FakeHasher{
+		seed:       append([]byte(nil), seed...),
+		difficulty: f.Difficulty,
+		size:       size,
+		flags:      cfg.Flags,
+		onWait:     cfg.OnWait,
+		tokens:     make(chan struct{}, size),
+		done:       make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		h.tokens <- struct{}{}
+	}
+	return h, nil
+}
+
+// FakeHasher computes FakeHash for one seed, with at most HasherConfig.VMs
+// hashes in flight like a real VM pool
+type FakeHasher struct {
+	seed       []byte
+	difficulty uint64
+	size       int
+	flags      randomx.Flag
+	onWait     func(wait time.Duration)
+
+	tokens    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+
+	inUse    atomic.Int64
+	waits    atomic.Uint64
+	waitTime atomic.Int64 // Nanoseconds
+}
+
+// Hash implements randomx.Hasher
+func (h *FakeHasher) Hash(ctx context.Context, input []byte) ([randomx.HashSize]byte, error) {
+	if err := h.acquire(ctx); err != nil {
+		return [randomx.HashSize]byte{}, err
+	}
+	defer h.releaseVM()
+	return FakeHash(h.seed, input, h.difficulty), nil
+}
+
+// Pipeline implements randomx.Hasher
+func (h *FakeHasher) Pipeline(ctx context.Context) (randomx.Pipeline, error) {
+	if err := h.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return &fakePipeline{h: h}, nil
+}
+
+// acquire takes a VM slot, waiting while every slot is in use
+func (h *FakeHasher) acquire(ctx context.Context) error {
+	start := time.Now()
+	select {
+	case <-h.done:
+		return randomx.ErrPoolClosed
+	case <-h.tokens:
+	default:
+		h.waits.Add(1)
+		select {
+		case <-h.tokens:
+		case <-h.done:
+			return randomx.ErrPoolClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	wait := time.Since(start)
+	h.waitTime.Add(int64(wait))
+	if h.onWait != nil {
+		h.onWait(wait)
+	}
+	h.inUse.Add(1)
+	return nil
+}
+
+func (h *FakeHasher) releaseVM() {
+	h.inUse.Add(-1)
+	h.tokens <- struct{}{}
+}
+
+// fakePipeline hashes the previously queued input on each call, like the
+// RandomX batch API
+type fakePipeline struct {
+	h       *FakeHasher
+	pending []byte
+	closed  bool
+}
+
+func (p *fakePipeline) First(input []byte) {
+	p.pending = append(p.pending[:0], input...)
+}
+
+func (p *fakePipeline) Next(input []byte) [randomx.HashSize]byte {
+	hash := FakeHash(p.h.seed, p.pending, p.h.difficulty)
+	p.pending = append(p.pending[:0], input...)
+	return hash
+}
+
+func (p *fakePipeline) Last() [randomx.HashSize]byte {
+	return FakeHash(p.h.seed, p.pending, p.h.difficulty)
+}
+
+func (p *fakePipeline) Close() {
+	if !p.closed {
+		p.closed = true
+		p.h.releaseVM()
+	}
+}
+
+// Stats implements randomx.Hasher
+func (h *FakeHasher) Stats() randomx.VMPoolStats {
+	return randomx.VMPoolStats{
+		Size:     h.size,
+		Created:  h.size,
+		InUse:    int(h.inUse.Load()),
+		Waits:    h.waits.Load(),
+		WaitTime: time.Duration(h.waitTime.Load()),
+	}
+}
+
+// Allocation implements randomx.Hasher. The fake allocates no RandomX
+// memory and reports large pages as granted whenever they are requested.
+func (h *FakeHasher) Allocation() randomx.AllocationInfo {
+	largePages := h.flags&randomx.FlagLargePages != 0
+	return randomx.AllocationInfo{
+		Flags:               h.flags,
+		LargePagesRequested: largePages,
+		LargePages:          largePages,
+	}
+}
+
+// Close implements randomx.Hasher, waiting for in-flight hashes
+func (h *FakeHasher) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+		for i := 0; i < h.size; i++ {
+			<-h.tokens
+		}
+	})
+}
+
+// FakeHash returns SHA-256(seed || input) divided by difficulty, in the
+// little-endian byte order of RandomX hashes
+func FakeHash(seed, input []byte, difficulty uint64) [randomx.HashSize]byte {
+	sum := sha256.New()
+	sum.Write(seed)
+	sum.Write(input)
+	var hash [randomx.HashSize]byte
+	sum.Sum(hash[:0])
+	if difficulty <= 1 {
+		return hash
+	}
+
+	// Reverse into big-endian for math/big, divide and reverse back
+	var be [randomx.HashSize]byte
+	for i := range hash {
+		be[i] = hash[randomx.HashSize-1-i]
+	}
+	n := new(big.Int).SetBytes(be[:])
+	n.Div(n, new(big.Int).SetUint64(difficulty))
+	n.FillBytes(be[:])
+	for i := range be {
+		hash[i] = be[randomx.HashSize-1-i]
+	}
+	return hash
+}
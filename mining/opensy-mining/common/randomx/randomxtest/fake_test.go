@@ -0,0 +1,49 @@
+package randomxtest
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+)
+
+func TestFakeHasher(t *testing.T) {
+	seed := []byte("seed")
+	input := make([]byte, 80)
+
+	h, err := FakeHasherFactory{}.NewHasher(seed, randomx.HasherConfig{VMs: 2})
+	if err != nil {
+		t.Fatalf("NewHasher: %v", err)
+	}
+	a, err := h.Hash(context.Background(), input)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if b, _ := h.Hash(context.Background(), input); a != b {
+		t.Error("hash not deterministic")
+	}
+	if other := FakeHash([]byte("other"), input, 0); other == a {
+		t.Error("hash does not depend on the seed")
+	}
+
+	// Difficulty scales the little-endian value down
+	scaled := FakeHash(seed, input, 1<<20)
+	if leInt(scaled).Cmp(new(big.Int).Rsh(leInt(a), 20)) != 0 {
+		t.Error("difficulty 2^20 did not divide the hash by 2^20")
+	}
+
+	h.Close()
+	if _, err := h.Hash(context.Background(), input); !errors.Is(err, randomx.ErrPoolClosed) {
+		t.Errorf("Hash after Close: got %v, want ErrPoolClosed", err)
+	}
+}
+
+func leInt(hash [randomx.HashSize]byte) *big.Int {
+	be := make([]byte, len(hash))
+	for i := range hash {
+		be[i] = hash[len(hash)-1-i]
+	}
+	return new(big.Int).SetBytes(be)
+}
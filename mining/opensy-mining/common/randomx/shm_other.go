@@ -0,0 +1,23 @@
+//go:build cgo && !unix
+
+// Package randomx - shm_other.go reports shared datasets as unsupported on
+// hosts without mmap and flock
+package randomx
+
+/*
+#include <randomx.h>
+*/
+import "C"
+import "errors"
+
+// DefaultSharedDatasetDir is unused on this platform
+const DefaultSharedDatasetDir = ""
+
+type sharedFile struct{}
+
+func (*sharedFile) close(*C.randomx_dataset) {}
+
+// OpenSharedDataset is not supported on this platform
+func OpenSharedDataset(string, *Cache, int) (*Dataset, error) {
+	return nil, errors.New("randomx: shared datasets need a unix host")
+}
@@ -0,0 +1,280 @@
+//go:build cgo && unix
+
+// Package randomx - shm_unix.go maps the full dataset from a file shared by
+// every process on the host that hashes with the same seed
+package randomx
+
+/*
+#include <stddef.h>
+#include <stdlib.h>
+#include <stdint.h>
+#include <randomx.h>
+
+// randomx_opensy.h is written by `make randomx` once it has checked that the
+// pinned RandomX source lays out randomx_dataset as mirrored below. Any
+// other RandomX build must not compile this file.
+#if !__has_include(<randomx_opensy.h>)
+#error "randomx_opensy.h not found: build RandomX with common/randomx/Makefile, which checks the randomx_dataset layout shared datasets depend on"
+#endif
+#include <randomx_opensy.h>
+#if OPENSY_RANDOMX_DATASET_LAYOUT != 1
+#error "RandomX randomx_dataset layout does not match opensy_dataset"
+#endif
+
+// Mirrors struct randomx_dataset from dataset.hpp in RandomX v1.1.10, the
+// version pinned by RANDOMX_VERSION in the Makefile:
+//
+//	struct randomx_dataset {
+//		uint8_t* memory = nullptr;
+//		randomx::DatasetDeallocFunc* dealloc;
+//	};
+//
+// so a dataset can point at memory RandomX did not allocate. RandomX only
+// reads and writes memory; dealloc is never called because Go frees the
+// wrapper.
+typedef struct {
+	uint8_t *memory;
+	void *dealloc;
+} opensy_dataset;
+
+_Static_assert(offsetof(opensy_dataset, memory) == 0, "opensy_dataset.memory must come first, as in randomx_dataset");
+_Static_assert(sizeof(opensy_dataset) == 2 * sizeof(void *), "opensy_dataset must be two pointers, as in randomx_dataset");
+
+// opensy_wrap_dataset returns NULL if allocation fails or the linked
+// RandomX does not find memory where opensy_dataset puts it
+static randomx_dataset *opensy_wrap_dataset(void *memory) {
+	opensy_dataset *d = calloc(1, sizeof(opensy_dataset));
+	if (d == NULL) {
+		return NULL;
+	}
+	d->memory = memory;
+	if (randomx_get_dataset_memory((randomx_dataset *)d) != memory) {
+		free(d);
+		return NULL;
+	}
+	return (randomx_dataset *)d;
+}
+*/
+import "C"
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// DefaultSharedDatasetDir is where shared datasets live by default. It is a
+// tmpfs on Linux, so the files never touch disk.
+const DefaultSharedDatasetDir = "/dev/shm"
+
+const sharedDatasetPrefix = "opensy-randomx-"
+
+// SharedDatasetPath returns the file holding the dataset for seed in dir
+func SharedDatasetPath(dir string, seed []byte) string {
+	return filepath.Join(dir, sharedDatasetPrefix+hex.EncodeToString(seed)+".dataset")
+}
+
+// sharedFile is a read-only mapping of a dataset file. The file stays open
+// with a shared flock until the dataset is freed, so a process rotating to
+// a new seed only deletes files nobody has mapped.
+type sharedFile struct {
+	file *os.File
+	mem  []byte
+}
+
+func (s *sharedFile) close(dataset *C.randomx_dataset) {
+	C.free(unsafe.Pointer(dataset))
+	syscall.Munmap(s.mem)
+	s.file.Close() // Drops the flock
+}
+
+// OpenSharedDataset maps the dataset for cache's key from dir (default
+// DefaultSharedDatasetDir), building it first if no process on the host has.
+// Builders serialize on an exclusive lock per seed, so a rotation builds
+// each dataset once while the others wait, then map the finished file
+// read-only. Files for seeds no process has mapped any more are removed.
+//
+// The returned Dataset is reference counted like one from NewDataset, but
+// Init fails with ErrSharedDataset and it never uses large pages.
+func OpenSharedDataset(dir string, cache *Cache, numThreads int) (*Dataset, error) {
+	if dir == "" {
+		dir = DefaultSharedDatasetDir
+	}
+	key := cache.Key()
+	if len(key) == 0 {
+		return nil, ErrNotInitialized
+	}
+	path := SharedDatasetPath(dir, key)
+	size := int64(C.randomx_dataset_item_count()) * DatasetItemSize
+
+	file, err := openSharedDatasetFile(path, size)
+	if errors.Is(err, fs.ErrNotExist) {
+		file, err = buildSharedDataset(path, size, cache, numThreads)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("map shared dataset: %w", err)
+	}
+	wrapped := C.opensy_wrap_dataset(unsafe.Pointer(&mem[0]))
+	if wrapped == nil {
+		syscall.Munmap(mem)
+		file.Close()
+		return nil, ErrDatasetAllocation
+	}
+
+	d := &Dataset{
+		dataset: wrapped,
+		flags:   FlagFullMem,
+		shared:  &sharedFile{file: file, mem: mem},
+	}
+	d.refs.Store(1)
+
+	removeStaleDatasets(dir, path)
+	return d, nil
+}
+
+// openSharedDatasetFile opens a finished dataset file and takes a shared
+// lock on it for as long as it stays open
+func openSharedDatasetFile(path string, size int64) (*os.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_SH); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("lock shared dataset: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() != size {
+		// Written by a RandomX build with other parameters
+		file.Close()
+		return nil, fmt.Errorf("shared dataset %s is %d bytes, want %d", path, info.Size(), size)
+	}
+	return file, nil
+}
+
+// buildSharedDataset writes the dataset to a temporary file and renames it
+// into place, so a file at path is always complete. An exclusive lock on
+// path+".lock" keeps other processes from building the same seed. The file
+// is returned open and share-locked, as from openSharedDatasetFile.
+func buildSharedDataset(path string, size int64, cache *Cache, numThreads int) (*os.File, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("create dataset lock: %w", err)
+	}
+	defer lock.Close()
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return nil, fmt.Errorf("lock dataset: %w", err)
+	}
+
+	// Another process may have finished while we waited for the lock
+	if file, err := openSharedDatasetFile(path, size); !errors.Is(err, fs.ErrNotExist) {
+		return file, err
+	}
+
+	if !cache.retain() {
+		return nil, ErrNotInitialized
+	}
+	defer cache.unref()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("create shared dataset: %w", err)
+	}
+	done := false
+	defer func() {
+		if !done {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := tmp.Chmod(0o644); err != nil {
+		return nil, err
+	}
+	if err := tmp.Truncate(size); err != nil {
+		return nil, fmt.Errorf("size shared dataset: %w", err)
+	}
+	if err := fillSharedDataset(tmp, size, cache, numThreads); err != nil {
+		return nil, err
+	}
+
+	// Lock before the file becomes visible so no process rotating seeds
+	// removes it before we map it
+	if err := syscall.Flock(int(tmp.Fd()), syscall.LOCK_SH); err != nil {
+		return nil, fmt.Errorf("lock shared dataset: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	done = true
+	return tmp, nil
+}
+
+// fillSharedDataset computes the dataset into file through a writable mapping
+func fillSharedDataset(file *os.File, size int64, cache *Cache, numThreads int) error {
+	mem, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("map shared dataset: %w", err)
+	}
+	defer syscall.Munmap(mem)
+
+	wrapped := C.opensy_wrap_dataset(unsafe.Pointer(&mem[0]))
+	if wrapped == nil {
+		return ErrDatasetAllocation
+	}
+	defer C.free(unsafe.Pointer(wrapped))
+	initDatasetItems(wrapped, cache, numThreads)
+	return nil
+}
+
+// removeStaleDatasets deletes other seeds' datasets in dir that no process
+// holds open, and temporary files left by builders that died mid-build
+func removeStaleDatasets(dir, keep string) {
+	// A live builder holds its seed's lock for as long as its file exists
+	tmps, _ := filepath.Glob(filepath.Join(dir, sharedDatasetPrefix+"*.dataset.*.tmp"))
+	for _, tmp := range tmps {
+		dataset := tmp[:strings.LastIndex(tmp, ".dataset.")+len(".dataset")]
+		lock, err := os.OpenFile(dataset+".lock", os.O_RDWR, 0)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				os.Remove(tmp)
+			}
+			continue
+		}
+		if syscall.Flock(int(lock.Fd()), syscall.LOCK_EX|syscall.LOCK_NB) == nil {
+			os.Remove(tmp)
+		}
+		lock.Close()
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, sharedDatasetPrefix+"*.dataset"))
+	for _, path := range paths {
+		if path == keep {
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		if syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB) == nil {
+			os.Remove(path)
+			os.Remove(path + ".lock")
+		}
+		file.Close()
+	}
+}
@@ -0,0 +1,166 @@
+//go:build cgo
+
+// Package randomx - vmpool.go pools VMs for concurrent hashing
+package randomx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// VMPool is a bounded pool of reusable VMs sharing one Context.
+//
+// Creating a VM per hash is expensive; a pool lets up to Size goroutines
+// hash in parallel while reusing VMs. Close waits for borrowed VMs to be
+// returned, so the Context can be released safely afterwards.
+type VMPool struct {
+	ctx  *Context
+	size int
+
+	idle   chan *VM      // VMs ready for reuse
+	tokens chan struct{} // One token per VM that may exist
+	done   chan struct{} // Closed by Close
+
+	mu      sync.Mutex
+	closed  bool
+	created int
+
+	inUse    atomic.Int64
+	waits    atomic.Uint64
+	waitTime atomic.Int64 // Nanoseconds
+
+	// OnWait is called with the time each Get spent waiting for a VM
+	OnWait func(wait time.Duration)
+}
+
+// NewVMPool creates a pool of at most size VMs on ctx. VMs are created
+// lazily on first use.
+func NewVMPool(ctx *Context, size int) *VMPool {
+	if size <= 0 {
+		size = 1
+	}
+	p := &VMPool{
+		ctx:    ctx,
+		size:   size,
+		idle:   make(chan *VM, size),
+		tokens: make(chan struct{}, size),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		p.tokens <- struct{}{}
+	}
+	return p
+}
+
+// Get borrows a VM, waiting until one is free or ctx is done. The VM must
+// be returned with Put.
+func (p *VMPool) Get(ctx context.Context) (*VM, error) {
+	start := time.Now()
+	select {
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-p.tokens:
+	default:
+		p.waits.Add(1)
+		select {
+		case <-p.tokens:
+		case <-p.done:
+			return nil, ErrPoolClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	wait := time.Since(start)
+	p.waitTime.Add(int64(wait))
+	if p.OnWait != nil {
+		p.OnWait(wait)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.tokens <- struct{}{}
+		return nil, ErrPoolClosed
+	}
+	p.mu.Unlock()
+
+	select {
+	case vm := <-p.idle:
+		p.inUse.Add(1)
+		return vm, nil
+	default:
+	}
+
+	vm, err := p.ctx.CreateVM()
+	if err != nil {
+		p.tokens <- struct{}{}
+		return nil, err
+	}
+	p.mu.Lock()
+	p.created++
+	p.mu.Unlock()
+	p.inUse.Add(1)
+	return vm, nil
+}
+
+// Put returns a VM borrowed with Get.
+func (p *VMPool) Put(vm *VM) {
+	p.inUse.Add(-1)
+	p.idle <- vm
+	p.tokens <- struct{}{}
+}
+
+// CalculateHash hashes input on a pooled VM.
+func (p *VMPool) CalculateHash(ctx context.Context, input []byte) ([HashSize]byte, error) {
+	vm, err := p.Get(ctx)
+	if err != nil {
+		return [HashSize]byte{}, err
+	}
+	defer p.Put(vm)
+	return vm.CalculateHash(input), nil
+}
+
+// Stats returns a snapshot of pool usage.
+func (p *VMPool) Stats() VMPoolStats {
+	p.mu.Lock()
+	created := p.created
+	p.mu.Unlock()
+	return VMPoolStats{
+		Size:     p.size,
+		Created:  created,
+		InUse:    int(p.inUse.Load()),
+		Waits:    p.waits.Load(),
+		WaitTime: time.Duration(p.waitTime.Load()),
+	}
+}
+
+// Close stops lending VMs, waits for every borrowed VM to be returned and
+// destroys them. It does not close the Context.
+func (p *VMPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	// Holding every token means no VM is borrowed
+	for i := 0; i < p.size; i++ {
+		<-p.tokens
+	}
+	for {
+		select {
+		case vm := <-p.idle:
+			vm.Close()
+		default:
+			p.mu.Lock()
+			p.created = 0
+			p.mu.Unlock()
+			return
+		}
+	}
+}
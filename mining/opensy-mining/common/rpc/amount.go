@@ -0,0 +1,113 @@
+// Package rpc - amount.go provides exact SYL amount handling for wallet RPCs
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Amount is a quantity of SYL in satoshis (qirsh), 1 SYL = 1e8.
+// It marshals to and from the node's decimal JSON representation without
+// going through float64, so amounts never pick up rounding errors.
+type Amount int64
+
+// Amount units
+const (
+	Satoshi Amount = 1
+	SYL     Amount = 100_000_000
+)
+
+// ErrInvalidAmount is returned when a decimal amount cannot be represented
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// String formats the amount in SYL with 8 decimal places
+func (a Amount) String() string {
+	sign := ""
+	u := uint64(a)
+	if a < 0 {
+		sign = "-"
+		u = uint64(-a)
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, u/uint64(SYL), u%uint64(SYL))
+}
+
+// MarshalJSON encodes the amount as a JSON number in SYL
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalJSON decodes a JSON number (or numeric string) in SYL
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		return nil
+	}
+	v, err := ParseAmount(s)
+	if err != nil {
+		return err
+	}
+	*a = v
+	return nil
+}
+
+// ParseAmount parses a decimal SYL amount such as "12.5" or "0.00000001".
+// More than 8 significant decimal places is an error.
+func ParseAmount(s string) (Amount, error) {
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	// The node always prints amounts in plain decimal
+	if strings.ContainsAny(s, "eE") {
+		return 0, fmt.Errorf("%w: %q (exponent notation)", ErrInvalidAmount, s)
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q has no digits", ErrInvalidAmount, s)
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > 8 {
+		return 0, fmt.Errorf("%w: %q has more than 8 decimals", ErrInvalidAmount, s)
+	}
+	frac += strings.Repeat("0", 8-len(frac))
+
+	if whole == "" {
+		whole = "0"
+	}
+	w, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	f, err := strconv.ParseUint(frac, 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if w > uint64(1<<63-1)/uint64(SYL) {
+		return 0, fmt.Errorf("%w: %q overflows", ErrInvalidAmount, s)
+	}
+
+	v := Amount(w)*SYL + Amount(f)
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// FeeRate is a transaction fee rate in satoshis per 1000 virtual bytes
+type FeeRate int64
+
+// SatPerVByte formats the rate in sat/vB, the unit sendmany's fee_rate takes
+func (r FeeRate) SatPerVByte() string {
+	return fmt.Sprintf("%d.%03d", r/1000, r%1000)
+}
+
+// MarshalJSON encodes the rate as a JSON number in sat/vB
+func (r FeeRate) MarshalJSON() ([]byte, error) {
+	return []byte(r.SatPerVByte()), nil
+}
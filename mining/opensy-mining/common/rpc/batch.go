@@ -0,0 +1,130 @@
+// Package rpc - batch.go implements JSON-RPC batch calls
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BatchRequest is a single call within a JSON-RPC batch. After CallBatch
+// returns, Result holds the decoded result or Err the per-call error.
+type BatchRequest struct {
+	Method string
+	Params []interface{}
+	Result interface{} // Pointer to decode the result into (may be nil)
+	Err    error
+}
+
+// CallBatch sends all requests to one node in a single HTTP round trip and
+// matches responses back by id. It shares Call's failover, retry and circuit
+// breaker handling; the returned error covers transport failures only, and
+// per-call RPC errors are reported in each request's Err.
+func (c *Client) CallBatch(ctx context.Context, reqs []*BatchRequest) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+	return c.withFailover(ctx, fmt.Sprintf("batch(%s x%d)", reqs[0].Method, len(reqs)), func(n *node) error {
+		start := time.Now()
+		err := c.sendBatch(ctx, n, reqs)
+		if ctx.Err() == nil {
+			n.observe(time.Since(start), err)
+		}
+		return err
+	})
+}
+
+func (c *Client) sendBatch(ctx context.Context, n *node, reqs []*BatchRequest) error {
+	batch := make([]Request, len(reqs))
+	index := make(map[uint64]int, len(reqs))
+	for i, r := range reqs {
+		id := c.reqID.Add(1)
+		batch[i] = Request{JSONRPC: "2.0", ID: id, Method: r.Method, Params: r.Params}
+		index[id] = i
+	}
+
+	var responses []Response
+	if err := c.post(ctx, c.client, n, "", batch, &responses); err != nil {
+		return err
+	}
+
+	answered := make([]bool, len(reqs))
+	for _, resp := range responses {
+		i, ok := index[resp.ID]
+		if !ok {
+			continue
+		}
+		answered[i] = true
+
+		r := reqs[i]
+		r.Err = nil
+		if resp.Error != nil {
+			r.Err = resp.Error
+			continue
+		}
+		if r.Result != nil && resp.Result != nil {
+			if err := json.Unmarshal(resp.Result, r.Result); err != nil {
+				r.Err = fmt.Errorf("%w: %v", ErrUnexpectedResult, err)
+			}
+		}
+	}
+
+	for i, ok := range answered {
+		if !ok {
+			return fmt.Errorf("batch response missing %s (id %d)", reqs[i].Method, batch[i].ID)
+		}
+	}
+	return nil
+}
+
+// GetBlockHashes returns the main chain block hashes at the given heights
+// in a single round trip
+func (c *Client) GetBlockHashes(ctx context.Context, heights []int64) ([]string, error) {
+	hashes := make([]string, len(heights))
+	reqs := make([]*BatchRequest, len(heights))
+	for i, h := range heights {
+		reqs[i] = &BatchRequest{Method: "getblockhash", Params: []interface{}{h}, Result: &hashes[i]}
+	}
+
+	if err := c.CallBatch(ctx, reqs); err != nil {
+		return nil, err
+	}
+	for i, r := range reqs {
+		if r.Err != nil {
+			return nil, fmt.Errorf("getblockhash %d: %w", heights[i], r.Err)
+		}
+	}
+	return hashes, nil
+}
+
+// GetBlocks returns block information (verbosity 1) for the given hashes
+// in a single round trip
+func (c *Client) GetBlocks(ctx context.Context, hashes []string) ([]*Block, error) {
+	blocks := make([]*Block, len(hashes))
+	reqs := make([]*BatchRequest, len(hashes))
+	for i, hash := range hashes {
+		blocks[i] = &Block{}
+		reqs[i] = &BatchRequest{Method: "getblock", Params: []interface{}{hash, 1}, Result: blocks[i]}
+	}
+
+	if err := c.CallBatch(ctx, reqs); err != nil {
+		return nil, err
+	}
+	for i, r := range reqs {
+		if r.Err != nil {
+			return nil, fmt.Errorf("getblock %s: %w", hashes[i], r.Err)
+		}
+	}
+	return blocks, nil
+}
+
+// GetBlocksByHeight returns block information for the given heights using
+// two batched round trips regardless of how many heights are requested
+func (c *Client) GetBlocksByHeight(ctx context.Context, heights []int64) ([]*Block, error) {
+	hashes, err := c.GetBlockHashes(ctx, heights)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetBlocks(ctx, hashes)
+}
@@ -0,0 +1,43 @@
+// Package rpc - blocknotify.go receives new-block pushes from the node's -blocknotify hook
+package rpc
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// BlockNotifyPath is the URL prefix served by BlockNotifyHandler
+const BlockNotifyPath = "/blocknotify/"
+
+// BlockNotifyHandler returns an HTTP handler for the node's -blocknotify
+// hook. The node should be started with something like:
+//
+//	-blocknotify="curl -s -X POST -H 'Authorization: Bearer TOKEN' http://pool:9100/blocknotify/%s"
+//
+// Requests must carry the bearer token; the block hash is taken from the
+// path. onBlock is called with the hash for every authenticated request.
+func BlockNotifyHandler(token string, onBlock func(hash string)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		hash := strings.TrimPrefix(r.URL.Path, BlockNotifyPath)
+		if b, err := hex.DecodeString(hash); err != nil || len(b) != 32 {
+			http.Error(w, "invalid block hash", http.StatusBadRequest)
+			return
+		}
+
+		onBlock(strings.ToLower(hash))
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
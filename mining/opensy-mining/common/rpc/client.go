@@ -8,7 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -36,62 +38,80 @@ func (s CircuitState) String() string {
 	}
 }
 
+// MarshalText implements encoding.TextMarshaler
+func (s CircuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
 // ErrCircuitOpen is returned when circuit is open
 var ErrCircuitOpen = errors.New("circuit breaker is open")
 
+// ErrLongPollTimeout is returned when a long poll outlives LongPollTimeout
+// without the template changing
+var ErrLongPollTimeout = errors.New("rpc: long poll timed out")
+
 // ClientConfig holds RPC client configuration
 type ClientConfig struct {
 	URL           string
 	User          string
 	Password      string
+	CookiePath    string // Node .cookie file; overrides User/Password
 	Timeout       time.Duration
 	RetryAttempts int
 	RetryDelay    time.Duration
-	// Circuit breaker
+	// LongPollTimeout bounds a getblocktemplate long poll. The node holds the
+	// request open until the template changes, so this must exceed Timeout.
+	LongPollTimeout time.Duration
+	// Failover nodes. When set, URL/User/Password are ignored and calls are
+	// routed to the healthiest node in the list.
+	Nodes           []NodeConfig
+	BroadcastSubmit bool // Send submitblock to every node, not just the best one
+	// Circuit breaker (applied per node)
 	CBEnabled      bool
 	CBThreshold    int
 	CBResetTimeout time.Duration
-	Logger         *slog.Logger
+	// Transport carries every HTTP request to the nodes; nil uses
+	// http.DefaultTransport. Tests plug in rpctest.Recorder or
+	// rpctest.Replayer here.
+	Transport http.RoundTripper
+	Logger    *slog.Logger
 }
 
 // DefaultClientConfig returns default configuration
 func DefaultClientConfig(url, user, password string) ClientConfig {
 	return ClientConfig{
-		URL:            url,
-		User:           user,
-		Password:       password,
-		Timeout:        30 * time.Second,
-		RetryAttempts:  3,
-		RetryDelay:     time.Second,
-		CBEnabled:      true,
-		CBThreshold:    5,
-		CBResetTimeout: 30 * time.Second,
-		Logger:         slog.Default(),
+		URL:             url,
+		User:            user,
+		Password:        password,
+		Timeout:         30 * time.Second,
+		RetryAttempts:   3,
+		RetryDelay:      time.Second,
+		LongPollTimeout: 2 * time.Minute,
+		CBEnabled:       true,
+		CBThreshold:     5,
+		CBResetTimeout:  30 * time.Second,
+		Logger:          slog.Default(),
 	}
 }
 
-// Client is a JSON-RPC client for the OpenSY node
+// Client is a JSON-RPC client for one or more OpenSY nodes
 type Client struct {
-	url      string
-	user     string
-	password string
-	client   *http.Client
-	reqID    atomic.Uint64
-	logger   *slog.Logger
+	nodes          []*node
+	client         *http.Client
+	longPollClient *http.Client
+	reqID          atomic.Uint64
+	logger         *slog.Logger
 
 	// Retry configuration
 	retryAttempts int
 	retryDelay    time.Duration
 
+	broadcastSubmit bool
+
 	// Circuit breaker
 	cbEnabled      bool
-	cbState        CircuitState
-	cbFailures     int
-	cbSuccesses    int
 	cbThreshold    int
 	cbResetTimeout time.Duration
-	cbLastChange   time.Time
-	cbMu           sync.Mutex
 }
 
 // NewClient creates a new RPC client
@@ -105,19 +125,40 @@ func NewClientWithConfig(cfg ClientConfig) *Client {
 	if cfg.Logger == nil {
 		cfg.Logger = slog.Default()
 	}
+	if cfg.LongPollTimeout <= 0 {
+		cfg.LongPollTimeout = 2 * time.Minute
+	}
+
+	nodeCfgs := cfg.Nodes
+	if len(nodeCfgs) == 0 {
+		nodeCfgs = []NodeConfig{{URL: cfg.URL, User: cfg.User, Password: cfg.Password, CookiePath: cfg.CookiePath}}
+	}
+	logger := cfg.Logger.With("component", "rpc-client")
+	nodes := make([]*node, len(nodeCfgs))
+	for i, nc := range nodeCfgs {
+		nodes[i] = newNode(nc)
+		if _, err := nodes[i].reloadCookie(); err != nil {
+			// The node may not be up yet; the cookie is re-read on 401
+			logger.Warn("Failed to load RPC cookie", "node", nodes[i].name, "error", err)
+		}
+	}
+
 	return &Client{
-		url:            cfg.URL,
-		user:           cfg.User,
-		password:       cfg.Password,
-		logger:         cfg.Logger.With("component", "rpc-client"),
-		retryAttempts:  cfg.RetryAttempts,
-		retryDelay:     cfg.RetryDelay,
-		cbEnabled:      cfg.CBEnabled,
-		cbState:        CircuitClosed,
-		cbThreshold:    cfg.CBThreshold,
-		cbResetTimeout: cfg.CBResetTimeout,
+		nodes:           nodes,
+		logger:          logger,
+		retryAttempts:   cfg.RetryAttempts,
+		retryDelay:      cfg.RetryDelay,
+		broadcastSubmit: cfg.BroadcastSubmit,
+		cbEnabled:       cfg.CBEnabled,
+		cbThreshold:     cfg.CBThreshold,
+		cbResetTimeout:  cfg.CBResetTimeout,
 		client: &http.Client{
-			Timeout: cfg.Timeout,
+			Timeout:   cfg.Timeout,
+			Transport: cfg.Transport,
+		},
+		longPollClient: &http.Client{
+			Timeout:   cfg.LongPollTimeout,
+			Transport: cfg.Transport,
 		},
 	}
 }
@@ -148,14 +189,25 @@ func (e *RPCError) Error() string {
 	return fmt.Sprintf("RPC error %d: %s", e.Code, e.Message)
 }
 
-// Call makes a JSON-RPC call with circuit breaker and retry
+// Call makes a JSON-RPC call with failover, circuit breaker and retry.
+// Nodes are tried in order of health score; each retry round walks the
+// remaining usable nodes before backing off.
 func (c *Client) Call(ctx context.Context, method string, params []interface{}, result interface{}) error {
-	// Check circuit breaker
-	if c.cbEnabled && !c.cbAllow() {
+	return c.withFailover(ctx, method, func(n *node) error {
+		return c.doCall(ctx, n, method, params, result)
+	})
+}
+
+// withFailover runs fn against candidate nodes until one succeeds, applying
+// retry rounds and recording circuit breaker results
+func (c *Client) withFailover(ctx context.Context, method string, fn func(n *node) error) error {
+	nodes := c.candidates()
+	if len(nodes) == 0 {
 		return ErrCircuitOpen
 	}
 
 	var lastErr error
+	failed := make(map[*node]bool, len(nodes))
 	for attempt := 0; attempt <= c.retryAttempts; attempt++ {
 		if attempt > 0 {
 			select {
@@ -165,22 +217,77 @@ func (c *Client) Call(ctx context.Context, method string, params []interface{},
 			}
 		}
 
-		err := c.doCall(ctx, method, params, result)
-		if err == nil {
-			c.cbRecordSuccess()
-			return nil
+		for _, n := range nodes {
+			err := fn(n)
+			if err == nil || !IsTransient(err) {
+				// The node answered; deterministic errors are the caller's
+				// problem and are neither retried nor held against the node
+				if ctx.Err() == nil {
+					c.cbRecordSuccess(n)
+				}
+				delete(failed, n) // Recovered on retry
+				for f := range failed {
+					c.cbRecordFailure(f)
+				}
+				return err
+			}
+
+			lastErr = err
+			failed[n] = true
+			c.logger.Warn("RPC call failed", "node", n.name, "method", method, "attempt", attempt+1, "error", err)
+		}
+	}
+
+	for f := range failed {
+		c.cbRecordFailure(f)
+	}
+	return lastErr
+}
+
+// callNode makes a JSON-RPC call against a single node with retry, bypassing
+// node selection. Used for broadcasts and health probes.
+func (c *Client) callNode(ctx context.Context, n *node, method string, params []interface{}, result interface{}) error {
+	if c.cbEnabled && !c.cbAllow(n) {
+		return ErrCircuitOpen
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.retryAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryDelay * time.Duration(attempt)):
+			}
+		}
+
+		err := c.doCall(ctx, n, method, params, result)
+		if err == nil || !IsTransient(err) {
+			if ctx.Err() == nil {
+				c.cbRecordSuccess(n)
+			}
+			return err
 		}
 
 		lastErr = err
-		c.logger.Warn("RPC call failed", "method", method, "attempt", attempt+1, "error", err)
+		c.logger.Warn("RPC call failed", "node", n.name, "method", method, "attempt", attempt+1, "error", err)
 	}
 
-	c.cbRecordFailure()
+	c.cbRecordFailure(n)
 	return lastErr
 }
 
-// doCall performs the actual RPC call
-func (c *Client) doCall(ctx context.Context, method string, params []interface{}, result interface{}) error {
+// doCall performs the actual RPC call against a node and records its latency
+func (c *Client) doCall(ctx context.Context, n *node, method string, params []interface{}, result interface{}) error {
+	start := time.Now()
+	err := c.send(ctx, c.client, n, "", method, params, result)
+	if ctx.Err() == nil {
+		n.observe(time.Since(start), err)
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, httpClient *http.Client, n *node, path, method string, params []interface{}, result interface{}) error {
 	req := Request{
 		JSONRPC: "2.0",
 		ID:      c.reqID.Add(1),
@@ -188,61 +295,111 @@ func (c *Client) doCall(ctx context.Context, method string, params []interface{}
 		Params:  params,
 	}
 
-	body, err := json.Marshal(req)
+	var rpcResp Response
+	if err := c.post(ctx, httpClient, n, path, req, &rpcResp); err != nil {
+		return err
+	}
+
+	if rpcResp.Error != nil {
+		return rpcResp.Error
+	}
+
+	if result != nil && rpcResp.Result != nil {
+		if err := json.Unmarshal(rpcResp.Result, result); err != nil {
+			return fmt.Errorf("%w: %v", ErrUnexpectedResult, err)
+		}
+	}
+
+	return nil
+}
+
+// post sends a JSON-RPC payload (single request or batch) to a node endpoint
+// (path is appended to the node URL, e.g. "/wallet/pool") and decodes the
+// response body into out
+func (c *Client) post(ctx context.Context, httpClient *http.Client, n *node, path string, payload interface{}, out interface{}) error {
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	resp, err := c.postBody(ctx, httpClient, n, path, body)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return err
 	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	if c.user != "" {
-		httpReq.SetBasicAuth(c.user, c.password)
-	}
+		// The node rewrites its cookie on restart; pick up the new one
+		changed, cerr := n.reloadCookie()
+		if cerr != nil {
+			c.logger.Warn("Failed to reload RPC cookie", "node", n.name, "error", cerr)
+		}
+		if !changed {
+			return ErrUnauthorized
+		}
+		c.logger.Info("Reloaded RPC cookie", "node", n.name)
 
-	resp, err := c.client.Do(httpReq)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		if resp, err = c.postBody(ctx, httpClient, n, path, body); err != nil {
+			return err
+		}
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
 	if resp.StatusCode != http.StatusOK {
+		// Nodes answering JSON-RPC 1.0 style report RPC errors with a
+		// 404/500/503 status and the error object in the body
+		var errResp Response
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && errResp.Error != nil {
+			return errResp.Error
+		}
 		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	var rpcResp Response
-	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
 		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if rpcResp.Error != nil {
-		return rpcResp.Error
+	return nil
+}
+
+func (c *Client) postBody(ctx context.Context, httpClient *http.Client, n *node, path string, body []byte) (*http.Response, error) {
+	endpoint := n.url
+	if path != "" {
+		endpoint = strings.TrimSuffix(endpoint, "/") + path
 	}
 
-	if result != nil && rpcResp.Result != nil {
-		if err := json.Unmarshal(rpcResp.Result, result); err != nil {
-			return fmt.Errorf("failed to unmarshal result: %w", err)
-		}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	return nil
+	httpReq.Header.Set("Content-Type", "application/json")
+	if user, password := n.credentials(); user != "" {
+		httpReq.SetBasicAuth(user, password)
+	}
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
 }
 
 // Circuit breaker methods
-func (c *Client) cbAllow() bool {
-	c.cbMu.Lock()
-	defer c.cbMu.Unlock()
+func (c *Client) cbAllow(n *node) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-	switch c.cbState {
+	switch n.cbState {
 	case CircuitClosed:
 		return true
 	case CircuitOpen:
-		if time.Since(c.cbLastChange) >= c.cbResetTimeout {
-			c.cbState = CircuitHalfOpen
-			c.logger.Info("Circuit breaker half-open")
+		if time.Since(n.cbLastChange) >= c.cbResetTimeout {
+			n.cbState = CircuitHalfOpen
+			c.logger.Info("Circuit breaker half-open", "node", n.name)
 			return true
 		}
 		return false
@@ -252,54 +409,66 @@ func (c *Client) cbAllow() bool {
 	return false
 }
 
-func (c *Client) cbRecordSuccess() {
+func (c *Client) cbRecordSuccess(n *node) {
 	if !c.cbEnabled {
 		return
 	}
-	c.cbMu.Lock()
-	defer c.cbMu.Unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-	switch c.cbState {
+	switch n.cbState {
 	case CircuitHalfOpen:
-		c.cbSuccesses++
-		if c.cbSuccesses >= c.cbThreshold {
-			c.cbState = CircuitClosed
-			c.cbFailures = 0
-			c.cbSuccesses = 0
-			c.logger.Info("Circuit breaker closed")
+		n.cbSuccesses++
+		if n.cbSuccesses >= c.cbThreshold {
+			n.cbState = CircuitClosed
+			n.cbFailures = 0
+			n.cbSuccesses = 0
+			c.logger.Info("Circuit breaker closed", "node", n.name)
 		}
 	case CircuitClosed:
-		c.cbFailures = 0
+		n.cbFailures = 0
 	}
 }
 
-func (c *Client) cbRecordFailure() {
+func (c *Client) cbRecordFailure(n *node) {
 	if !c.cbEnabled {
 		return
 	}
-	c.cbMu.Lock()
-	defer c.cbMu.Unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
 
-	switch c.cbState {
+	switch n.cbState {
 	case CircuitHalfOpen:
-		c.cbState = CircuitOpen
-		c.cbLastChange = time.Now()
-		c.logger.Warn("Circuit breaker opened (half-open failed)")
+		n.cbState = CircuitOpen
+		n.cbSuccesses = 0
+		n.cbLastChange = time.Now()
+		c.logger.Warn("Circuit breaker opened (half-open failed)", "node", n.name)
 	case CircuitClosed:
-		c.cbFailures++
-		if c.cbFailures >= c.cbThreshold {
-			c.cbState = CircuitOpen
-			c.cbLastChange = time.Now()
-			c.logger.Warn("Circuit breaker opened", "failures", c.cbFailures)
+		n.cbFailures++
+		if n.cbFailures >= c.cbThreshold {
+			n.cbState = CircuitOpen
+			n.cbLastChange = time.Now()
+			c.logger.Warn("Circuit breaker opened", "node", n.name, "failures", n.cbFailures)
 		}
 	}
 }
 
-// CircuitState returns current circuit breaker state
+// CircuitState returns the most permissive circuit breaker state across all
+// nodes: closed if any node is closed, open only if every node is open.
 func (c *Client) CircuitState() CircuitState {
-	c.cbMu.Lock()
-	defer c.cbMu.Unlock()
-	return c.cbState
+	state := CircuitOpen
+	for _, n := range c.nodes {
+		n.mu.Lock()
+		s := n.cbState
+		n.mu.Unlock()
+		if s == CircuitClosed {
+			return CircuitClosed
+		}
+		if s == CircuitHalfOpen {
+			state = CircuitHalfOpen
+		}
+	}
+	return state
 }
 
 // BlockTemplate represents a block template from getblocktemplate
@@ -357,20 +526,125 @@ func (c *Client) GetBlockTemplate(ctx context.Context) (*BlockTemplate, error) {
 	return &template, nil
 }
 
-// SubmitBlock submits a solved block to the network
+// GetBlockTemplateLongPoll blocks until the node's template differs from the
+// one identified by longPollID (a new block or mempool change), then returns
+// the new template. An empty longPollID returns immediately.
+//
+// Long polls go to the healthiest node once, without retry; the caller is
+// expected to loop. Latency is not recorded since the node holds the request
+// open by design, and a poll outliving LongPollTimeout returns
+// ErrLongPollTimeout without counting against the node.
+func (c *Client) GetBlockTemplateLongPoll(ctx context.Context, longPollID string) (*BlockTemplate, error) {
+	nodes := c.candidates()
+	if len(nodes) == 0 {
+		return nil, ErrCircuitOpen
+	}
+	n := nodes[0]
+
+	request := map[string]interface{}{
+		"rules": []string{"segwit"},
+	}
+	if longPollID != "" {
+		request["longpollid"] = longPollID
+	}
+
+	var template BlockTemplate
+	err := c.send(ctx, c.longPollClient, n, "", "getblocktemplate", []interface{}{request}, &template)
+	if err != nil {
+		// A quiet node holding the poll past the timeout is not a failure
+		var netErr net.Error
+		if ctx.Err() == nil && errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, fmt.Errorf("%w: %v", ErrLongPollTimeout, err)
+		}
+		if ctx.Err() == nil && IsTransient(err) {
+			c.cbRecordFailure(n)
+		}
+		return nil, err
+	}
+
+	c.cbRecordSuccess(n)
+	return &template, nil
+}
+
+// ProposeBlock asks the node to validate a block built from a template
+// (getblocktemplate proposal mode, BIP23) without requiring valid proof of
+// work. It returns a *BlockRejectedError carrying the node's reason if the
+// block would be invalid. Proposals against a tip that has since moved on
+// ("inconclusive") are not treated as rejections.
+func (c *Client) ProposeBlock(ctx context.Context, blockHex string) error {
+	params := []interface{}{
+		map[string]interface{}{
+			"mode":  "proposal",
+			"data":  blockHex,
+			"rules": []string{"segwit"},
+		},
+	}
+
+	var result interface{}
+	if err := c.Call(ctx, "getblocktemplate", params, &result); err != nil {
+		return err
+	}
+
+	if reason, ok := result.(string); ok && strings.HasPrefix(reason, "inconclusive") {
+		return nil
+	}
+	return submitResultError(result)
+}
+
+// SubmitBlock submits a solved block to the network. With BroadcastSubmit
+// enabled the block is sent to every node and accepted if any node takes it.
 func (c *Client) SubmitBlock(ctx context.Context, blockHex string) error {
+	if c.broadcastSubmit && len(c.nodes) > 1 {
+		return c.submitBlockAll(ctx, blockHex)
+	}
+
 	var result interface{}
 	if err := c.Call(ctx, "submitblock", []interface{}{blockHex}, &result); err != nil {
 		return err
 	}
 
-	// submitblock returns null on success, or a string on failure
-	if result != nil {
-		if errStr, ok := result.(string); ok && errStr != "" {
-			return fmt.Errorf("block rejected: %s", errStr)
+	return submitResultError(result)
+}
+
+func (c *Client) submitBlockAll(ctx context.Context, blockHex string) error {
+	errs := make([]error, len(c.nodes))
+
+	var wg sync.WaitGroup
+	for i, n := range c.nodes {
+		wg.Add(1)
+		go func(i int, n *node) {
+			defer wg.Done()
+			var result interface{}
+			if err := c.callNode(ctx, n, "submitblock", []interface{}{blockHex}, &result); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = submitResultError(result)
+		}(i, n)
+	}
+	wg.Wait()
+
+	var firstErr error
+	for i, err := range errs {
+		if err == nil {
+			return nil
+		}
+		c.logger.Warn("submitblock failed on node", "node", c.nodes[i].name, "error", err)
+		if firstErr == nil {
+			firstErr = err
 		}
 	}
+	return firstErr
+}
 
+// submitResultError interprets a submitblock result: null on success,
+// or a BIP22 reason string on failure
+func submitResultError(result interface{}) error {
+	if result != nil {
+		if errStr, ok := result.(string); ok && errStr != "" && errStr != "duplicate" {
+			return &BlockRejectedError{Reason: errStr}
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,53 @@
+// Package rpc - cookie.go implements node cookie-file authentication
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrUnauthorized is returned when the node rejects the RPC credentials
+var ErrUnauthorized = errors.New("rpc: unauthorized (check credentials or cookie file)")
+
+// readCookie reads a node .cookie file ("__cookie__:<password>")
+func readCookie(path string) (user, password string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read cookie: %w", err)
+	}
+
+	user, password, ok := strings.Cut(strings.TrimSpace(string(data)), ":")
+	if !ok || user == "" {
+		return "", "", fmt.Errorf("malformed cookie file %s", path)
+	}
+	return user, password, nil
+}
+
+// credentials returns the node's current user and password
+func (n *node) credentials() (string, string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.user, n.password
+}
+
+// reloadCookie re-reads the node's cookie file, returning true if the
+// credentials changed. The node rewrites the cookie on every restart.
+func (n *node) reloadCookie() (bool, error) {
+	if n.cookiePath == "" {
+		return false, nil
+	}
+
+	user, password, err := readCookie(n.cookiePath)
+	if err != nil {
+		return false, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	changed := user != n.user || password != n.password
+	n.user = user
+	n.password = password
+	return changed, nil
+}
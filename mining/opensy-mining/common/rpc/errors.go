@@ -0,0 +1,143 @@
+// Package rpc - errors.go classifies node errors for retry and failover decisions
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Bitcoin-style RPC error codes returned by OpenSY nodes
+const (
+	// Standard JSON-RPC 2.0 errors
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeParseError     = -32700
+
+	// General application errors
+	CodeMiscError            = -1
+	CodeTypeError            = -3
+	CodeInvalidAddress       = -5
+	CodeOutOfMemory          = -7
+	CodeInvalidParameter     = -8
+	CodeDatabaseError        = -20
+	CodeDeserializationError = -22
+	CodeVerifyError          = -25
+	CodeVerifyRejected       = -26
+	CodeVerifyAlreadyInChain = -27
+	CodeInWarmup             = -28
+	CodeMethodDeprecated     = -32
+
+	// P2P client errors
+	CodeClientNotConnected  = -9
+	CodeClientInIBD         = -10
+	CodeClientP2PDisabled   = -31
+	CodeClientNodeCapacity  = -34
+	CodeClientMempoolDenied = -33
+
+	// Wallet errors
+	CodeWalletError             = -4
+	CodeWalletInsufficientFunds = -6
+	CodeWalletUnlockNeeded      = -13
+	CodeWalletNotFound          = -18
+	CodeWalletNotSpecified      = -19
+)
+
+// Sentinel errors matched by RPCError via errors.Is
+var (
+	ErrNodeWarmingUp      = errors.New("rpc: node is warming up")
+	ErrInInitialDownload  = errors.New("rpc: node is in initial block download")
+	ErrNodeNotConnected   = errors.New("rpc: node has no peers")
+	ErrMethodNotFound     = errors.New("rpc: method not found")
+	ErrInvalidParameter   = errors.New("rpc: invalid parameter")
+	ErrInvalidAddress     = errors.New("rpc: invalid address or key")
+	ErrDeserialization    = errors.New("rpc: deserialization error")
+	ErrVerifyRejected     = errors.New("rpc: rejected by verification")
+	ErrAlreadyInChain     = errors.New("rpc: already in chain")
+	ErrInternal           = errors.New("rpc: internal node error")
+	ErrWalletError        = errors.New("rpc: wallet error")
+	ErrInsufficientFunds  = errors.New("rpc: insufficient funds")
+	ErrWalletLocked       = errors.New("rpc: wallet is locked")
+	ErrWalletNotFound     = errors.New("rpc: wallet not found")
+	ErrWalletNotSpecified = errors.New("rpc: wallet not specified")
+	ErrUnexpectedResult   = errors.New("rpc: unexpected result")
+	ErrBlockRejected      = errors.New("rpc: block rejected")
+)
+
+// codeErrors maps node error codes to their sentinel
+var codeErrors = map[int]error{
+	CodeInWarmup:                ErrNodeWarmingUp,
+	CodeClientInIBD:             ErrInInitialDownload,
+	CodeClientNotConnected:      ErrNodeNotConnected,
+	CodeMethodNotFound:          ErrMethodNotFound,
+	CodeInvalidParams:           ErrInvalidParameter,
+	CodeInvalidParameter:        ErrInvalidParameter,
+	CodeTypeError:               ErrInvalidParameter,
+	CodeInvalidAddress:          ErrInvalidAddress,
+	CodeDeserializationError:    ErrDeserialization,
+	CodeVerifyError:             ErrVerifyRejected,
+	CodeVerifyRejected:          ErrVerifyRejected,
+	CodeVerifyAlreadyInChain:    ErrAlreadyInChain,
+	CodeInternalError:           ErrInternal,
+	CodeWalletError:             ErrWalletError,
+	CodeWalletInsufficientFunds: ErrInsufficientFunds,
+	CodeWalletUnlockNeeded:      ErrWalletLocked,
+	CodeWalletNotFound:          ErrWalletNotFound,
+	CodeWalletNotSpecified:      ErrWalletNotSpecified,
+}
+
+// Is reports whether the error matches a sentinel, e.g.
+// errors.Is(err, rpc.ErrNodeWarmingUp)
+func (e *RPCError) Is(target error) bool {
+	sentinel, ok := codeErrors[e.Code]
+	return ok && sentinel == target
+}
+
+// Transient reports whether the error reflects the node's state rather than
+// the request, so the call may succeed later or on another node
+func (e *RPCError) Transient() bool {
+	switch e.Code {
+	case CodeInWarmup, CodeClientInIBD, CodeClientNotConnected, CodeOutOfMemory, CodeDatabaseError:
+		return true
+	default:
+		return false
+	}
+}
+
+// BlockRejectedError is returned when the node refuses a submitted block
+type BlockRejectedError struct {
+	Reason string // BIP22 reason, e.g. "high-hash" or "bad-txnmrklroot"
+}
+
+func (e *BlockRejectedError) Error() string {
+	return fmt.Sprintf("block rejected: %s", e.Reason)
+}
+
+// Is matches ErrBlockRejected
+func (e *BlockRejectedError) Is(target error) bool {
+	return target == ErrBlockRejected
+}
+
+// IsTransient reports whether a failed call is worth retrying or failing
+// over. Transport errors, bad HTTP statuses, authentication failures and
+// node-state RPC errors (warming up, IBD) are transient; errors caused by
+// the request itself (invalid address, bad parameters, rejected block) are
+// not, and neither is caller cancellation. Only transient errors count
+// against a node's circuit breaker and health score.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Transient()
+	}
+
+	if errors.Is(err, ErrBlockRejected) || errors.Is(err, ErrUnexpectedResult) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,247 @@
+// Package rpc - nodes.go tracks per-node health for multi-node failover
+package rpc
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Health scoring parameters
+const (
+	// healthDecay is the EWMA weight given to the newest observation
+	healthDecay = 0.2
+	// tipLagPenalty is the score penalty (in latency-equivalent milliseconds)
+	// for every block a node trails the best known tip
+	tipLagPenalty = 1000.0
+	// errorRatePenalty scales latency by (1 + errorRatePenalty*errorRate)
+	errorRatePenalty = 10.0
+)
+
+// NodeConfig describes a single OpenSY node endpoint
+type NodeConfig struct {
+	Name     string // Display name (defaults to URL without credentials)
+	URL      string
+	User     string
+	Password string
+	// CookiePath points at the node's .cookie file. When set it takes
+	// precedence over User/Password and is re-read whenever the node
+	// answers 401, so node restarts do not break the client.
+	CookiePath string
+}
+
+// NodeStatus is a point-in-time snapshot of a node's health
+type NodeStatus struct {
+	Name        string       `json:"name"`
+	URL         string       `json:"url"`
+	Circuit     CircuitState `json:"circuit"`
+	Usable      bool         `json:"usable"`
+	LatencyMs   float64      `json:"latency_ms"`
+	ErrorRate   float64      `json:"error_rate"`
+	TipHeight   int64        `json:"tip_height"`
+	Score       float64      `json:"score"`
+	LastError   string       `json:"last_error,omitempty"`
+	LastSuccess time.Time    `json:"last_success"`
+}
+
+// node is a single backend with its own circuit breaker and health stats
+type node struct {
+	name       string
+	url        string
+	cookiePath string
+
+	mu sync.Mutex
+
+	// Credentials (reloaded from cookiePath)
+	user     string
+	password string
+
+	// Circuit breaker
+	cbState      CircuitState
+	cbFailures   int
+	cbSuccesses  int
+	cbLastChange time.Time
+
+	// Health
+	latency     time.Duration // EWMA of call latency
+	errorRate   float64       // EWMA of call failures (0..1)
+	tipHeight   int64
+	lastError   string
+	lastSuccess time.Time
+}
+
+func newNode(cfg NodeConfig) *node {
+	name := cfg.Name
+	if name == "" {
+		name = redactURL(cfg.URL)
+	}
+	return &node{
+		name:       name,
+		url:        cfg.URL,
+		cookiePath: cfg.CookiePath,
+		user:       cfg.User,
+		password:   cfg.Password,
+		cbState:    CircuitClosed,
+	}
+}
+
+// redactURL drops any user:password from a node URL
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid URL"
+	}
+	u.User = nil
+	return u.String()
+}
+
+// observe folds the outcome of one call into the node's health stats
+func (n *node) observe(latency time.Duration, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.latency == 0 {
+		n.latency = latency
+	} else {
+		n.latency = time.Duration((1-healthDecay)*float64(n.latency) + healthDecay*float64(latency))
+	}
+
+	// Only node-side failures count against health
+	sample := 0.0
+	if IsTransient(err) {
+		sample = 1.0
+		n.lastError = err.Error()
+	} else {
+		n.lastSuccess = time.Now()
+	}
+	n.errorRate = (1-healthDecay)*n.errorRate + healthDecay*sample
+}
+
+func (n *node) setTip(height int64) {
+	n.mu.Lock()
+	n.tipHeight = height
+	n.mu.Unlock()
+}
+
+// score returns the node's health score; lower is better
+func (n *node) score(bestTip int64) float64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s := (float64(n.latency)/float64(time.Millisecond) + 1) * (1 + errorRatePenalty*n.errorRate)
+	if n.tipHeight > 0 && bestTip > n.tipHeight {
+		s += float64(bestTip-n.tipHeight) * tipLagPenalty
+	}
+	return s
+}
+
+func (c *Client) bestTip() int64 {
+	var best int64
+	for _, n := range c.nodes {
+		n.mu.Lock()
+		if n.tipHeight > best {
+			best = n.tipHeight
+		}
+		n.mu.Unlock()
+	}
+	return best
+}
+
+// candidates returns the nodes whose circuit allows a call, healthiest first.
+// Ties keep configuration order so the first node acts as the primary.
+func (c *Client) candidates() []*node {
+	if len(c.nodes) == 1 {
+		if c.cbEnabled && !c.cbAllow(c.nodes[0]) {
+			return nil
+		}
+		return c.nodes
+	}
+
+	bestTip := c.bestTip()
+	type scored struct {
+		n     *node
+		score float64
+	}
+	usable := make([]scored, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		if c.cbEnabled && !c.cbAllow(n) {
+			continue
+		}
+		usable = append(usable, scored{n: n, score: n.score(bestTip)})
+	}
+	sort.SliceStable(usable, func(i, j int) bool {
+		return usable[i].score < usable[j].score
+	})
+
+	nodes := make([]*node, len(usable))
+	for i, u := range usable {
+		nodes[i] = u.n
+	}
+	return nodes
+}
+
+// Nodes returns the health of every configured node
+func (c *Client) Nodes() []NodeStatus {
+	bestTip := c.bestTip()
+	statuses := make([]NodeStatus, len(c.nodes))
+	for i, n := range c.nodes {
+		score := n.score(bestTip)
+
+		n.mu.Lock()
+		usable := n.cbState != CircuitOpen || time.Since(n.cbLastChange) >= c.cbResetTimeout
+		statuses[i] = NodeStatus{
+			Name:        n.name,
+			URL:         n.url,
+			Circuit:     n.cbState,
+			Usable:      !c.cbEnabled || usable,
+			LatencyMs:   float64(n.latency) / float64(time.Millisecond),
+			ErrorRate:   n.errorRate,
+			TipHeight:   n.tipHeight,
+			Score:       score,
+			LastError:   n.lastError,
+			LastSuccess: n.lastSuccess,
+		}
+		n.mu.Unlock()
+	}
+	return statuses
+}
+
+// UsableNodes returns the number of nodes that currently accept calls
+func (c *Client) UsableNodes() int {
+	count := 0
+	for _, s := range c.Nodes() {
+		if s.Usable {
+			count++
+		}
+	}
+	return count
+}
+
+// UpdateNodeHealth probes every node's tip height so that lagging nodes
+// are scored down. Call it periodically; it does not retry.
+func (c *Client) UpdateNodeHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, n := range c.nodes {
+		wg.Add(1)
+		go func(n *node) {
+			defer wg.Done()
+			if c.cbEnabled && !c.cbAllow(n) {
+				return
+			}
+
+			var height int64
+			if err := c.doCall(ctx, n, "getblockcount", nil, &height); err != nil {
+				if IsTransient(err) {
+					c.cbRecordFailure(n)
+				}
+				c.logger.Debug("Node health probe failed", "node", n.name, "error", err)
+				return
+			}
+			c.cbRecordSuccess(n)
+			n.setTip(height)
+		}(n)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,133 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakePublisher accepts one ZMQ subscriber and publishes hashblock messages
+func fakePublisher(t *testing.T, hashes [][]byte) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	subscribed := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		if err := zmtpHandshake(rw, "PUB"); err != nil {
+			t.Errorf("publisher handshake: %v", err)
+			return
+		}
+
+		_, sub, err := readZMTPFrame(rw.Reader)
+		if err != nil || len(sub) == 0 || sub[0] != 1 {
+			t.Errorf("expected subscription frame, got %x (%v)", sub, err)
+			return
+		}
+		subscribed <- string(sub[1:])
+
+		for i, hash := range hashes {
+			seq := binary.LittleEndian.AppendUint32(nil, uint32(i))
+			writeZMTPFrame(rw.Writer, zmtpFlagMore, []byte(TopicHashBlock))
+			writeZMTPFrame(rw.Writer, zmtpFlagMore, hash)
+			writeZMTPFrame(rw.Writer, 0, seq)
+		}
+		rw.Flush()
+
+		// Hold the connection open until the test ends
+		conn.Read(make([]byte, 1))
+	}()
+
+	return "tcp://" + ln.Addr().String(), subscribed
+}
+
+func TestZMQSubscriber(t *testing.T) {
+	hash1, _ := hex.DecodeString(strings.Repeat("ab", 32))
+	hash2, _ := hex.DecodeString(strings.Repeat("cd", 32))
+	addr, subscribed := fakePublisher(t, [][]byte{hash1, hash2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	got := make(chan *ZMQMessage, 2)
+	sub := NewZMQSubscriber(DefaultZMQConfig(addr))
+	sub.OnMessage = func(msg *ZMQMessage) { got <- msg }
+	go sub.Run(ctx)
+
+	select {
+	case topic := <-subscribed:
+		if topic != TopicHashBlock {
+			t.Errorf("subscribed to %q, want %q", topic, TopicHashBlock)
+		}
+	case <-ctx.Done():
+		t.Fatal("subscriber never subscribed")
+	}
+
+	for i, want := range []string{strings.Repeat("ab", 32), strings.Repeat("cd", 32)} {
+		select {
+		case msg := <-got:
+			hash, ok := msg.BlockHash()
+			if !ok || hash != want {
+				t.Errorf("message %d hash = %q, want %q", i, hash, want)
+			}
+			if msg.Sequence != uint32(i) {
+				t.Errorf("message %d sequence = %d", i, msg.Sequence)
+			}
+		case <-ctx.Done():
+			t.Fatalf("message %d not received", i)
+		}
+	}
+}
+
+func TestBlockNotifyHandler(t *testing.T) {
+	var notified []string
+	h := BlockNotifyHandler("secret", func(hash string) { notified = append(notified, hash) })
+	hash := strings.Repeat("0f", 32)
+
+	tests := []struct {
+		name  string
+		path  string
+		token string
+		want  int
+	}{
+		{"valid", BlockNotifyPath + hash, "secret", http.StatusNoContent},
+		{"bad token", BlockNotifyPath + hash, "wrong", http.StatusUnauthorized},
+		{"no token", BlockNotifyPath + hash, "", http.StatusUnauthorized},
+		{"bad hash", BlockNotifyPath + "xyz", "secret", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	if len(notified) != 1 || notified[0] != hash {
+		t.Errorf("notified = %v, want [%s]", notified, hash)
+	}
+}
@@ -0,0 +1,188 @@
+// Package rpctest - block.go parses serialized blocks for proposal checks
+package rpctest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+var errTruncated = errors.New("truncated block")
+
+// blockReader walks a serialized block
+type blockReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *blockReader) next(n int) ([]byte, error) {
+	if n < 0 || r.pos+n > len(r.b) {
+		return nil, errTruncated
+	}
+	out := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return out, nil
+}
+
+func (r *blockReader) varInt() (uint64, error) {
+	prefix, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	switch prefix[0] {
+	case 0xfd:
+		b, err := r.next(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint16(b)), nil
+	case 0xfe:
+		b, err := r.next(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.LittleEndian.Uint32(b)), nil
+	case 0xff:
+		b, err := r.next(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.LittleEndian.Uint64(b), nil
+	default:
+		return uint64(prefix[0]), nil
+	}
+}
+
+// skipVarBytes skips a length-prefixed byte string
+func (r *blockReader) skipVarBytes() error {
+	n, err := r.varInt()
+	if err != nil {
+		return err
+	}
+	if n > uint64(len(r.b)) {
+		return errTruncated
+	}
+	_, err = r.next(int(n))
+	return err
+}
+
+// skipItems calls skip count times, where count is a CompactSize prefix
+func (r *blockReader) skipItems(skip func() error) (int, error) {
+	count, err := r.varInt()
+	if err != nil {
+		return 0, err
+	}
+	if count > uint64(len(r.b)) {
+		return 0, errTruncated
+	}
+	for i := uint64(0); i < count; i++ {
+		if err := skip(); err != nil {
+			return 0, err
+		}
+	}
+	return int(count), nil
+}
+
+// txID reads one transaction and returns its txid in internal byte order.
+// The witness section is excluded from the hash.
+func (r *blockReader) txID() ([]byte, error) {
+	start := r.pos
+	if _, err := r.next(4); err != nil { // version
+		return nil, err
+	}
+
+	segwit := r.pos+2 <= len(r.b) && r.b[r.pos] == 0x00 && r.b[r.pos+1] == 0x01
+	if segwit {
+		r.pos += 2
+	}
+	bodyStart := r.pos
+
+	inputs, err := r.skipItems(func() error {
+		if _, err := r.next(36); err != nil { // outpoint
+			return err
+		}
+		if err := r.skipVarBytes(); err != nil { // scriptSig
+			return err
+		}
+		_, err := r.next(4) // sequence
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.skipItems(func() error {
+		if _, err := r.next(8); err != nil { // value
+			return err
+		}
+		return r.skipVarBytes() // scriptPubKey
+	}); err != nil {
+		return nil, err
+	}
+	bodyEnd := r.pos
+
+	if segwit {
+		for i := 0; i < inputs; i++ {
+			if _, err := r.skipItems(r.skipVarBytes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	locktime, err := r.next(4)
+	if err != nil {
+		return nil, err
+	}
+
+	stripped := make([]byte, 0, 8+bodyEnd-bodyStart)
+	stripped = append(stripped, r.b[start:start+4]...)
+	stripped = append(stripped, r.b[bodyStart:bodyEnd]...)
+	stripped = append(stripped, locktime...)
+	first := sha256.Sum256(stripped)
+	second := sha256.Sum256(first[:])
+	return second[:], nil
+}
+
+// TxID returns the txid (RPC byte order) of a hex serialized transaction,
+// or "" if it does not parse
+func TxID(txHex string) string {
+	raw, err := hex.DecodeString(txHex)
+	if err != nil {
+		return ""
+	}
+	r := &blockReader{b: raw}
+	id, err := r.txID()
+	if err != nil || r.pos != len(raw) {
+		return ""
+	}
+	return hex.EncodeToString(reverse(id))
+}
+
+// parseBlock returns the header and txids (internal byte order) of a
+// serialized block
+func parseBlock(raw []byte) ([]byte, [][]byte, error) {
+	r := &blockReader{b: raw}
+	header, err := r.next(80)
+	if err != nil {
+		return nil, nil, err
+	}
+	count, err := r.varInt()
+	if err != nil {
+		return nil, nil, err
+	}
+	if count == 0 || count > uint64(len(raw)) {
+		return nil, nil, errors.New("bad transaction count")
+	}
+
+	txids := make([][]byte, 0, count)
+	for i := uint64(0); i < count; i++ {
+		id, err := r.txID()
+		if err != nil {
+			return nil, nil, err
+		}
+		txids = append(txids, id)
+	}
+	if r.pos != len(raw) {
+		return nil, nil, errors.New("trailing data after transactions")
+	}
+	return header, txids, nil
+}
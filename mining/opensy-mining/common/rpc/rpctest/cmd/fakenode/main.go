@@ -0,0 +1,51 @@
+// Fake OpenSY node for local pool development
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+	"github.com/opensyria/opensy-mining/common/rpc/rpctest"
+)
+
+func main() {
+	var (
+		listen        = flag.String("listen", "127.0.0.1:19632", "RPC listen address")
+		user          = flag.String("user", "", "Required RPC user (optional)")
+		pass          = flag.String("pass", "", "Required RPC password")
+		chain         = flag.String("chain", "regtest", "Reported chain name")
+		blockInterval = flag.Duration("block-interval", 2*time.Minute, "Mine a block this often (0 = never)")
+		premine       = flag.Int("premine", 64, "Blocks to mine at startup")
+	)
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	cfg := rpctest.DefaultConfig()
+	cfg.Chain = *chain
+	cfg.User = *user
+	cfg.Password = *pass
+
+	node := rpctest.New(cfg)
+	node.Mine(*premine)
+	node.FundWallet(1_000 * rpc.SYL)
+
+	if *blockInterval > 0 {
+		go func() {
+			for range time.Tick(*blockInterval) {
+				hash := node.Mine(1)[0]
+				logger.Info("Mined block", "height", node.Height(), "hash", hash)
+			}
+		}()
+	}
+
+	logger.Info("Fake node listening", "addr", *listen, "chain", *chain, "height", node.Height())
+	if err := http.ListenAndServe(*listen, node); err != nil {
+		logger.Error("Server error", "error", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,336 @@
+// Package rpctest - fixture.go records and replays node HTTP traffic
+package rpctest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Redacted replaces sensitive values in recorded fixtures
+const Redacted = "REDACTED"
+
+// redactedMethods have their params and results replaced when recorded
+// because they carry passphrases, private keys or seeds
+var redactedMethods = map[string]bool{
+	"walletpassphrase":          true,
+	"walletpassphrasechange":    true,
+	"encryptwallet":             true,
+	"createwallet":              true, // Passphrase
+	"dumpprivkey":               true,
+	"importprivkey":             true,
+	"signmessagewithprivkey":    true,
+	"signrawtransactionwithkey": true,
+	"importmulti":               true, // Private keys in requests
+	"importdescriptors":         true,
+	"sethdseed":                 true,
+	"dumpwallet":                true, // File of private keys
+	"importwallet":              true,
+	"listdescriptors":           true, // xprvs with private=true
+	"gethdkeys":                 true,
+}
+
+// Exchange is one recorded HTTP request/response pair
+type Exchange struct {
+	Path         string          `json:"path"` // URL path, e.g. "/" or "/wallet/pool"
+	Request      json.RawMessage `json:"request"`
+	Status       int             `json:"status"`
+	Response     json.RawMessage `json:"response,omitempty"`
+	ResponseText string          `json:"response_text,omitempty"` // Non-JSON response body
+}
+
+// Fixture is the on-disk form of a recording
+type Fixture struct {
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// LoadFixture reads a fixture file written by Recorder.Save
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Save writes the fixture as indented JSON
+func (f *Fixture) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Recorder is an http.RoundTripper that forwards requests to another transport and
+// records every exchange. Credentials never reach the recording: only the
+// URL path is kept, headers are dropped and the params and results of
+// secret-bearing wallet methods are redacted.
+//
+//	rec := rpctest.NewRecorder(nil)
+//	cfg := rpc.DefaultClientConfig(url, user, pass)
+//	cfg.Transport = rec
+//	... exercise the client against a real node ...
+//	rec.Fixture().Save("testdata/rejected-block.json")
+type Recorder struct {
+	next http.RoundTripper
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// NewRecorder creates a recorder forwarding to next (nil uses
+// http.DefaultTransport)
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{next: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	ex := Exchange{Path: req.URL.Path, Status: resp.StatusCode}
+	redact := redactsMethods(reqBody)
+	ex.Request = redactRequest(reqBody, redact)
+	if json.Valid(respBody) {
+		ex.Response = redactResponse(respBody, redact)
+	} else {
+		ex.ResponseText = string(respBody)
+	}
+
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, ex)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Fixture returns everything recorded so far
+func (r *Recorder) Fixture() *Fixture {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Fixture{Exchanges: append([]Exchange(nil), r.exchanges...)}
+}
+
+// rpcCall is the part of a JSON-RPC request or response the fixtures need
+type rpcCall struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method,omitempty"`
+}
+
+// decodeCalls parses a single JSON-RPC object or a batch
+func decodeCalls(body []byte) ([]rpcCall, bool, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var calls []rpcCall
+		err := json.Unmarshal(body, &calls)
+		return calls, true, err
+	}
+	var call rpcCall
+	err := json.Unmarshal(body, &call)
+	return []rpcCall{call}, false, err
+}
+
+// redactsMethods returns the ids of requests in body whose method is secret
+func redactsMethods(body []byte) map[string]bool {
+	calls, _, err := decodeCalls(body)
+	if err != nil {
+		return nil
+	}
+	ids := make(map[string]bool)
+	for _, call := range calls {
+		if redactedMethods[call.Method] {
+			ids[string(call.ID)] = true
+		}
+	}
+	return ids
+}
+
+// rewriteObjects applies fn to each JSON-RPC object in a single or batch body
+func rewriteObjects(body []byte, fn func(obj map[string]json.RawMessage)) ([]byte, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var objs []map[string]json.RawMessage
+		if err := json.Unmarshal(body, &objs); err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			fn(obj)
+		}
+		return json.Marshal(objs)
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, err
+	}
+	fn(obj)
+	return json.Marshal(obj)
+}
+
+func redactRequest(body []byte, ids map[string]bool) json.RawMessage {
+	if !json.Valid(body) {
+		return json.RawMessage(strconv.Quote(string(body)))
+	}
+	if len(ids) == 0 {
+		return json.RawMessage(body)
+	}
+	out, err := rewriteObjects(body, func(obj map[string]json.RawMessage) {
+		if ids[string(obj["id"])] {
+			obj["params"] = json.RawMessage(strconv.Quote(Redacted))
+		}
+	})
+	if err != nil {
+		return json.RawMessage(strconv.Quote(Redacted))
+	}
+	return out
+}
+
+func redactResponse(body []byte, ids map[string]bool) json.RawMessage {
+	if len(ids) == 0 {
+		return json.RawMessage(body)
+	}
+	out, err := rewriteObjects(body, func(obj map[string]json.RawMessage) {
+		if ids[string(obj["id"])] && string(obj["result"]) != "null" {
+			obj["result"] = json.RawMessage(strconv.Quote(Redacted))
+		}
+	})
+	if err != nil {
+		return json.RawMessage(strconv.Quote(Redacted))
+	}
+	return out
+}
+
+// ErrFixtureMismatch is returned by Replayer when a request does not match
+// the next recorded exchange
+var ErrFixtureMismatch = errors.New("request does not match fixture")
+
+// Replayer is an http.RoundTripper that serves a recorded fixture back in
+// order. Each request must call the same methods, in the same order and on
+// the same path, as the next recorded exchange; response ids are rewritten
+// to match the live request so the client accepts them.
+type Replayer struct {
+	mu        sync.Mutex
+	exchanges []Exchange
+	next      int
+}
+
+// NewReplayer creates a replayer for a fixture
+func NewReplayer(f *Fixture) *Replayer {
+	return &Replayer{exchanges: f.Exchanges}
+}
+
+// Remaining returns the number of exchanges not yet served
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.exchanges) - r.next
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		if reqBody, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	if r.next >= len(r.exchanges) {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w: fixture exhausted", ErrFixtureMismatch)
+	}
+	index := r.next
+	ex := r.exchanges[index]
+	r.next++
+	r.mu.Unlock()
+
+	if req.URL.Path != ex.Path {
+		return nil, fmt.Errorf("%w: exchange %d: path %q, recorded %q", ErrFixtureMismatch, index, req.URL.Path, ex.Path)
+	}
+	ids, err := matchCalls(reqBody, ex.Request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: exchange %d: %v", ErrFixtureMismatch, index, err)
+	}
+
+	body := []byte(ex.ResponseText)
+	if len(ex.Response) > 0 {
+		if body, err = rewriteObjects(ex.Response, func(obj map[string]json.RawMessage) {
+			if id, ok := ids[string(obj["id"])]; ok {
+				obj["id"] = id
+			}
+		}); err != nil {
+			return nil, fmt.Errorf("exchange %d: bad recorded response: %w", index, err)
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.Status, http.StatusText(ex.Status)),
+		StatusCode:    ex.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// matchCalls checks that a live request calls the recorded methods and maps
+// recorded ids to live ids
+func matchCalls(live, recorded []byte) (map[string]json.RawMessage, error) {
+	liveCalls, liveBatch, err := decodeCalls(live)
+	if err != nil {
+		return nil, fmt.Errorf("decode request: %v", err)
+	}
+	recCalls, recBatch, err := decodeCalls(recorded)
+	if err != nil {
+		return nil, fmt.Errorf("decode recorded request: %v", err)
+	}
+	if liveBatch != recBatch || len(liveCalls) != len(recCalls) {
+		return nil, fmt.Errorf("got %d calls, recorded %d", len(liveCalls), len(recCalls))
+	}
+
+	ids := make(map[string]json.RawMessage, len(liveCalls))
+	for i := range liveCalls {
+		if liveCalls[i].Method != recCalls[i].Method {
+			return nil, fmt.Errorf("call %d is %s, recorded %s", i, liveCalls[i].Method, recCalls[i].Method)
+		}
+		ids[string(recCalls[i].ID)] = liveCalls[i].ID
+	}
+	return ids, nil
+}
@@ -0,0 +1,84 @@
+package rpctest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+// exerciseClient drives the client through a template, a rejected block
+// and a reorg, returning what it observed
+func exerciseClient(t *testing.T, client *rpc.Client) []interface{} {
+	t.Helper()
+	ctx := context.Background()
+
+	tmpl, err := client.GetBlockTemplate(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockTemplate: %v", err)
+	}
+	submitErr := client.SubmitBlock(ctx, strings.Repeat("00", 81))
+	hashes, err := client.GetBlockHashes(ctx, []int64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("GetBlockHashes: %v", err)
+	}
+	passErr := client.Call(ctx, "walletpassphrase", []interface{}{"hunter2", 60}, nil)
+	createErr := client.Call(ctx, "createwallet", []interface{}{"pool", false, false, "c0rrect-horse"}, nil)
+
+	return []interface{}{tmpl.Height, tmpl.PreviousBlockHash, submitErr.Error(), hashes, passErr.Error(), createErr.Error()}
+}
+
+func TestRecordReplay(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.User, cfg.Password = "pool", "s3cret-rpc-pass"
+	node := NewServer(cfg)
+	defer node.Close()
+	node.Mine(5)
+	node.SetSubmitResult("high-hash")
+
+	rec := NewRecorder(nil)
+	clientCfg := rpc.DefaultClientConfig(node.URL(), cfg.User, cfg.Password)
+	clientCfg.RetryAttempts = 0
+	clientCfg.Transport = rec
+	live := exerciseClient(t, rpc.NewClientWithConfig(clientCfg))
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	if err := rec.Fixture().Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	raw, _ := os.ReadFile(path)
+	for _, secret := range []string{cfg.Password, "hunter2", "c0rrect-horse", "127.0.0.1"} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("fixture leaks %q", secret)
+		}
+	}
+
+	fixture, err := LoadFixture(path)
+	if err != nil {
+		t.Fatalf("LoadFixture: %v", err)
+	}
+	replayer := NewReplayer(fixture)
+	replayCfg := rpc.DefaultClientConfig("http://node.invalid", "", "")
+	replayCfg.RetryAttempts = 0
+	replayCfg.Transport = replayer
+	replayed := exerciseClient(t, rpc.NewClientWithConfig(replayCfg))
+
+	if !reflect.DeepEqual(live, replayed) {
+		t.Errorf("replay differs:\nlive     %v\nreplayed %v", live, replayed)
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("%d exchanges not replayed", replayer.Remaining())
+	}
+
+	// Out-of-order requests are reported instead of served
+	replayCfg.Transport = NewReplayer(fixture)
+	mismatch := rpc.NewClientWithConfig(replayCfg)
+	if _, err := mismatch.GetBlockCount(context.Background()); !errors.Is(err, ErrFixtureMismatch) {
+		t.Errorf("mismatched call: got %v, want ErrFixtureMismatch", err)
+	}
+}
@@ -0,0 +1,570 @@
+// Package rpctest - handlers.go serves the fake node's JSON-RPC methods
+package rpctest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+	Error   *rpc.RPCError   `json:"error"`
+}
+
+func (n *Node) serveRPC(w http.ResponseWriter, r *http.Request) {
+	if n.cfg.User != "" {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != n.cfg.User || pass != n.cfg.Password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	n.mu.Lock()
+	if n.httpFail > 0 {
+		n.httpFail--
+		n.mu.Unlock()
+		http.Error(w, "scripted failure", http.StatusInternalServerError)
+		return
+	}
+	n.mu.Unlock()
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []request
+		if err := json.Unmarshal(trimmed, &reqs); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resps := make([]response, len(reqs))
+		for i, req := range reqs {
+			resps[i] = n.handle(r.Context(), req)
+		}
+		json.NewEncoder(w).Encode(resps)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(n.handle(r.Context(), req))
+}
+
+// handle dispatches a single call
+func (n *Node) handle(ctx context.Context, req request) response {
+	resp := response{JSONRPC: "2.0", ID: req.ID}
+
+	n.mu.Lock()
+	if scripted, ok := n.errors[req.Method]; ok && scripted.count != 0 {
+		if scripted.count > 0 {
+			scripted.count--
+		}
+		n.mu.Unlock()
+		resp.Error = scripted.err
+		return resp
+	}
+	n.mu.Unlock()
+
+	handler, ok := n.methods()[req.Method]
+	if !ok {
+		resp.Error = &rpc.RPCError{Code: rpc.CodeMethodNotFound, Message: "Method not found"}
+		return resp
+	}
+
+	result, rpcErr := handler(ctx, params(req.Params))
+	if rpcErr != nil {
+		resp.Error = rpcErr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+// params wraps positional parameters with typed accessors
+type params []json.RawMessage
+
+func (p params) has(i int) bool {
+	return i < len(p) && string(p[i]) != "null"
+}
+
+func (p params) decode(i int, v interface{}) *rpc.RPCError {
+	if !p.has(i) {
+		return nil
+	}
+	if err := json.Unmarshal(p[i], v); err != nil {
+		return &rpc.RPCError{Code: rpc.CodeTypeError, Message: "JSON value is not of expected type"}
+	}
+	return nil
+}
+
+func invalidParameter(msg string) *rpc.RPCError {
+	return &rpc.RPCError{Code: rpc.CodeInvalidParameter, Message: msg}
+}
+
+type handlerFunc func(ctx context.Context, p params) (interface{}, *rpc.RPCError)
+
+func (n *Node) methods() map[string]handlerFunc {
+	return map[string]handlerFunc{
+		"ping":               func(context.Context, params) (interface{}, *rpc.RPCError) { return nil, nil },
+		"getblocktemplate":   n.getBlockTemplate,
+		"submitblock":        n.submitBlock,
+		"getblockcount":      n.getBlockCount,
+		"getbestblockhash":   n.getBestBlockHash,
+		"getblockhash":       n.getBlockHash,
+		"getblock":           n.getBlock,
+		"getblockchaininfo":  n.getBlockchainInfo,
+		"getmininginfo":      n.getMiningInfo,
+		"getnetworkinfo":     n.getNetworkInfo,
+		"getconnectioncount": n.getConnectionCount,
+		"getdifficulty":      n.getDifficulty,
+		"validateaddress":    n.validateAddress,
+		"estimatesmartfee":   n.estimateSmartFee,
+		"getbalance":         n.getBalance,
+		"listunspent":        n.listUnspent,
+		"sendmany":           n.sendMany,
+		"gettransaction":     n.getTransaction,
+		"walletprocesspsbt":  n.walletProcessPSBT,
+	}
+}
+
+// longPollIDLocked identifies the current template; n.mu must be held
+func (n *Node) longPollIDLocked() string {
+	return n.chain[len(n.chain)-1].Hash + strconv.Itoa(n.updates)
+}
+
+func (n *Node) getBlockTemplate(ctx context.Context, p params) (interface{}, *rpc.RPCError) {
+	var req struct {
+		Mode       string `json:"mode"`
+		Data       string `json:"data"`
+		LongPollID string `json:"longpollid"`
+	}
+	if err := p.decode(0, &req); err != nil {
+		return nil, err
+	}
+	if req.Mode == "proposal" {
+		return n.proposeBlock(req.Data)
+	}
+
+	n.mu.Lock()
+	if n.ibd {
+		n.mu.Unlock()
+		return nil, &rpc.RPCError{Code: rpc.CodeClientInIBD, Message: "OpenSY is in initial sync and waiting for blocks..."}
+	}
+
+	// Long poll: hold the request until the template changes
+	for req.LongPollID != "" && req.LongPollID == n.longPollIDLocked() {
+		changed := n.changed
+		n.mu.Unlock()
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, &rpc.RPCError{Code: rpc.CodeMiscError, Message: "long poll cancelled"}
+		}
+		n.mu.Lock()
+	}
+	defer n.mu.Unlock()
+
+	tip := n.chain[len(n.chain)-1]
+	height := tip.Height + 1
+
+	txs := make([]map[string]interface{}, len(n.mempool))
+	wtxids := [][]byte{make([]byte, 32)} // Coinbase wtxid is zero
+	fees := int64(0)
+	for i, tx := range n.mempool {
+		txs[i] = map[string]interface{}{
+			"data":    tx.Data,
+			"txid":    tx.TxID,
+			"hash":    tx.TxID,
+			"depends": []int{},
+			"fee":     tx.Fee,
+			"sigops":  4,
+			"weight":  len(tx.Data) * 2,
+		}
+		id, _ := hex.DecodeString(tx.TxID)
+		wtxids = append(wtxids, reverse(id))
+		fees += tx.Fee
+	}
+
+	curTime := time.Now().Unix()
+	if curTime <= tip.Time {
+		curTime = tip.Time + 1
+	}
+
+	template := map[string]interface{}{
+		"version":                    0x20000000,
+		"rules":                      []string{"csv", "!segwit", "taproot"},
+		"previousblockhash":          tip.Hash,
+		"transactions":               txs,
+		"coinbaseaux":                map[string]string{"flags": ""},
+		"coinbasevalue":              int64(n.cfg.CoinbaseValue) + fees,
+		"longpollid":                 n.longPollIDLocked(),
+		"target":                     compactToTarget(n.cfg.Bits),
+		"mintime":                    tip.Time + 1,
+		"mutable":                    []string{"time", "transactions", "prevblock"},
+		"noncerange":                 "00000000ffffffff",
+		"sigoplimit":                 80000,
+		"sizelimit":                  4000000,
+		"weightlimit":                4000000,
+		"curtime":                    curTime,
+		"bits":                       n.cfg.Bits,
+		"height":                     height,
+		"seedhash":                   n.seedHashLocked(height),
+		"default_witness_commitment": witnessCommitment(wtxids),
+	}
+	if next := n.seedHashLocked((height/SeedInterval + 1) * SeedInterval); next != "" && next != template["seedhash"] {
+		template["nextseedhash"] = next
+	}
+	return template, nil
+}
+
+// proposeBlock checks a block proposal the way the node's TestBlockValidity
+// would for the parts the fake chain models: it must extend the tip and its
+// merkle root must commit to its transactions
+func (n *Node) proposeBlock(blockHex string) (interface{}, *rpc.RPCError) {
+	raw, err := hex.DecodeString(blockHex)
+	if err != nil {
+		return nil, &rpc.RPCError{Code: rpc.CodeDeserializationError, Message: "Block decode failed"}
+	}
+	header, txids, err := parseBlock(raw)
+	if err != nil {
+		return nil, &rpc.RPCError{Code: rpc.CodeDeserializationError, Message: "Block decode failed"}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.proposalResult != "" {
+		return n.proposalResult, nil
+	}
+	hash := HeaderHash(header)
+	if _, ok := n.blocks[hash]; ok {
+		return "duplicate", nil
+	}
+	if hex.EncodeToString(reverse(header[4:36])) != n.chain[len(n.chain)-1].Hash {
+		return "inconclusive-not-best-prevblk", nil
+	}
+	if !bytes.Equal(header[36:68], MerkleRoot(txids)) {
+		return "bad-txnmrklroot", nil
+	}
+	return nil, nil
+}
+
+func (n *Node) submitBlock(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	var blockHex string
+	if err := p.decode(0, &blockHex); err != nil {
+		return nil, err
+	}
+	raw, err := hex.DecodeString(blockHex)
+	if err != nil || len(raw) < 80 {
+		return nil, &rpc.RPCError{Code: rpc.CodeDeserializationError, Message: "Block decode failed"}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.submits = append(n.submits, blockHex)
+
+	if n.submitResult != "" {
+		return n.submitResult, nil
+	}
+
+	hash := HeaderHash(raw)
+	if _, ok := n.blocks[hash]; ok {
+		return "duplicate", nil
+	}
+
+	prevHash := hex.EncodeToString(reverse(raw[4:36]))
+	tip := n.chain[len(n.chain)-1]
+	if prevHash != tip.Hash {
+		if _, ok := n.blocks[prevHash]; ok {
+			return "inconclusive", nil
+		}
+		return "prev-blk-not-found", nil
+	}
+	// Header-only submissions skip the transaction checks
+	if len(raw) > 80 {
+		header, txids, err := parseBlock(raw)
+		if err != nil {
+			return nil, &rpc.RPCError{Code: rpc.CodeDeserializationError, Message: "Block decode failed"}
+		}
+		if !bytes.Equal(header[36:68], MerkleRoot(txids)) {
+			return "bad-txnmrklroot", nil
+		}
+	}
+
+	bits := reverse(raw[72:76])
+	n.connectLocked(&Block{
+		Hash:     hash,
+		PrevHash: prevHash,
+		Height:   tip.Height + 1,
+		Time:     int64(binary.LittleEndian.Uint32(raw[68:72])),
+		Bits:     hex.EncodeToString(bits),
+		Nonce:    binary.LittleEndian.Uint32(raw[76:80]),
+		Merkle:   hex.EncodeToString(reverse(raw[36:68])),
+		Raw:      raw,
+	})
+	return nil, nil
+}
+
+func (n *Node) getBlockCount(context.Context, params) (interface{}, *rpc.RPCError) {
+	return n.Height(), nil
+}
+
+func (n *Node) getBestBlockHash(context.Context, params) (interface{}, *rpc.RPCError) {
+	return n.Tip().Hash, nil
+}
+
+func (n *Node) getBlockHash(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	var height int64
+	if err := p.decode(0, &height); err != nil {
+		return nil, err
+	}
+	b, ok := n.BlockAt(height)
+	if !ok {
+		return nil, invalidParameter("Block height out of range")
+	}
+	return b.Hash, nil
+}
+
+func (n *Node) getBlock(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	var hash string
+	verbosity := 1
+	if err := p.decode(0, &hash); err != nil {
+		return nil, err
+	}
+	if err := p.decode(1, &verbosity); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	b, ok := n.blocks[hash]
+	if !ok {
+		return nil, &rpc.RPCError{Code: rpc.CodeInvalidAddress, Message: "Block not found"}
+	}
+	if verbosity == 0 {
+		return hex.EncodeToString(b.Raw), nil
+	}
+
+	confirmations := int64(-1)
+	nextHash := ""
+	if !b.Orphaned {
+		confirmations = n.confirmationsLocked(b.Height)
+		if b.Height+1 < int64(len(n.chain)) {
+			nextHash = n.chain[b.Height+1].Hash
+		}
+	}
+
+	result := map[string]interface{}{
+		"hash":          b.Hash,
+		"confirmations": confirmations,
+		"height":        b.Height,
+		"version":       0x20000000,
+		"versionHex":    "20000000",
+		"merkleroot":    b.Merkle,
+		"time":          b.Time,
+		"mediantime":    b.Time,
+		"nonce":         b.Nonce,
+		"bits":          b.Bits,
+		"difficulty":    difficulty(b.Bits),
+		"chainwork":     strings.Repeat("0", 64),
+		"nTx":           1 + len(b.TxIDs),
+		"tx":            append([]string{b.Merkle}, b.TxIDs...),
+	}
+	if b.PrevHash != "" {
+		result["previousblockhash"] = b.PrevHash
+	}
+	if nextHash != "" {
+		result["nextblockhash"] = nextHash
+	}
+	return result, nil
+}
+
+func (n *Node) getBlockchainInfo(context.Context, params) (interface{}, *rpc.RPCError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tip := n.chain[len(n.chain)-1]
+	progress := 1.0
+	if n.ibd {
+		progress = 0.5
+	}
+	return map[string]interface{}{
+		"chain":                n.cfg.Chain,
+		"blocks":               tip.Height,
+		"headers":              tip.Height,
+		"bestblockhash":        tip.Hash,
+		"difficulty":           difficulty(n.cfg.Bits),
+		"mediantime":           tip.Time,
+		"verificationprogress": progress,
+		"initialblockdownload": n.ibd,
+		"chainwork":            strings.Repeat("0", 64),
+		"size_on_disk":         0,
+		"pruned":               false,
+		"warnings":             "",
+	}, nil
+}
+
+func (n *Node) getMiningInfo(context.Context, params) (interface{}, *rpc.RPCError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return map[string]interface{}{
+		"blocks":        n.chain[len(n.chain)-1].Height,
+		"difficulty":    difficulty(n.cfg.Bits),
+		"networkhashps": 1000.0,
+		"pooledtx":      len(n.mempool),
+		"chain":         n.cfg.Chain,
+		"warnings":      "",
+	}, nil
+}
+
+func (n *Node) getNetworkInfo(context.Context, params) (interface{}, *rpc.RPCError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return map[string]interface{}{
+		"version":         300000,
+		"subversion":      "/OpenSY:rpctest/",
+		"protocolversion": 70016,
+		"localservices":   "0000000000000409",
+		"localrelay":      true,
+		"timeoffset":      0,
+		"connections":     n.cfg.Connections,
+		"networkactive":   true,
+		"warnings":        "",
+	}, nil
+}
+
+func (n *Node) getConnectionCount(context.Context, params) (interface{}, *rpc.RPCError) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.cfg.Connections, nil
+}
+
+func (n *Node) getDifficulty(context.Context, params) (interface{}, *rpc.RPCError) {
+	return difficulty(n.cfg.Bits), nil
+}
+
+// ScriptPubKey returns the fake P2WPKH script validateaddress reports for
+// an address
+func ScriptPubKey(address string) string {
+	sum := sha256.Sum256([]byte(address))
+	return "0014" + hex.EncodeToString(sum[:20])
+}
+
+func (n *Node) validAddress(address string) bool {
+	return strings.HasPrefix(address, n.cfg.AddressHRP+"1") && len(address) > len(n.cfg.AddressHRP)+6
+}
+
+func (n *Node) validateAddress(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	var address string
+	if err := p.decode(0, &address); err != nil {
+		return nil, err
+	}
+	if !n.validAddress(address) {
+		return map[string]interface{}{"isvalid": false, "error": "Invalid or unsupported Segwit (Bech32) or Base58 encoding."}, nil
+	}
+	script := ScriptPubKey(address)
+	return map[string]interface{}{
+		"isvalid":         true,
+		"address":         address,
+		"scriptPubKey":    script,
+		"isscript":        false,
+		"iswitness":       true,
+		"witness_version": 0,
+		"witness_program": script[4:],
+	}, nil
+}
+
+// compactToTarget expands compact bits to a 64-char hex target
+func compactToTarget(bits string) string {
+	b, _ := hex.DecodeString(bits)
+	if len(b) != 4 {
+		return strings.Repeat("0", 64)
+	}
+	compact := binary.BigEndian.Uint32(b)
+	exponent := uint(compact >> 24)
+	mantissa := big.NewInt(int64(compact & 0x007fffff))
+
+	target := new(big.Int)
+	if exponent <= 3 {
+		target.Rsh(mantissa, 8*(3-exponent))
+	} else {
+		target.Lsh(mantissa, 8*(exponent-3))
+	}
+	out := target.Text(16)
+	if len(out) < 64 {
+		out = strings.Repeat("0", 64-len(out)) + out
+	}
+	return out
+}
+
+// difficulty returns the difficulty for compact bits relative to 0x1d00ffff
+func difficulty(bits string) float64 {
+	target, _ := new(big.Float).SetString("0x" + compactToTarget(bits))
+	diff1, _ := new(big.Float).SetString("0x" + compactToTarget("1d00ffff"))
+	if target == nil || target.Sign() == 0 {
+		return 0
+	}
+	d, _ := new(big.Float).Quo(diff1, target).Float64()
+	return d
+}
+
+// witnessCommitment returns the coinbase witness commitment script for the
+// given wtxids (internal byte order, coinbase first)
+func witnessCommitment(wtxids [][]byte) string {
+	root := MerkleRoot(wtxids)
+	first := sha256.Sum256(append(root, make([]byte, 32)...))
+	commitment := sha256.Sum256(first[:])
+	return "6a24aa21a9ed" + hex.EncodeToString(commitment[:])
+}
+
+// MerkleRoot computes a Bitcoin-style merkle root over hashes in internal
+// byte order
+func MerkleRoot(hashes [][]byte) []byte {
+	if len(hashes) == 0 {
+		return make([]byte, 32)
+	}
+	level := hashes
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([][]byte, 0, len(level)/2)
+		for i := 0; i < len(level); i += 2 {
+			first := sha256.Sum256(append(append([]byte{}, level[i]...), level[i+1]...))
+			second := sha256.Sum256(first[:])
+			next = append(next, second[:])
+		}
+		level = next
+	}
+	return level[0]
+}
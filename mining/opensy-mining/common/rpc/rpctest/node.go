@@ -0,0 +1,368 @@
+// Package rpctest provides an in-process fake OpenSY node for tests and
+// local development.
+//
+// The node serves the JSON-RPC methods used by rpc.Client on top of a
+// controllable in-memory chain. Tests drive it directly: mine blocks, add
+// mempool transactions, inject reorgs and script errors, then observe how
+// the pool reacts through the real client.
+//
+//	node := rpctest.NewServer(rpctest.DefaultConfig())
+//	defer node.Close()
+//	client := rpc.NewClient(node.URL(), "", "")
+//	node.Mine(40) // crosses a RandomX seed boundary
+//
+// Recorder and Replayer capture traffic with a real node as a fixture
+// file and serve it back through ClientConfig.Transport, so odd responses
+// seen in production can be replayed in unit tests.
+package rpctest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+// SeedInterval is the number of blocks between RandomX key changes
+const SeedInterval = 32
+
+// Config holds fake node configuration
+type Config struct {
+	Chain         string     // Reported chain name ("main", "test", "regtest")
+	AddressHRP    string     // Bech32 prefix accepted by validateaddress
+	Bits          string     // Compact difficulty target for every block
+	CoinbaseValue rpc.Amount // Block subsidy paid in templates
+	User          string     // Required basic auth user (optional)
+	Password      string
+	Connections   int       // Reported peer count
+	GenesisTime   time.Time // Timestamp of the genesis block
+}
+
+// DefaultConfig returns a regtest-like configuration
+func DefaultConfig() Config {
+	return Config{
+		Chain:         "regtest",
+		AddressHRP:    "rsyl",
+		Bits:          "207fffff",
+		CoinbaseValue: 10_000 * rpc.SYL,
+		Connections:   8,
+		GenesisTime:   time.Unix(1733616000, 0),
+	}
+}
+
+// Block is a block in the fake chain
+type Block struct {
+	Hash     string
+	PrevHash string
+	Height   int64
+	Time     int64
+	Bits     string
+	Nonce    uint32
+	Merkle   string
+	Raw      []byte   // Serialized block when submitted, header only when mined
+	TxIDs    []string // Wallet transactions included in this block
+	Orphaned bool     // Disconnected by a reorg
+}
+
+// MempoolTx is a transaction offered in block templates
+type MempoolTx struct {
+	TxID string
+	Data string
+	Fee  int64
+}
+
+// scriptedError is an error returned for the next count calls of a method
+type scriptedError struct {
+	err   *rpc.RPCError
+	count int // Negative means forever
+}
+
+// Node is a fake OpenSY node
+type Node struct {
+	cfg Config
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	chain    []*Block          // Active chain, indexed by height
+	blocks   map[string]*Block // All known blocks including orphans
+	mempool  []MempoolTx
+	updates  int           // Template updates since the last tip change
+	changed  chan struct{} // Closed when the template changes
+	errors   map[string]*scriptedError
+	httpFail int // Next n requests fail with HTTP 500
+	ibd      bool
+	submits  []string // Raw submitblock payloads
+	counter  uint32   // Makes mined block hashes unique
+
+	// submitResult and proposalResult override submitblock's and
+	// proposal-mode getblocktemplate's results when set
+	submitResult   string
+	proposalResult string
+
+	wallet *wallet
+}
+
+// New creates a fake node with only a genesis block. Serve it with
+// NewServer, or mount it as an http.Handler for local development.
+func New(cfg Config) *Node {
+	n := &Node{
+		cfg:     cfg,
+		blocks:  make(map[string]*Block),
+		changed: make(chan struct{}),
+		errors:  make(map[string]*scriptedError),
+		wallet:  newWallet(),
+	}
+	genesis := n.makeBlock("", 0, cfg.GenesisTime.Unix())
+	n.chain = append(n.chain, genesis)
+	n.blocks[genesis.Hash] = genesis
+	return n
+}
+
+// NewServer creates a fake node listening on a local HTTP port
+func NewServer(cfg Config) *Node {
+	n := New(cfg)
+	n.srv = httptest.NewServer(n)
+	return n
+}
+
+// URL returns the node's RPC URL
+func (n *Node) URL() string {
+	return n.srv.URL
+}
+
+// Close shuts down the HTTP server
+func (n *Node) Close() {
+	if n.srv != nil {
+		n.srv.Close()
+	}
+}
+
+// makeBlock builds a block with a real 80-byte header so hashes look like
+// the node's (byte-reversed double SHA-256)
+func (n *Node) makeBlock(prevHash string, height, timestamp int64) *Block {
+	n.counter++
+
+	merkleSrc := make([]byte, 12)
+	binary.LittleEndian.PutUint64(merkleSrc, uint64(height))
+	binary.LittleEndian.PutUint32(merkleSrc[8:], n.counter)
+	merkle := sha256.Sum256(merkleSrc)
+
+	header := make([]byte, 80)
+	binary.LittleEndian.PutUint32(header[0:4], 0x20000000)
+	if prevHash != "" {
+		prev, _ := hex.DecodeString(prevHash)
+		copy(header[4:36], reverse(prev))
+	}
+	copy(header[36:68], merkle[:])
+	binary.LittleEndian.PutUint32(header[68:72], uint32(timestamp))
+	bits, _ := hex.DecodeString(n.cfg.Bits)
+	copy(header[72:76], reverse(bits))
+	binary.LittleEndian.PutUint32(header[76:80], n.counter)
+
+	return &Block{
+		Hash:     HeaderHash(header),
+		PrevHash: prevHash,
+		Height:   height,
+		Time:     timestamp,
+		Bits:     n.cfg.Bits,
+		Nonce:    n.counter,
+		Merkle:   hex.EncodeToString(reverse(merkle[:])),
+		Raw:      header,
+	}
+}
+
+// HeaderHash returns the block hash (RPC byte order) of an 80-byte header
+func HeaderHash(header []byte) string {
+	first := sha256.Sum256(header[:80])
+	second := sha256.Sum256(first[:])
+	return hex.EncodeToString(reverse(second[:]))
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+// notifyLocked wakes long polls; n.mu must be held
+func (n *Node) notifyLocked() {
+	close(n.changed)
+	n.changed = make(chan struct{})
+}
+
+// connectLocked appends a block to the active chain; n.mu must be held
+func (n *Node) connectLocked(b *Block) {
+	n.chain = append(n.chain, b)
+	n.blocks[b.Hash] = b
+	b.TxIDs = n.wallet.confirmPending(b.Height)
+	n.mempool = nil
+	n.updates = 0
+	n.notifyLocked()
+}
+
+// Mine appends count blocks to the active chain and returns their hashes
+func (n *Node) Mine(count int) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	hashes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		tip := n.chain[len(n.chain)-1]
+		b := n.makeBlock(tip.Hash, tip.Height+1, tip.Time+120)
+		n.connectLocked(b)
+		hashes = append(hashes, b.Hash)
+	}
+	return hashes
+}
+
+// Reorg disconnects the top depth blocks and mines replacement blocks on
+// the new tip, returning the new hashes. Wallet transactions confirmed in
+// disconnected blocks go back to the mempool.
+func (n *Node) Reorg(depth, replacement int) []string {
+	n.mu.Lock()
+	if depth >= len(n.chain) {
+		depth = len(n.chain) - 1 // Never disconnect genesis
+	}
+	for _, b := range n.chain[len(n.chain)-depth:] {
+		b.Orphaned = true
+		n.wallet.unconfirm(b.TxIDs)
+	}
+	n.chain = n.chain[:len(n.chain)-depth]
+	n.mu.Unlock()
+
+	return n.Mine(replacement)
+}
+
+// Height returns the active chain height
+func (n *Node) Height() int64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return int64(len(n.chain) - 1)
+}
+
+// Tip returns the active chain tip
+func (n *Node) Tip() Block {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return *n.chain[len(n.chain)-1]
+}
+
+// BlockAt returns the active chain block at height
+func (n *Node) BlockAt(height int64) (Block, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if height < 0 || height >= int64(len(n.chain)) {
+		return Block{}, false
+	}
+	return *n.chain[height], true
+}
+
+// SeedHash returns the RandomX seed for a block at height: the hash of the
+// last interval boundary at least one full interval below it
+func (n *Node) SeedHash(height int64) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.seedHashLocked(height)
+}
+
+func seedHeight(height int64) int64 {
+	if height < SeedInterval {
+		return 0
+	}
+	return (height/SeedInterval - 1) * SeedInterval
+}
+
+func (n *Node) seedHashLocked(height int64) string {
+	h := seedHeight(height)
+	if h >= int64(len(n.chain)) {
+		return ""
+	}
+	return n.chain[h].Hash
+}
+
+// AddMempoolTx adds a transaction to future templates and wakes long polls.
+// Without a TxID, Data's real txid is used if it parses as a transaction.
+func (n *Node) AddMempoolTx(tx MempoolTx) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if tx.TxID == "" {
+		tx.TxID = TxID(tx.Data)
+	}
+	if tx.TxID == "" {
+		sum := sha256.Sum256([]byte(tx.Data + fmt.Sprint(len(n.mempool), n.counter)))
+		tx.TxID = hex.EncodeToString(sum[:])
+	}
+	n.mempool = append(n.mempool, tx)
+	n.updates++
+	n.notifyLocked()
+}
+
+// SetError makes the next count calls of method fail with the given RPC
+// error code. A negative count fails every call until ClearErrors.
+func (n *Node) SetError(method string, code int, message string, count int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.errors[method] = &scriptedError{err: &rpc.RPCError{Code: code, Message: message}, count: count}
+}
+
+// FailHTTP makes the next count requests fail with HTTP 500
+func (n *Node) FailHTTP(count int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.httpFail = count
+}
+
+// ClearErrors removes all scripted errors
+func (n *Node) ClearErrors() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.errors = make(map[string]*scriptedError)
+	n.httpFail = 0
+}
+
+// SetInitialBlockDownload toggles the reported IBD state
+func (n *Node) SetInitialBlockDownload(ibd bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.ibd = ibd
+}
+
+// SetConnections sets the reported peer count
+func (n *Node) SetConnections(count int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.cfg.Connections = count
+}
+
+// Submissions returns every payload passed to submitblock
+func (n *Node) Submissions() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.submits...)
+}
+
+// FundWallet credits the wallet with a confirmed coinbase-like output
+func (n *Node) FundWallet(amount rpc.Amount) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.wallet.fund(amount, int64(len(n.chain)-1))
+}
+
+// confirmations returns the confirmations of a block at height on the
+// active chain
+func (n *Node) confirmationsLocked(height int64) int64 {
+	return int64(len(n.chain)) - height
+}
+
+// ServeHTTP implements http.Handler
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.serveRPC(w, r)
+}
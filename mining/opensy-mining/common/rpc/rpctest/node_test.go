@@ -0,0 +1,197 @@
+package rpctest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+func newClient(t *testing.T) (*Node, *rpc.Client) {
+	t.Helper()
+	node := NewServer(DefaultConfig())
+	t.Cleanup(node.Close)
+
+	cfg := rpc.DefaultClientConfig(node.URL(), "", "")
+	cfg.RetryAttempts = 0
+	return node, rpc.NewClientWithConfig(cfg)
+}
+
+func TestTemplateSeedRotation(t *testing.T) {
+	node, client := newClient(t)
+	ctx := context.Background()
+
+	node.Mine(31)
+	tmpl, err := client.GetBlockTemplate(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockTemplate: %v", err)
+	}
+	genesis, _ := node.BlockAt(0)
+	if tmpl.Height != 32 || tmpl.SeedHash != genesis.Hash {
+		t.Errorf("height %d seed %s, want 32 keyed on genesis", tmpl.Height, tmpl.SeedHash)
+	}
+
+	node.Mine(32)
+	tmpl, _ = client.GetBlockTemplate(ctx)
+	key, _ := node.BlockAt(32)
+	if tmpl.Height != 64 || tmpl.SeedHash != key.Hash {
+		t.Errorf("height %d seed %s, want 64 keyed on block 32", tmpl.Height, tmpl.SeedHash)
+	}
+}
+
+func TestLongPollWakesOnBlock(t *testing.T) {
+	node, client := newClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tmpl, err := client.GetBlockTemplate(ctx)
+	if err != nil {
+		t.Fatalf("GetBlockTemplate: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		node.Mine(1)
+	}()
+
+	next, err := client.GetBlockTemplateLongPoll(ctx, tmpl.LongPollID)
+	if err != nil {
+		t.Fatalf("long poll: %v", err)
+	}
+	if next.Height != tmpl.Height+1 {
+		t.Errorf("long poll returned height %d, want %d", next.Height, tmpl.Height+1)
+	}
+}
+
+func TestReorgOrphansBlocks(t *testing.T) {
+	node, client := newClient(t)
+	ctx := context.Background()
+
+	old := node.Mine(10)
+	node.Reorg(3, 4)
+
+	if node.Height() != 11 {
+		t.Fatalf("height = %d, want 11", node.Height())
+	}
+	hashes, err := client.GetBlockHashes(ctx, []int64{7, 8})
+	if err != nil {
+		t.Fatalf("GetBlockHashes: %v", err)
+	}
+	if hashes[0] != old[6] || hashes[1] == old[7] {
+		t.Errorf("block 7 should survive and block 8 be replaced")
+	}
+
+	orphan, err := client.GetBlock(ctx, old[9])
+	if err != nil || orphan.Confirmations != -1 {
+		t.Errorf("orphaned block confirmations = %v, %v; want -1", orphan, err)
+	}
+}
+
+func TestScriptedErrorsAndSubmit(t *testing.T) {
+	node, client := newClient(t)
+	ctx := context.Background()
+
+	node.SetError("getblocktemplate", rpc.CodeInWarmup, "Loading block index...", 1)
+	if _, err := client.GetBlockTemplate(ctx); !errors.Is(err, rpc.ErrNodeWarmingUp) {
+		t.Fatalf("error = %v, want ErrNodeWarmingUp", err)
+	}
+
+	tip := node.Tip()
+	header := make([]byte, 80)
+	copy(header[4:36], reverse(mustHex(tip.Hash)))
+	if err := client.SubmitBlock(ctx, hexString(header)); err != nil {
+		t.Fatalf("SubmitBlock: %v", err)
+	}
+	if node.Height() != 1 || node.Tip().Hash != HeaderHash(header) {
+		t.Errorf("submitted block not connected")
+	}
+
+	node.SetSubmitResult("high-hash")
+	err := client.SubmitBlock(ctx, hexString(header))
+	var rejected *rpc.BlockRejectedError
+	if !errors.As(err, &rejected) || rejected.Reason != "high-hash" {
+		t.Errorf("error = %v, want high-hash rejection", err)
+	}
+}
+
+func TestWalletPayout(t *testing.T) {
+	node, client := newClient(t)
+	ctx := context.Background()
+	wallet := client.Wallet("payouts")
+
+	node.FundWallet(100 * rpc.SYL)
+	txid, err := wallet.SendMany(ctx, map[string]rpc.Amount{
+		"rsyl1qminer1": 10 * rpc.SYL,
+		"rsyl1qminer2": 5 * rpc.SYL,
+	}, rpc.SendOptions{FeeRate: 2000})
+	if err != nil {
+		t.Fatalf("SendMany: %v", err)
+	}
+
+	if conf, _ := wallet.Confirmations(ctx, txid); conf != 0 {
+		t.Errorf("confirmations before mining = %d, want 0", conf)
+	}
+	node.Mine(3)
+
+	tx, err := wallet.WaitForConfirmations(ctx, txid, 3, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForConfirmations: %v", err)
+	}
+	if tx.Amount != -15*rpc.SYL || tx.Fee >= 0 {
+		t.Errorf("tx amount %s fee %s", tx.Amount, tx.Fee)
+	}
+
+	if _, err := wallet.SendMany(ctx, map[string]rpc.Amount{"bogus": rpc.SYL}, rpc.SendOptions{}); !errors.Is(err, rpc.ErrInvalidAddress) {
+		t.Errorf("error = %v, want ErrInvalidAddress", err)
+	}
+}
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func hexString(b []byte) string {
+	return hex.EncodeToString(b)
+}
+
+func TestProposeBlock(t *testing.T) {
+	node, client := newClient(t)
+	ctx := context.Background()
+	node.Mine(1)
+
+	coinbase, _ := hex.DecodeString("01000000" + "01" + strings.Repeat("00", 32) + "ffffffff" +
+		"020101" + "ffffffff" + "01" + "00e1f50500000000" + "0151" + "00000000")
+	first := sha256.Sum256(coinbase)
+	txid := sha256.Sum256(first[:])
+
+	prev, _ := hex.DecodeString(node.Tip().Hash)
+	header := make([]byte, 80)
+	copy(header[4:36], reverse(prev))
+	copy(header[36:68], MerkleRoot([][]byte{txid[:]}))
+	block := hex.EncodeToString(append(append(header, 0x01), coinbase...))
+
+	if err := client.ProposeBlock(ctx, block); err != nil {
+		t.Fatalf("valid proposal: %v", err)
+	}
+
+	header[36] ^= 0xff
+	bad := hex.EncodeToString(append(append(header, 0x01), coinbase...))
+	var rejected *rpc.BlockRejectedError
+	if err := client.ProposeBlock(ctx, bad); !errors.As(err, &rejected) || rejected.Reason != "bad-txnmrklroot" {
+		t.Errorf("bad merkle root: got %v", err)
+	}
+
+	node.Mine(1)
+	if err := client.ProposeBlock(ctx, block); err != nil {
+		t.Errorf("stale prevblock should be inconclusive, got %v", err)
+	}
+}
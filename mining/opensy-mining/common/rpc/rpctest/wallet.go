@@ -0,0 +1,310 @@
+// Package rpctest - wallet.go implements the fake node's wallet
+package rpctest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+// defaultFeeRate is used by sendmany and estimatesmartfee unless overridden
+const defaultFeeRate rpc.FeeRate = 1000 // 1 sat/vB
+
+// wallet is a single in-memory wallet shared by every /wallet/<name> path
+type wallet struct {
+	txs     map[string]*walletTx
+	utxos   []*utxo
+	feeRate rpc.FeeRate
+	seq     int
+}
+
+type walletTx struct {
+	txid    string
+	amount  rpc.Amount
+	fee     rpc.Amount
+	height  int64 // -1 while in the mempool
+	details []rpc.TxDetail
+}
+
+type utxo struct {
+	tx      *walletTx
+	vout    uint32
+	address string
+	amount  rpc.Amount
+}
+
+func newWallet() *wallet {
+	return &wallet{
+		txs:     make(map[string]*walletTx),
+		feeRate: defaultFeeRate,
+	}
+}
+
+func (w *wallet) newTxID() string {
+	w.seq++
+	sum := sha256.Sum256([]byte(fmt.Sprintf("rpctest-wallet-tx-%d", w.seq)))
+	return hex.EncodeToString(sum[:])
+}
+
+func (w *wallet) fund(amount rpc.Amount, height int64) {
+	tx := &walletTx{
+		txid:    w.newTxID(),
+		amount:  amount,
+		height:  height,
+		details: []rpc.TxDetail{{Address: "rpctest-funding", Category: "receive", Amount: amount}},
+	}
+	w.txs[tx.txid] = tx
+	w.utxos = append(w.utxos, &utxo{tx: tx, address: "rpctest-funding", amount: amount})
+}
+
+// confirmPending mines every mempool wallet transaction at height
+func (w *wallet) confirmPending(height int64) []string {
+	var txids []string
+	for _, tx := range w.txs {
+		if tx.height < 0 {
+			tx.height = height
+			txids = append(txids, tx.txid)
+		}
+	}
+	sort.Strings(txids)
+	return txids
+}
+
+// unconfirm returns transactions from disconnected blocks to the mempool
+func (w *wallet) unconfirm(txids []string) {
+	for _, txid := range txids {
+		if tx, ok := w.txs[txid]; ok {
+			tx.height = -1
+		}
+	}
+}
+
+// SetFeeRate sets the rate reported by estimatesmartfee and used by
+// sendmany when no fee_rate is given
+func (n *Node) SetFeeRate(rate rpc.FeeRate) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.wallet.feeRate = rate
+}
+
+// SetSubmitResult makes submitblock return reason (e.g. "high-hash") for
+// every block until it is reset with ""
+func (n *Node) SetSubmitResult(reason string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.submitResult = reason
+}
+
+// SetProposalResult makes getblocktemplate proposals return reason (e.g.
+// "bad-cb-amount") for every block until it is reset with ""
+func (n *Node) SetProposalResult(reason string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.proposalResult = reason
+}
+
+// txConfirmationsLocked returns confirmations for a wallet tx; n.mu must be held
+func (n *Node) txConfirmationsLocked(tx *walletTx) int64 {
+	if tx.height < 0 {
+		return 0
+	}
+	return n.confirmationsLocked(tx.height)
+}
+
+func (n *Node) getBalance(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	minConf := int64(0)
+	if err := p.decode(1, &minConf); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var balance rpc.Amount
+	for _, u := range n.wallet.utxos {
+		if n.txConfirmationsLocked(u.tx) >= minConf {
+			balance += u.amount
+		}
+	}
+	return balance, nil
+}
+
+func (n *Node) listUnspent(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	minConf, maxConf := int64(1), int64(9999999)
+	var addresses []string
+	if err := p.decode(0, &minConf); err != nil {
+		return nil, err
+	}
+	if err := p.decode(1, &maxConf); err != nil {
+		return nil, err
+	}
+	if err := p.decode(2, &addresses); err != nil {
+		return nil, err
+	}
+	filter := make(map[string]bool, len(addresses))
+	for _, a := range addresses {
+		filter[a] = true
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	result := []rpc.Unspent{}
+	for _, u := range n.wallet.utxos {
+		conf := n.txConfirmationsLocked(u.tx)
+		if conf < minConf || conf > maxConf || (len(filter) > 0 && !filter[u.address]) {
+			continue
+		}
+		result = append(result, rpc.Unspent{
+			TxID:          u.tx.txid,
+			Vout:          u.vout,
+			Address:       u.address,
+			ScriptPubKey:  ScriptPubKey(u.address),
+			Amount:        u.amount,
+			Confirmations: conf,
+			Spendable:     true,
+			Solvable:      true,
+			Safe:          conf > 0,
+		})
+	}
+	return result, nil
+}
+
+func (n *Node) sendMany(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	var amounts map[string]rpc.Amount
+	if err := p.decode(1, &amounts); err != nil {
+		return nil, err
+	}
+	if len(amounts) == 0 {
+		return nil, invalidParameter("Transaction must have at least one recipient")
+	}
+
+	feeRate := rpc.FeeRate(0)
+	if p.has(8) {
+		var satPerVB rpc.Amount
+		if err := p.decode(8, &satPerVB); err != nil {
+			return nil, err
+		}
+		feeRate = rpc.FeeRate(satPerVB / 100_000) // 1e-8 units -> sat/kvB
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	w := n.wallet
+	if feeRate == 0 {
+		feeRate = w.feeRate
+	}
+
+	addresses := make([]string, 0, len(amounts))
+	var total rpc.Amount
+	for address, amount := range amounts {
+		if !n.validAddress(address) {
+			return nil, &rpc.RPCError{Code: rpc.CodeInvalidAddress, Message: "Invalid OpenSY address: " + address}
+		}
+		if amount <= 0 {
+			return nil, &rpc.RPCError{Code: rpc.CodeTypeError, Message: "Invalid amount for send"}
+		}
+		addresses = append(addresses, address)
+		total += amount
+	}
+	sort.Strings(addresses)
+
+	vsize := int64(110 + 31*len(amounts))
+	fee := rpc.Amount(int64(feeRate) * vsize / 1000)
+
+	// Spend confirmed or own-change outputs, oldest first
+	var selected rpc.Amount
+	spent := 0
+	for _, u := range w.utxos {
+		if selected >= total+fee {
+			break
+		}
+		selected += u.amount
+		spent++
+	}
+	if selected < total+fee {
+		return nil, &rpc.RPCError{Code: rpc.CodeWalletInsufficientFunds, Message: "Insufficient funds"}
+	}
+
+	tx := &walletTx{txid: w.newTxID(), amount: -total, fee: -fee, height: -1}
+	for i, address := range addresses {
+		tx.details = append(tx.details, rpc.TxDetail{
+			Address:  address,
+			Category: "send",
+			Amount:   -amounts[address],
+			Vout:     uint32(i),
+			Fee:      -fee,
+		})
+	}
+	w.txs[tx.txid] = tx
+
+	w.utxos = w.utxos[spent:]
+	if change := selected - total - fee; change > 0 {
+		w.utxos = append(w.utxos, &utxo{tx: tx, vout: uint32(len(addresses)), address: "rpctest-change", amount: change})
+	}
+
+	n.updates++
+	n.notifyLocked()
+	return tx.txid, nil
+}
+
+func (n *Node) getTransaction(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	var txid string
+	if err := p.decode(0, &txid); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	tx, ok := n.wallet.txs[txid]
+	if !ok {
+		return nil, &rpc.RPCError{Code: rpc.CodeInvalidAddress, Message: "Invalid or non-wallet transaction id"}
+	}
+
+	result := rpc.WalletTransaction{
+		TxID:          tx.txid,
+		Amount:        tx.amount,
+		Fee:           tx.fee,
+		Confirmations: n.txConfirmationsLocked(tx),
+		Replaceable:   "no",
+		Details:       tx.details,
+	}
+	if tx.height >= 0 {
+		result.BlockHash = n.chain[tx.height].Hash
+		result.BlockHeight = tx.height
+		result.Time = n.chain[tx.height].Time
+	}
+	return result, nil
+}
+
+func (n *Node) walletProcessPSBT(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	var psbt string
+	sign := true
+	if err := p.decode(0, &psbt); err != nil {
+		return nil, err
+	}
+	if err := p.decode(1, &sign); err != nil {
+		return nil, err
+	}
+	return rpc.ProcessedPSBT{PSBT: psbt, Complete: sign}, nil
+}
+
+func (n *Node) estimateSmartFee(_ context.Context, p params) (interface{}, *rpc.RPCError) {
+	target := 6
+	if err := p.decode(0, &target); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return map[string]interface{}{
+		"feerate": rpc.Amount(n.wallet.feeRate), // SYL/kvB
+		"blocks":  target,
+	}, nil
+}
@@ -0,0 +1,287 @@
+// Package rpc - wallet.go provides a typed wallet RPC client for payouts
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// WalletClient calls wallet RPCs on a named wallet (/wallet/<name>).
+//
+// Wallets live on a single node, so wallet calls are pinned to the first
+// configured node instead of failing over. Read-only calls are retried on
+// transient errors; calls that move funds are never retried, since a lost
+// response does not mean the transaction was not broadcast.
+type WalletClient struct {
+	c    *Client
+	n    *node
+	name string
+	path string
+}
+
+// Wallet returns a client for the named wallet on the primary node
+func (c *Client) Wallet(name string) *WalletClient {
+	return &WalletClient{
+		c:    c,
+		n:    c.nodes[0],
+		name: name,
+		path: "/wallet/" + url.PathEscape(name),
+	}
+}
+
+// Name returns the wallet name
+func (w *WalletClient) Name() string {
+	return w.name
+}
+
+// call makes a wallet RPC; idempotent calls are retried on transient errors
+func (w *WalletClient) call(ctx context.Context, idempotent bool, method string, params []interface{}, result interface{}) error {
+	c := w.c
+	if c.cbEnabled && !c.cbAllow(w.n) {
+		return ErrCircuitOpen
+	}
+
+	attempts := 0
+	if idempotent {
+		attempts = c.retryAttempts
+	}
+
+	var err error
+	for attempt := 0; attempt <= attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.retryDelay * time.Duration(attempt)):
+			}
+		}
+
+		start := time.Now()
+		err = c.send(ctx, c.client, w.n, w.path, method, params, result)
+		if ctx.Err() == nil {
+			w.n.observe(time.Since(start), err)
+		}
+		if err == nil || !IsTransient(err) {
+			if ctx.Err() == nil {
+				c.cbRecordSuccess(w.n)
+			}
+			return err
+		}
+		c.logger.Warn("Wallet RPC failed", "wallet", w.name, "method", method, "attempt", attempt+1, "error", err)
+	}
+
+	c.cbRecordFailure(w.n)
+	return err
+}
+
+// GetBalance returns the wallet's trusted balance with at least minConf
+// confirmations
+func (w *WalletClient) GetBalance(ctx context.Context, minConf int) (Amount, error) {
+	var balance Amount
+	if err := w.call(ctx, true, "getbalance", []interface{}{"*", minConf}, &balance); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// Unspent is a wallet UTXO from listunspent
+type Unspent struct {
+	TxID          string `json:"txid"`
+	Vout          uint32 `json:"vout"`
+	Address       string `json:"address"`
+	Label         string `json:"label,omitempty"`
+	ScriptPubKey  string `json:"scriptPubKey"`
+	Amount        Amount `json:"amount"`
+	Confirmations int64  `json:"confirmations"`
+	Spendable     bool   `json:"spendable"`
+	Solvable      bool   `json:"solvable"`
+	Safe          bool   `json:"safe"`
+}
+
+// ListUnspent returns UTXOs with between minConf and maxConf confirmations,
+// optionally restricted to the given addresses
+func (w *WalletClient) ListUnspent(ctx context.Context, minConf, maxConf int, addresses ...string) ([]Unspent, error) {
+	if addresses == nil {
+		addresses = []string{}
+	}
+	var utxos []Unspent
+	if err := w.call(ctx, true, "listunspent", []interface{}{minConf, maxConf, addresses}, &utxos); err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+// SendOptions controls fee selection for SendMany
+type SendOptions struct {
+	Comment         string
+	SubtractFeeFrom []string // Addresses that pay the fee out of their amount
+	Replaceable     *bool    // Signal BIP125 replace-by-fee; nil keeps the wallet default
+	// FeeRate sets an explicit fee rate. When zero, ConfTarget (or the
+	// wallet default) is used for fee estimation.
+	FeeRate    FeeRate
+	ConfTarget int
+}
+
+// SendMany pays several addresses in one transaction and returns the txid.
+// It is never retried: check GetTransaction before resending after an error.
+func (w *WalletClient) SendMany(ctx context.Context, amounts map[string]Amount, opts SendOptions) (string, error) {
+	subtract := opts.SubtractFeeFrom
+	if subtract == nil {
+		subtract = []string{}
+	}
+
+	var replaceable, confTarget, feeRate interface{}
+	if opts.Replaceable != nil {
+		replaceable = *opts.Replaceable
+	}
+	estimateMode := "unset"
+	if opts.FeeRate > 0 {
+		feeRate = opts.FeeRate
+	} else if opts.ConfTarget > 0 {
+		confTarget = opts.ConfTarget
+		estimateMode = "economical"
+	}
+
+	params := []interface{}{
+		"", // dummy
+		amounts,
+		nil, // minconf (ignored)
+		opts.Comment,
+		subtract,
+		replaceable,
+		confTarget,
+		estimateMode,
+		feeRate,
+	}
+
+	var txid string
+	if err := w.call(ctx, false, "sendmany", params, &txid); err != nil {
+		return "", err
+	}
+	return txid, nil
+}
+
+// TxDetail is one wallet-relevant output of a transaction
+type TxDetail struct {
+	Address  string `json:"address"`
+	Category string `json:"category"` // send, receive, generate, immature, orphan
+	Amount   Amount `json:"amount"`
+	Vout     uint32 `json:"vout"`
+	Fee      Amount `json:"fee,omitempty"`
+}
+
+// WalletTransaction is the result of gettransaction
+type WalletTransaction struct {
+	TxID          string     `json:"txid"`
+	Amount        Amount     `json:"amount"`
+	Fee           Amount     `json:"fee,omitempty"`
+	Confirmations int64      `json:"confirmations"`
+	BlockHash     string     `json:"blockhash,omitempty"`
+	BlockHeight   int64      `json:"blockheight,omitempty"`
+	Time          int64      `json:"time"`
+	Replaceable   string     `json:"bip125-replaceable"`
+	Details       []TxDetail `json:"details"`
+	Hex           string     `json:"hex"`
+}
+
+// Conflicted reports whether the transaction conflicts with the main chain
+// (double spent or replaced); it will never confirm
+func (t *WalletTransaction) Conflicted() bool {
+	return t.Confirmations < 0
+}
+
+// GetTransaction returns a wallet transaction
+func (w *WalletClient) GetTransaction(ctx context.Context, txid string) (*WalletTransaction, error) {
+	var tx WalletTransaction
+	if err := w.call(ctx, true, "gettransaction", []interface{}{txid}, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// Confirmations returns the number of confirmations of a wallet transaction
+// (0 in the mempool, negative when conflicted)
+func (w *WalletClient) Confirmations(ctx context.Context, txid string) (int64, error) {
+	tx, err := w.GetTransaction(ctx, txid)
+	if err != nil {
+		return 0, err
+	}
+	return tx.Confirmations, nil
+}
+
+// WaitForConfirmations polls until txid has at least confirmations
+// confirmations, returning the final transaction. It fails if the
+// transaction becomes conflicted or ctx is cancelled.
+func (w *WalletClient) WaitForConfirmations(ctx context.Context, txid string, confirmations int64, poll time.Duration) (*WalletTransaction, error) {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	for {
+		tx, err := w.GetTransaction(ctx, txid)
+		if err != nil {
+			return nil, err
+		}
+		if tx.Conflicted() {
+			return tx, fmt.Errorf("transaction %s conflicted (%d confirmations)", txid, tx.Confirmations)
+		}
+		if tx.Confirmations >= confirmations {
+			return tx, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return tx, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessedPSBT is the result of walletprocesspsbt
+type ProcessedPSBT struct {
+	PSBT     string `json:"psbt"`
+	Complete bool   `json:"complete"`
+	Hex      string `json:"hex,omitempty"` // Final transaction when complete
+}
+
+// WalletProcessPSBT updates a base64 PSBT with wallet inputs and, if sign
+// is set, signs it
+func (w *WalletClient) WalletProcessPSBT(ctx context.Context, psbt string, sign bool) (*ProcessedPSBT, error) {
+	var result ProcessedPSBT
+	if err := w.call(ctx, false, "walletprocesspsbt", []interface{}{psbt, sign}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FeeEstimate is the result of estimatesmartfee
+type FeeEstimate struct {
+	FeeRate FeeRate  // Zero when the node has no estimate
+	Blocks  int      // Target the estimate is valid for
+	Errors  []string // Reasons no estimate was available
+}
+
+// EstimateSmartFee estimates the fee rate needed to confirm within
+// confTarget blocks. mode is "economical", "conservative" or "" (default).
+func (c *Client) EstimateSmartFee(ctx context.Context, confTarget int, mode string) (*FeeEstimate, error) {
+	params := []interface{}{confTarget}
+	if mode != "" {
+		params = append(params, mode)
+	}
+
+	var result struct {
+		FeeRate *Amount  `json:"feerate"` // SYL per kvB
+		Blocks  int      `json:"blocks"`
+		Errors  []string `json:"errors"`
+	}
+	if err := c.Call(ctx, "estimatesmartfee", params, &result); err != nil {
+		return nil, err
+	}
+
+	estimate := &FeeEstimate{Blocks: result.Blocks, Errors: result.Errors}
+	if result.FeeRate != nil {
+		estimate.FeeRate = FeeRate(*result.FeeRate)
+	}
+	return estimate, nil
+}
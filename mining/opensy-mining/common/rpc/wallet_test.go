@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAmountRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Amount
+		out  string
+	}{
+		{"0.1", 10_000_000, "0.10000000"},
+		{"0.00000001", 1, "0.00000001"},
+		{"21000000000", 21_000_000_000 * SYL, "21000000000.00000000"},
+		{"-1.5", -150_000_000, "-1.50000000"},
+		{"0.30000000", 30_000_000, "0.30000000"},
+	}
+
+	for _, tt := range tests {
+		var a Amount
+		if err := json.Unmarshal([]byte(tt.in), &a); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", tt.in, err)
+		}
+		if a != tt.want {
+			t.Errorf("Unmarshal(%s) = %d, want %d", tt.in, a, tt.want)
+		}
+		out, _ := json.Marshal(a)
+		if string(out) != tt.out {
+			t.Errorf("Marshal(%d) = %s, want %s", a, out, tt.out)
+		}
+	}
+
+	for _, bad := range []string{"0.000000001", "1e-8", "abc", "", "-", ".", "-."} {
+		if _, err := ParseAmount(bad); err == nil {
+			t.Errorf("ParseAmount(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestWalletSendMany(t *testing.T) {
+	var gotPath string
+	var gotParams []json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		var req struct {
+			ID     uint64            `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		gotParams = req.Params
+		json.NewEncoder(w).Encode(Response{JSONRPC: "2.0", ID: req.ID, Result: json.RawMessage(`"txid1"`)})
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClientWithConfig(testConfig(srv.URL))
+	txid, err := c.Wallet("pool payouts").SendMany(context.Background(),
+		map[string]Amount{"syl1qexample": 12_345_678_901},
+		SendOptions{FeeRate: 2500})
+	if err != nil || txid != "txid1" {
+		t.Fatalf("SendMany = %q, %v", txid, err)
+	}
+
+	if gotPath != "/wallet/pool payouts" {
+		t.Errorf("path = %q, want /wallet/pool payouts", gotPath)
+	}
+	if string(gotParams[1]) != `{"syl1qexample":123.45678901}` {
+		t.Errorf("amounts = %s", gotParams[1])
+	}
+	if string(gotParams[5]) != "null" {
+		t.Errorf("replaceable = %s, want null for the wallet default", gotParams[5])
+	}
+	if string(gotParams[7]) != `"unset"` || string(gotParams[8]) != "2.500" {
+		t.Errorf("fee params = %s %s, want \"unset\" 2.500", gotParams[7], gotParams[8])
+	}
+}
@@ -0,0 +1,308 @@
+// Package rpc - zmq.go implements a minimal ZMTP 3.0 subscriber for node notifications
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+)
+
+// ZMQ notification topics published by the node (-zmqpub<topic>=<addr>)
+const (
+	TopicHashBlock = "hashblock"
+	TopicRawBlock  = "rawblock"
+)
+
+// ZMTP frame flags
+const (
+	zmtpFlagMore    = 0x01
+	zmtpFlagLong    = 0x02
+	zmtpFlagCommand = 0x04
+)
+
+// maxZMQFrameSize bounds a single frame (rawblock is at most a few MB)
+const maxZMQFrameSize = 32 << 20
+
+// ErrZMQProtocol is returned when the peer violates ZMTP
+var ErrZMQProtocol = errors.New("zmq: protocol error")
+
+// ZMQMessage is a notification received from the node
+type ZMQMessage struct {
+	Topic    string
+	Body     []byte
+	Sequence uint32
+}
+
+// BlockHash returns the block hash (RPC byte order) carried by a hashblock
+// or rawblock notification
+func (m *ZMQMessage) BlockHash() (string, bool) {
+	switch m.Topic {
+	case TopicHashBlock:
+		if len(m.Body) != 32 {
+			return "", false
+		}
+		return hex.EncodeToString(m.Body), true
+	case TopicRawBlock:
+		if len(m.Body) < 80 {
+			return "", false
+		}
+		first := sha256.Sum256(m.Body[:80])
+		second := sha256.Sum256(first[:])
+		for i := 0; i < 16; i++ {
+			second[i], second[31-i] = second[31-i], second[i]
+		}
+		return hex.EncodeToString(second[:]), true
+	default:
+		return "", false
+	}
+}
+
+// ZMQConfig holds ZMQ subscriber configuration
+type ZMQConfig struct {
+	Addr           string   // Publisher endpoint, e.g. tcp://127.0.0.1:28332
+	Topics         []string // Topics to subscribe to
+	DialTimeout    time.Duration
+	ReconnectDelay time.Duration
+	Logger         *slog.Logger
+}
+
+// DefaultZMQConfig returns default configuration subscribed to hashblock
+func DefaultZMQConfig(addr string) ZMQConfig {
+	return ZMQConfig{
+		Addr:           addr,
+		Topics:         []string{TopicHashBlock},
+		DialTimeout:    5 * time.Second,
+		ReconnectDelay: 2 * time.Second,
+		Logger:         slog.Default(),
+	}
+}
+
+// ZMQSubscriber is a pure-Go ZMQ SUB socket speaking ZMTP 3.0 with the
+// NULL security mechanism, which is what the node's publisher offers
+type ZMQSubscriber struct {
+	cfg    ZMQConfig
+	logger *slog.Logger
+
+	// OnMessage is called for every notification received
+	OnMessage func(msg *ZMQMessage)
+}
+
+// NewZMQSubscriber creates a new subscriber
+func NewZMQSubscriber(cfg ZMQConfig) *ZMQSubscriber {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = 2 * time.Second
+	}
+	return &ZMQSubscriber{
+		cfg:    cfg,
+		logger: cfg.Logger.With("component", "zmq-sub", "addr", cfg.Addr),
+	}
+}
+
+// Run connects to the publisher and delivers messages until ctx is
+// cancelled, reconnecting on any error
+func (s *ZMQSubscriber) Run(ctx context.Context) {
+	for {
+		err := s.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Warn("ZMQ connection lost, reconnecting", "error", err, "delay", s.cfg.ReconnectDelay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.cfg.ReconnectDelay):
+		}
+	}
+}
+
+func (s *ZMQSubscriber) session(ctx context.Context) error {
+	addr := strings.TrimPrefix(s.cfg.Addr, "tcp://")
+	dialer := net.Dialer{Timeout: s.cfg.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock reads on shutdown
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	if err := zmtpHandshake(rw, "SUB"); err != nil {
+		return err
+	}
+
+	for _, topic := range s.cfg.Topics {
+		// ZMTP 3.0 subscriptions are messages prefixed with 0x01
+		if err := writeZMTPFrame(rw.Writer, 0, append([]byte{1}, topic...)); err != nil {
+			return fmt.Errorf("subscribe: %w", err)
+		}
+	}
+	if err := rw.Flush(); err != nil {
+		return fmt.Errorf("subscribe: %w", err)
+	}
+
+	s.logger.Info("ZMQ subscribed", "topics", s.cfg.Topics)
+
+	lastSeq := make(map[string]uint32)
+	for {
+		parts, err := readZMTPMessage(rw.Reader)
+		if err != nil {
+			return err
+		}
+		if len(parts) < 2 {
+			continue
+		}
+
+		msg := &ZMQMessage{Topic: string(parts[0]), Body: parts[1]}
+		if len(parts) >= 3 && len(parts[2]) == 4 {
+			msg.Sequence = binary.LittleEndian.Uint32(parts[2])
+			if prev, ok := lastSeq[msg.Topic]; ok && msg.Sequence != prev+1 {
+				s.logger.Warn("ZMQ notifications missed", "topic", msg.Topic, "expected", prev+1, "got", msg.Sequence)
+			}
+			lastSeq[msg.Topic] = msg.Sequence
+		}
+
+		if s.OnMessage != nil {
+			s.OnMessage(msg)
+		}
+	}
+}
+
+// zmtpHandshake exchanges greetings and READY commands with the peer
+func zmtpHandshake(rw *bufio.ReadWriter, socketType string) error {
+	greeting := make([]byte, 64)
+	greeting[0] = 0xff
+	greeting[9] = 0x7f
+	greeting[10] = 3 // Version 3.0
+	greeting[11] = 0
+	copy(greeting[12:32], "NULL")
+	if _, err := rw.Write(greeting); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+
+	ready := []byte{5}
+	ready = append(ready, "READY"...)
+	ready = append(ready, byte(len("Socket-Type")))
+	ready = append(ready, "Socket-Type"...)
+	ready = binary.BigEndian.AppendUint32(ready, uint32(len(socketType)))
+	ready = append(ready, socketType...)
+	if err := writeZMTPFrame(rw.Writer, zmtpFlagCommand, ready); err != nil {
+		return fmt.Errorf("ready: %w", err)
+	}
+	if err := rw.Flush(); err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+
+	peer := make([]byte, 64)
+	if _, err := io.ReadFull(rw, peer); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	if peer[0] != 0xff || peer[9] != 0x7f || peer[10] < 3 {
+		return fmt.Errorf("%w: unsupported greeting", ErrZMQProtocol)
+	}
+	if mech := strings.TrimRight(string(peer[12:32]), "\x00"); mech != "NULL" {
+		return fmt.Errorf("%w: unsupported mechanism %q", ErrZMQProtocol, mech)
+	}
+
+	flags, body, err := readZMTPFrame(rw.Reader)
+	if err != nil {
+		return fmt.Errorf("ready: %w", err)
+	}
+	if flags&zmtpFlagCommand == 0 || len(body) < 6 || string(body[1:6]) != "READY" {
+		return fmt.Errorf("%w: expected READY", ErrZMQProtocol)
+	}
+	return nil
+}
+
+func writeZMTPFrame(w *bufio.Writer, flags byte, body []byte) error {
+	if len(body) > 255 {
+		flags |= zmtpFlagLong
+	}
+	if err := w.WriteByte(flags); err != nil {
+		return err
+	}
+	var err error
+	if flags&zmtpFlagLong != 0 {
+		var size [8]byte
+		binary.BigEndian.PutUint64(size[:], uint64(len(body)))
+		_, err = w.Write(size[:])
+	} else {
+		err = w.WriteByte(byte(len(body)))
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func readZMTPFrame(r *bufio.Reader) (byte, []byte, error) {
+	flags, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var size uint64
+	if flags&zmtpFlagLong != 0 {
+		var buf [8]byte
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, nil, err
+		}
+		size = binary.BigEndian.Uint64(buf[:])
+	} else {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		size = uint64(b)
+	}
+	if size > maxZMQFrameSize {
+		return 0, nil, fmt.Errorf("%w: frame too large (%d bytes)", ErrZMQProtocol, size)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return flags, body, nil
+}
+
+// readZMTPMessage reads one multipart message, skipping command frames
+func readZMTPMessage(r *bufio.Reader) ([][]byte, error) {
+	var parts [][]byte
+	for {
+		flags, body, err := readZMTPFrame(r)
+		if err != nil {
+			return nil, err
+		}
+		if flags&zmtpFlagCommand != 0 {
+			continue // PING and friends
+		}
+		parts = append(parts, body)
+		if flags&zmtpFlagMore == 0 {
+			return parts, nil
+		}
+	}
+}
@@ -13,21 +13,70 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/opensyria/opensy-mining/common/randomx"
 	"github.com/opensyria/opensy-mining/coopmine"
+	"github.com/opensyria/opensy-mining/coopmine/config"
 )
 
 func main() {
 	var (
+		configPath      = flag.String("config", "", "Worker YAML config file (command-line flags override it)")
 		coordinatorAddr = flag.String("coordinator", "localhost:5555", "Coordinator address")
 		workerID        = flag.String("worker-id", "", "Worker ID (auto-generated if empty)")
 		workerName      = flag.String("worker-name", "", "Worker name (auto-generated if empty)")
 		threads         = flag.Int("threads", 0, "Mining threads (0 = auto-detect)")
+		hugePages       = flag.Bool("huge-pages", true, "Use huge pages for RandomX memory (falls back to normal pages)")
+		fullDataset     = flag.Bool("full-dataset", false, "Mine on the ~2 GB RandomX dataset instead of light mode")
+		sharedDataset   = flag.String("shared-dataset", "", "Directory (e.g. /dev/shm) for a full dataset shared by processes on this host")
+		benchReport     = flag.String("bench-report", "", "randomx-bench JSON report to pick the thread count from")
 		logLevel        = flag.String("log-level", "info", "Log level: debug, info, warn, error")
 		logFormat       = flag.String("log-format", "text", "Log format: text or json")
 	)
 
 	flag.Parse()
 
+	if *configPath != "" {
+		fileCfg, err := config.LoadWorkerConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Flags given on the command line take precedence over the file
+		set := make(map[string]bool)
+		flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["coordinator"] && fileCfg.Coordinator.Address != "" {
+			*coordinatorAddr = fileCfg.Coordinator.Address
+		}
+		if !set["worker-id"] && fileCfg.Worker.ID != "" {
+			*workerID = fileCfg.Worker.ID
+		}
+		if !set["worker-name"] && fileCfg.Worker.Name != "" {
+			*workerName = fileCfg.Worker.Name
+		}
+		if !set["threads"] && fileCfg.Mining.Threads > 0 {
+			*threads = fileCfg.Mining.Threads
+		}
+		if !set["huge-pages"] {
+			*hugePages = fileCfg.Mining.HugePages
+		}
+		if !set["full-dataset"] {
+			*fullDataset = fileCfg.Mining.Flags.FullMem
+		}
+		if !set["shared-dataset"] && fileCfg.Mining.SharedDatasetDir != "" {
+			*sharedDataset = fileCfg.Mining.SharedDatasetDir
+		}
+		if !set["bench-report"] && fileCfg.Mining.BenchReport != "" {
+			*benchReport = fileCfg.Mining.BenchReport
+		}
+		if !set["log-level"] && fileCfg.Logging.Level != "" {
+			*logLevel = fileCfg.Logging.Level
+		}
+		if !set["log-format"] && fileCfg.Logging.Format != "" {
+			*logFormat = fileCfg.Logging.Format
+		}
+	}
+
 	if *workerID == "" {
 		b := make([]byte, 4)
 		rand.Read(b)
@@ -42,6 +91,21 @@ func main() {
 		*workerName = fmt.Sprintf("%s-%s", hostname, (*workerID)[:4])
 	}
 
+	if *benchReport != "" {
+		report, err := randomx.LoadBenchReport(*benchReport)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load bench report: %v\n", err)
+			os.Exit(1)
+		}
+		if report.SelfTest != "ok" {
+			fmt.Fprintf(os.Stderr, "Bench report self-test failed, refusing to mine: %s\n", report.SelfTest)
+			os.Exit(1)
+		}
+		if best, ok := report.Best(*fullDataset); ok && *threads <= 0 {
+			*threads = best.Threads
+		}
+	}
+
 	if *threads <= 0 {
 		*threads = runtime.NumCPU()
 	}
@@ -65,17 +129,22 @@ func main() {
 		"worker_name", *workerName,
 		"coordinator", *coordinatorAddr,
 		"threads", *threads,
+		"huge_pages", *hugePages,
+		"full_dataset", *fullDataset,
 		"os", runtime.GOOS,
 		"arch", runtime.GOARCH,
 	)
 
 	cfg := coopmine.ServiceConfig{
-		Mode:            "worker",
-		CoordinatorAddr: *coordinatorAddr,
-		WorkerID:        *workerID,
-		WorkerName:      *workerName,
-		Threads:         *threads,
-		Logger:          logger,
+		Mode:             "worker",
+		CoordinatorAddr:  *coordinatorAddr,
+		WorkerID:         *workerID,
+		WorkerName:       *workerName,
+		Threads:          *threads,
+		HugePages:        *hugePages,
+		FullDataset:      *fullDataset,
+		SharedDatasetDir: *sharedDataset,
+		Logger:           logger,
 	}
 
 	service := coopmine.NewService(cfg)
@@ -142,6 +211,7 @@ func statsReporter(service *coopmine.Service, logger *slog.Logger) {
 			"shares_valid", stats.SharesValid,
 			"shares_invalid", stats.SharesInvalid,
 			"threads", stats.Threads,
+			"huge_pages", stats.HugePages,
 			"mining", stats.Mining,
 			"coordinator", stats.CoordinatorConnected,
 			"uptime", stats.Uptime.Round(time.Second),
@@ -125,6 +125,8 @@ type MiningConfig struct {
 	HugePages        bool          `yaml:"huge_pages"`
 	Flags            RandomXFlags  `yaml:"flags"`
 	HashrateInterval time.Duration `yaml:"hashrate_interval"`
+	BenchReport      string        `yaml:"bench_report"` // randomx-bench JSON used when threads is 0
+	SharedDatasetDir string        `yaml:"shared_dataset_dir"`
 }
 
 // RandomXFlags holds RandomX-specific flags
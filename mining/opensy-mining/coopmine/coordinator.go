@@ -9,9 +9,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
 )
 
 // ClusterConfig holds cluster configuration
@@ -26,7 +30,10 @@ type ClusterConfig struct {
 	HeartbeatInt     time.Duration
 	WorkerTimeout    time.Duration
 	JobTimeout       time.Duration
-	Logger           *slog.Logger
+	// Hashers, when set, recomputes every worker share before it is
+	// forwarded upstream; nil trusts workers' results
+	Hashers randomx.HasherFactory
+	Logger  *slog.Logger
 }
 
 // DefaultClusterConfig returns default configuration
@@ -50,6 +57,8 @@ type WorkerInfo struct {
 	JoinedAt      time.Time
 	CurrentJob    string
 	Status        WorkerStatus
+
+	extraNonces map[string]uint32 // Job ID -> extra nonce sent to the worker
 }
 
 // WorkerStatus represents worker state
@@ -117,6 +126,11 @@ type Coordinator struct {
 	blocksFound   atomic.Uint64
 	startTime     time.Time
 
+	// Share verification, for the most recent seed
+	hasher     randomx.Hasher
+	hasherSeed string
+	hasherMu   sync.Mutex
+
 	// Upstream pool connection (handled separately)
 	poolJobChan chan *Job
 
@@ -179,6 +193,14 @@ func (c *Coordinator) Stop() {
 	c.logger.Info("Stopping coordinator")
 	c.cancel()
 	c.wg.Wait()
+
+	c.hasherMu.Lock()
+	if c.hasher != nil {
+		c.hasher.Close()
+		c.hasher = nil
+	}
+	c.hasherMu.Unlock()
+
 	c.logger.Info("Coordinator stopped")
 }
 
@@ -262,10 +284,11 @@ func (c *Coordinator) GetJobForWorker(workerID string) (*Job, error) {
 	}
 	worker.CurrentJob = currentJob.ID
 	worker.Status = WorkerMining
-	c.workersMu.Unlock()
 
 	// Create worker-specific job with unique extra nonce
 	extraNonce := c.extraNonce.Add(1)
+	worker.setExtraNonce(currentJob.ID, extraNonce)
+	c.workersMu.Unlock()
 	workerJob := &Job{
 		ID:         currentJob.ID,
 		Blob:       currentJob.Blob,
@@ -289,6 +312,7 @@ func (c *Coordinator) SubmitShare(share *Share) (bool, error) {
 		c.workersMu.Unlock()
 		return false, fmt.Errorf("worker %s not found", share.WorkerID)
 	}
+	extraNonce := worker.extraNonces[share.JobID]
 	c.workersMu.Unlock()
 
 	// Verify job exists
@@ -310,6 +334,17 @@ func (c *Coordinator) SubmitShare(share *Share) (bool, error) {
 		return false, fmt.Errorf("job %s expired", share.JobID)
 	}
 
+	// Recompute the hash rather than trusting the worker
+	if c.cfg.Hashers != nil {
+		if err := c.verifyShare(job, extraNonce, share); err != nil {
+			c.sharesInvalid.Add(1)
+			c.workersMu.Lock()
+			worker.SharesInvalid++
+			c.workersMu.Unlock()
+			return false, err
+		}
+	}
+
 	// Forward share to upstream pool
 	if c.OnShareAccepted != nil {
 		accepted, err := c.OnShareAccepted(share.JobID, share.Nonce, share.Result)
@@ -353,8 +388,22 @@ func (c *Coordinator) SetJob(job *Job) {
 			delete(c.jobHistory, id)
 		}
 	}
+	live := make(map[string]bool, len(c.jobHistory))
+	for id := range c.jobHistory {
+		live[id] = true
+	}
 	c.jobMu.Unlock()
 
+	c.workersMu.Lock()
+	for _, worker := range c.workers {
+		for id := range worker.extraNonces {
+			if !live[id] {
+				delete(worker.extraNonces, id)
+			}
+		}
+	}
+	c.workersMu.Unlock()
+
 	c.logger.Info("New job received",
 		"job_id", job.ID,
 		"height", job.Height,
@@ -367,6 +416,69 @@ func (c *Coordinator) SetJob(job *Job) {
 	}
 }
 
+// verifyShare recomputes a share's hash with the extra nonce the worker was
+// given and checks it against the claimed result and the job target
+func (c *Coordinator) verifyShare(job *Job, extraNonce uint32, share *Share) error {
+	nonce, err := strconv.ParseUint(share.Nonce, 16, 32)
+	if err != nil {
+		return fmt.Errorf("invalid nonce %q", share.Nonce)
+	}
+	hasher, err := c.hasherFor(job.SeedHash)
+	if err != nil {
+		return err
+	}
+
+	workerJob := *job
+	workerJob.ExtraNonce = extraNonce
+	hash, err := hasher.Hash(c.ctx, buildHeader(&workerJob, uint32(nonce)))
+	if err != nil {
+		return fmt.Errorf("hash calculation failed: %w", err)
+	}
+	if hex.EncodeToString(hash[:]) != share.Result {
+		return fmt.Errorf("invalid hash")
+	}
+	if !checkTarget(hash[:], job.Target) {
+		return fmt.Errorf("low difficulty")
+	}
+	return nil
+}
+
+// hasherFor returns the hasher for seedHash, replacing the previous seed's.
+// Only one seed is kept, so late shares across a key change rebuild it.
+func (c *Coordinator) hasherFor(seedHash string) (randomx.Hasher, error) {
+	c.hasherMu.Lock()
+	defer c.hasherMu.Unlock()
+
+	if c.hasher != nil && c.hasherSeed == seedHash {
+		return c.hasher, nil
+	}
+	seed, err := hex.DecodeString(seedHash)
+	if err != nil || len(seed) == 0 {
+		return nil, fmt.Errorf("invalid seed hash %q", seedHash)
+	}
+	hasher, err := c.cfg.Hashers.NewHasher(seed, randomx.HasherConfig{
+		VMs:   runtime.NumCPU(),
+		Flags: randomx.FlagDefault,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hasher: %w", err)
+	}
+	if c.hasher != nil {
+		go c.hasher.Close() // Waits for in-flight verifications
+	}
+	c.hasher, c.hasherSeed = hasher, seedHash
+	return hasher, nil
+}
+
+// setExtraNonce records the extra nonce sent with a job; workersMu must be
+// held
+func (w *WorkerInfo) setExtraNonce(jobID string, extraNonce uint32) {
+	if w.extraNonces == nil {
+		w.extraNonces = make(map[string]uint32)
+	}
+	w.extraNonces[jobID] = extraNonce
+}
+
 // GetStats returns cluster statistics
 func (c *Coordinator) GetStats() *ClusterStats {
 	c.workersMu.RLock()
@@ -496,10 +608,11 @@ func (c *Coordinator) GetWorker(id string) *WorkerInfo {
 	return c.workers[id]
 }
 
-// GetWorkerExtraNonce returns the extra nonce for a worker
-func (c *Coordinator) GetWorkerExtraNonce(workerID string) uint32 {
-	c.workersMu.RLock()
-	defer c.workersMu.RUnlock()
+// GetWorkerExtraNonce returns the extra nonce for a worker and records it
+// against jobID for share verification
+func (c *Coordinator) GetWorkerExtraNonce(workerID, jobID string) uint32 {
+	c.workersMu.Lock()
+	defer c.workersMu.Unlock()
 	if w, ok := c.workers[workerID]; ok {
 		// Use worker index as extra nonce to ensure uniqueness
 		var idx uint32
@@ -509,7 +622,7 @@ func (c *Coordinator) GetWorkerExtraNonce(workerID string) uint32 {
 			}
 			idx++
 		}
-		_ = w // Use w if needed
+		w.setExtraNonce(jobID, idx)
 		return idx
 	}
 	return 0
@@ -1,8 +1,13 @@
 package coopmine
 
 import (
+	"encoding/hex"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx/randomxtest"
 )
 
 func TestCoordinatorBasic(t *testing.T) {
@@ -308,3 +313,54 @@ func TestWorkerTimeout(t *testing.T) {
 		t.Errorf("Expected worker status Offline, got %v", worker.Status)
 	}
 }
+
+func TestCoordinatorVerifiesShares(t *testing.T) {
+	cfg := ClusterConfig{
+		ClusterID:    "test-verify",
+		HeartbeatInt: 100 * time.Millisecond,
+		JobTimeout:   5 * time.Second,
+		Hashers:      randomxtest.FakeHasherFactory{},
+	}
+
+	coord := NewCoordinator(cfg)
+	if err := coord.Start(); err != nil {
+		t.Fatalf("Failed to start coordinator: %v", err)
+	}
+	defer coord.Stop()
+
+	coord.RegisterWorker("w1", "Worker 1", "127.0.0.1:5000")
+	coord.SetJob(&Job{
+		ID:        "job-1",
+		Blob:      strings.Repeat("00", 80),
+		Target:    "ffffffff",
+		SeedHash:  strings.Repeat("ab", 32),
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	})
+	job, err := coord.GetJobForWorker("w1")
+	if err != nil {
+		t.Fatalf("GetJobForWorker: %v", err)
+	}
+
+	// The result must be the hash of the worker's own header
+	const nonce = 0x1234
+	seed, _ := hex.DecodeString(job.SeedHash)
+	hash := randomxtest.FakeHash(seed, buildHeader(job, nonce), 0)
+	share := &Share{
+		WorkerID: "w1",
+		JobID:    "job-1",
+		Nonce:    fmt.Sprintf("%08x", nonce),
+		Result:   hex.EncodeToString(hash[:]),
+	}
+	if ok, err := coord.SubmitShare(share); !ok || err != nil {
+		t.Fatalf("valid share rejected: %v", err)
+	}
+
+	share.Nonce = fmt.Sprintf("%08x", nonce+1)
+	if ok, _ := coord.SubmitShare(share); ok {
+		t.Error("share with mismatched result accepted")
+	}
+	if stats := coord.GetStats(); stats.SharesValid != 1 || stats.SharesInvalid != 1 {
+		t.Errorf("valid=%d invalid=%d, want 1 and 1", stats.SharesValid, stats.SharesInvalid)
+	}
+}
@@ -343,7 +343,7 @@ func (s *GRPCServer) broadcastJob(job *Job) {
 	defer s.jobStreamsMu.RUnlock()
 
 	for workerID, ch := range s.jobStreams {
-		extraNonce := s.coordinator.GetWorkerExtraNonce(workerID)
+		extraNonce := s.coordinator.GetWorkerExtraNonce(workerID, job.ID)
 
 		msg := s.jobToProtoWithNonce(job, extraNonce)
 
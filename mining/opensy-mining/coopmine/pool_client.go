@@ -97,11 +97,16 @@ type stratumError struct {
 	Message string `json:"message"`
 }
 
+// poolAlgo is the algorithm the coordinator's workers hash: RandomX over
+// the 80-byte header with the nonce at offset 76
+const poolAlgo = "rx/opensy"
+
 type loginParams struct {
-	Login string `json:"login"`
-	Pass  string `json:"pass"`
-	Agent string `json:"agent"`
-	RigID string `json:"rigid,omitempty"`
+	Login string   `json:"login"`
+	Pass  string   `json:"pass"`
+	Agent string   `json:"agent"`
+	RigID string   `json:"rigid,omitempty"`
+	Algo  []string `json:"algo,omitempty"`
 }
 
 type loginResult struct {
@@ -221,6 +226,7 @@ func (pc *PoolClient) Login() error {
 		Pass:  pc.cfg.Password,
 		Agent: "OpenSY-CoopMine/1.0.0",
 		RigID: pc.cfg.RigID,
+		Algo:  []string{poolAlgo},
 	}
 
 	result, err := pc.call("login", params)
@@ -28,10 +28,13 @@ type ServiceConfig struct {
 	PoolPass   string
 
 	// Worker settings (worker mode)
-	CoordinatorAddr string
-	WorkerID        string
-	WorkerName      string
-	Threads         int
+	CoordinatorAddr  string
+	WorkerID         string
+	WorkerName       string
+	Threads          int
+	HugePages        bool
+	FullDataset      bool
+	SharedDatasetDir string
 
 	// Common
 	Logger *slog.Logger
@@ -208,11 +211,14 @@ func (s *Service) startWorker() error {
 
 	// Create worker
 	workerCfg := WorkerConfig{
-		WorkerID:        s.cfg.WorkerID,
-		WorkerName:      s.cfg.WorkerName,
-		CoordinatorAddr: s.cfg.CoordinatorAddr,
-		Threads:         s.cfg.Threads,
-		Logger:          s.cfg.Logger,
+		WorkerID:         s.cfg.WorkerID,
+		WorkerName:       s.cfg.WorkerName,
+		CoordinatorAddr:  s.cfg.CoordinatorAddr,
+		Threads:          s.cfg.Threads,
+		HugePages:        s.cfg.HugePages,
+		FullDataset:      s.cfg.FullDataset,
+		SharedDatasetDir: s.cfg.SharedDatasetDir,
+		Logger:           s.cfg.Logger,
 	}
 	s.worker = NewWorker(workerCfg)
 
@@ -334,6 +340,9 @@ func (s *Service) GetStats() ServiceStats {
 			stats.TotalHashrate = workerStats.Hashrate
 			stats.SharesValid = workerStats.SharesValid
 			stats.SharesInvalid = workerStats.SharesInvalid
+			stats.Mining = workerStats.Mining
+			stats.Threads = workerStats.Threads
+			stats.HugePages = workerStats.HugePages
 		}
 		if s.grpcClient != nil {
 			stats.CoordinatorConnected = s.grpcClient.IsConnected()
@@ -357,6 +366,7 @@ type ServiceStats struct {
 	CoordinatorConnected bool
 	Mining               bool
 	Threads              int
+	HugePages            bool // Worker obtained huge pages for RandomX
 }
 
 // GetCoordinator returns the coordinator (coordinator mode only)
@@ -1,12 +1,12 @@
-//go:build cgo && randomx
-
 // Package coopmine - worker.go implements the worker node that connects to a coordinator
 package coopmine
 
 import (
 	"context"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
@@ -21,9 +21,18 @@ type WorkerConfig struct {
 	WorkerID        string
 	WorkerName      string
 	CoordinatorAddr string
-	Threads         int // Mining threads (0 = auto)
-	HeartbeatInt    time.Duration
-	Logger          *slog.Logger
+	Threads         int  // Mining threads (0 = auto)
+	HugePages       bool // Try large pages for RandomX memory, falling back to normal pages
+	FullDataset     bool // Hash on the ~2 GB dataset instead of light mode
+	// SharedDatasetDir maps the dataset from a per-seed file in this
+	// directory (e.g. /dev/shm), shared with other workers and the pool
+	// on the host. Empty allocates a private dataset.
+	SharedDatasetDir string
+	HeartbeatInt     time.Duration
+	// Hashers builds the RandomX hasher for each seed; nil uses the RandomX
+	// library, which fails with randomx.ErrUnavailable in non-cgo builds
+	Hashers randomx.HasherFactory
+	Logger  *slog.Logger
 }
 
 // DefaultWorkerConfig returns default configuration
@@ -41,14 +50,13 @@ type Worker struct {
 	logger *slog.Logger
 
 	// RandomX
-	rxCache  *randomx.Cache
-	rxVMs    []*randomx.VM
+	hasher   randomx.Hasher
+	threads  int
 	rxMu     sync.RWMutex
 	seedHash string
 
-	// Current job
-	currentJob *Job
-	jobMu      sync.RWMutex
+	// Current job, decoded once for the mining threads
+	work atomic.Pointer[workState]
 
 	// Mining state
 	mining    atomic.Bool
@@ -69,11 +77,28 @@ type Worker struct {
 	wg     sync.WaitGroup
 }
 
+// workState is everything a mining thread needs for one job, prepared once
+// in SetJob so the hot loop never decodes hex or takes a lock
+type workState struct {
+	job     *Job
+	header  []byte // Blob with the extra nonce applied
+	target  []byte // Decoded share target
+	hasher  randomx.Hasher
+	threads int
+}
+
+// hashCountBatch is how many hashes a mining thread counts locally before
+// adding them to the shared counter
+const hashCountBatch = 256
+
 // NewWorker creates a new mining worker
 func NewWorker(cfg WorkerConfig) *Worker {
 	if cfg.Logger == nil {
 		cfg.Logger = slog.Default()
 	}
+	if cfg.Hashers == nil {
+		cfg.Hashers = randomx.DefaultHasherFactory()
+	}
 
 	if cfg.WorkerID == "" {
 		b := make([]byte, 4)
@@ -125,13 +150,9 @@ func (w *Worker) Stop() {
 
 	// Cleanup RandomX
 	w.rxMu.Lock()
-	for _, vm := range w.rxVMs {
-		if vm != nil {
-			vm.Close()
-		}
-	}
-	if w.rxCache != nil {
-		w.rxCache.Close()
+	if w.hasher != nil {
+		w.hasher.Close()
+		w.hasher = nil
 	}
 	w.rxMu.Unlock()
 
@@ -140,17 +161,38 @@ func (w *Worker) Stop() {
 
 // SetJob sets a new job to mine
 func (w *Worker) SetJob(job *Job) error {
-	w.jobMu.Lock()
-	w.currentJob = job
-	w.jobMu.Unlock()
+	header, err := hex.DecodeString(job.Blob)
+	if err != nil {
+		return fmt.Errorf("invalid job blob: %w", err)
+	}
+	target, err := hex.DecodeString(job.Target)
+	if err != nil {
+		return fmt.Errorf("invalid job target: %w", err)
+	}
 
 	// Check if seed hash changed
+	var old randomx.Hasher
 	if job.SeedHash != w.seedHash {
-		if err := w.updateSeed(job.SeedHash); err != nil {
+		if old, err = w.updateSeed(job.SeedHash); err != nil {
 			return fmt.Errorf("failed to update seed: %w", err)
 		}
 	}
 
+	w.rxMu.RLock()
+	w.work.Store(&workState{
+		job:     job,
+		header:  withExtraNonce(header, job.ExtraNonce),
+		target:  target,
+		hasher:  w.hasher,
+		threads: w.threads,
+	})
+	w.rxMu.RUnlock()
+
+	// Threads return the old seed's VMs once they see the new job
+	if old != nil {
+		go old.Close()
+	}
+
 	w.logger.Info("New job received",
 		"job_id", job.ID,
 		"height", job.Height,
@@ -165,64 +207,64 @@ func (w *Worker) SetJob(job *Job) error {
 	return nil
 }
 
-func (w *Worker) updateSeed(seedHash string) error {
+// updateSeed builds the hasher for a new seed and returns the previous one,
+// which the caller closes once mining threads have moved off it
+func (w *Worker) updateSeed(seedHash string) (randomx.Hasher, error) {
 	seedBytes, err := hex.DecodeString(seedHash)
 	if err != nil {
-		return fmt.Errorf("invalid seed hash: %w", err)
+		return nil, fmt.Errorf("invalid seed hash: %w", err)
 	}
 
-	w.rxMu.Lock()
-	defer w.rxMu.Unlock()
-
-	// Close old VMs
-	for _, vm := range w.rxVMs {
-		if vm != nil {
-			vm.Close()
-		}
+	// Determine thread count
+	threads := w.cfg.Threads
+	if threads <= 0 {
+		threads = 1 // Default to 1, should detect CPU cores
 	}
 
-	// Close old cache
-	if w.rxCache != nil {
-		w.rxCache.Close()
+	flags := randomx.FlagDefault | randomx.FlagJIT
+	if w.cfg.HugePages {
+		flags |= randomx.FlagLargePages
 	}
 
-	// Create new cache
-	cache, err := randomx.NewCache(randomx.FlagDefault | randomx.FlagJIT)
+	// One VM per thread
+	hasher, err := w.cfg.Hashers.NewHasher(seedBytes, randomx.HasherConfig{
+		VMs:              threads,
+		FullDataset:      w.cfg.FullDataset,
+		SharedDatasetDir: w.cfg.SharedDatasetDir,
+		Flags:            flags,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create cache: %w", err)
+		return nil, fmt.Errorf("failed to create hasher: %w", err)
 	}
-	cache.Init(seedBytes)
-	w.rxCache = cache
-
-	// Determine thread count
-	threads := w.cfg.Threads
-	if threads <= 0 {
-		threads = 1 // Default to 1, should detect CPU cores
+	alloc := hasher.Allocation()
+	if alloc.LargePagesRequested && !alloc.LargePages {
+		w.logger.Warn("Huge pages unavailable, using normal pages (see /proc/sys/vm/nr_hugepages)")
 	}
 
-	// Create VMs (one per thread)
-	w.rxVMs = make([]*randomx.VM, threads)
-	for i := 0; i < threads; i++ {
-		vm, err := randomx.NewVM(cache, nil, randomx.FlagDefault|randomx.FlagJIT)
-		if err != nil {
-			return fmt.Errorf("failed to create VM %d: %w", i, err)
-		}
-		w.rxVMs[i] = vm
-	}
+	w.rxMu.Lock()
+	defer w.rxMu.Unlock()
 
+	old := w.hasher
+	w.hasher = hasher
+	w.threads = threads
 	w.seedHash = seedHash
 	w.logger.Info("RandomX seed updated",
 		"seed", seedHash[:16]+"...",
 		"threads", threads,
+		"huge_pages", alloc.LargePages,
+		"memory_mb", alloc.Bytes()>>20,
+		"shared_dataset", alloc.DatasetShared,
 	)
 
-	return nil
+	return old, nil
 }
 
 func (w *Worker) startMining() {
 	w.mining.Store(true)
 
-	threads := len(w.rxVMs)
+	w.rxMu.RLock()
+	threads := w.threads
+	w.rxMu.RUnlock()
 	if threads == 0 {
 		threads = 1
 	}
@@ -238,130 +280,151 @@ func (w *Worker) startMining() {
 func (w *Worker) miningThread(threadID int) {
 	defer w.wg.Done()
 
-	w.rxMu.RLock()
-	if threadID >= len(w.rxVMs) || w.rxVMs[threadID] == nil {
-		w.rxMu.RUnlock()
-		w.logger.Error("No VM for thread", "thread", threadID)
-		return
-	}
-	vm := w.rxVMs[threadID]
-	w.rxMu.RUnlock()
-
-	var nonce uint32 = uint32(threadID) // Start at different points
-
 	for w.mining.Load() {
-		select {
-		case <-w.ctx.Done():
-			return
-		default:
-		}
-
-		w.jobMu.RLock()
-		job := w.currentJob
-		w.jobMu.RUnlock()
-
-		if job == nil {
+		ws := w.work.Load()
+		if ws == nil || ws.hasher == nil {
 			time.Sleep(100 * time.Millisecond)
 			continue
 		}
+		// ErrPoolClosed means the seed changed; pick up the new job
+		if err := w.mineJob(threadID, ws); err != nil && !errors.Is(err, randomx.ErrPoolClosed) {
+			return
+		}
+	}
+}
 
-		// Build header with nonce
-		header := w.buildHeader(job, nonce)
-
-		// Calculate hash
-		hash := vm.CalculateHash(header)
-		w.hashCount.Add(1)
-
-		// Check if meets target
-		if w.checkTarget(hash, job.Target) {
-			nonceHex := fmt.Sprintf("%08x", nonce)
-			resultHex := hex.EncodeToString(hash)
+// mineJob hashes this thread's share of the job's nonces until the job
+// changes, mining stops or the nonces run out. The header is mutated in
+// place and hashed on one VM with the pipelined batch API, so each hash
+// overlaps with preparing the next.
+func (w *Worker) mineJob(threadID int, ws *workState) error {
+	pipe, err := ws.hasher.Pipeline(w.ctx)
+	if err != nil {
+		return err
+	}
+	defer pipe.Close()
+
+	header := append([]byte(nil), ws.header...)
+	stride := uint32(ws.threads)
+	nonce := uint32(threadID) // Start at different points
+	var counted uint64
+	defer func() { w.hashCount.Add(counted) }()
+
+	putNonce(header, nonce)
+	pipe.First(header)
+
+	for w.mining.Load() && w.work.Load() == ws {
+		// Queue the next nonce; the pipeline returns the previous one's hash
+		prev := nonce
+		nonce += stride
+		if nonce < prev {
+			// Wrapped around, job is exhausted for this thread
+			counted++
+			w.checkHash(ws, prev, pipe.Last())
+			for w.mining.Load() && w.work.Load() == ws {
+				time.Sleep(10 * time.Millisecond)
+			}
+			return nil
+		}
+		putNonce(header, nonce)
+		hash := pipe.Next(header)
+		w.checkHash(ws, prev, hash)
 
-			w.logger.Info("Share found!",
-				"job", job.ID,
-				"nonce", nonceHex,
-				"hash", resultHex[:16]+"...",
-			)
+		if counted++; counted == hashCountBatch {
+			w.hashCount.Add(counted)
+			counted = 0
+		}
+	}
+	return nil
+}
 
-			w.sharesValid.Add(1)
+// checkHash reports a share if the hash of nonce meets the job target
+func (w *Worker) checkHash(ws *workState, nonce uint32, sum [randomx.HashSize]byte) {
+	hash := sum[:]
+	if !meetsTarget(hash, ws.target) {
+		return
+	}
+	job := ws.job
+	nonceHex := fmt.Sprintf("%08x", nonce)
+	resultHex := hex.EncodeToString(hash)
+
+	w.logger.Info("Share found!",
+		"job", job.ID,
+		"nonce", nonceHex,
+		"hash", resultHex[:16]+"...",
+	)
 
-			if w.OnShareFound != nil {
-				w.OnShareFound(job.ID, nonceHex, resultHex)
-			}
+	w.sharesValid.Add(1)
 
-			// Check if block
-			if w.checkBlockTarget(hash, job) {
-				w.logger.Info("BLOCK FOUND!",
-					"height", job.Height,
-					"hash", resultHex,
-				)
-				if w.OnBlockFound != nil {
-					w.OnBlockFound(job.Height, resultHex)
-				}
-			}
-		}
+	if w.OnShareFound != nil {
+		w.OnShareFound(job.ID, nonceHex, resultHex)
+	}
 
-		// Increment nonce, spacing by thread count
-		nonce += uint32(len(w.rxVMs))
-		if nonce < uint32(threadID) {
-			// Wrapped around, job is exhausted for this thread
-			time.Sleep(10 * time.Millisecond)
+	// Check if block
+	if w.checkBlockTarget(hash, job) {
+		w.logger.Info("BLOCK FOUND!",
+			"height", job.Height,
+			"hash", resultHex,
+		)
+		if w.OnBlockFound != nil {
+			w.OnBlockFound(job.Height, resultHex)
 		}
 	}
 }
 
-func (w *Worker) buildHeader(job *Job, nonce uint32) []byte {
-	// Decode blob
+// buildHeader returns the job blob with the nonce and the job's extra nonce
+// inserted
+func buildHeader(job *Job, nonce uint32) []byte {
 	blob, err := hex.DecodeString(job.Blob)
 	if err != nil {
 		return nil
 	}
+	header := withExtraNonce(blob, job.ExtraNonce)
+	putNonce(header, nonce)
+	return header
+}
 
-	// Make a copy
-	header := make([]byte, len(blob))
-	copy(header, blob)
+// withExtraNonce inserts the extra nonce at its reserved position, if the
+// header has one, and returns the header
+func withExtraNonce(header []byte, extraNonce uint32) []byte {
+	if extraNonce > 0 && len(header) >= 44 {
+		binary.LittleEndian.PutUint32(header[40:44], extraNonce)
+	}
+	return header
+}
 
-	// Insert nonce at bytes 76-80 (little endian)
+// putNonce writes the nonce at bytes 76-80 (little endian)
+func putNonce(header []byte, nonce uint32) {
 	if len(header) >= 80 {
-		header[76] = byte(nonce)
-		header[77] = byte(nonce >> 8)
-		header[78] = byte(nonce >> 16)
-		header[79] = byte(nonce >> 24)
+		binary.LittleEndian.PutUint32(header[76:80], nonce)
 	}
+}
 
-	// Insert extra nonce at reserved position if available
-	if job.ExtraNonce > 0 && len(header) >= 44 {
-		header[40] = byte(job.ExtraNonce)
-		header[41] = byte(job.ExtraNonce >> 8)
-		header[42] = byte(job.ExtraNonce >> 16)
-		header[43] = byte(job.ExtraNonce >> 24)
+// checkTarget reports whether hash meets the job's share target
+func checkTarget(hash []byte, targetHex string) bool {
+	target, err := hex.DecodeString(targetHex)
+	if err != nil {
+		return false
 	}
-
-	return header
+	return meetsTarget(hash, target)
 }
 
-func (w *Worker) checkTarget(hash []byte, targetHex string) bool {
+// meetsTarget compares the top four bytes of a little-endian hash against a
+// decoded target
+func meetsTarget(hash, target []byte) bool {
 	// Simplified target check
 	// Real implementation would compare full 256-bit values
-	if len(hash) < 4 || len(targetHex) < 8 {
-		return false
-	}
-
-	// Check leading zeros (simplified)
-	target, _ := hex.DecodeString(targetHex)
-	if len(target) < 4 {
+	if len(hash) < 32 || len(target) < 4 {
 		return false
 	}
 
 	// Compare first 4 bytes (little endian)
 	for i := 31; i >= 28; i-- {
-		if i < len(hash) {
-			if hash[i] > target[31-i] {
-				return false
-			}
-			if hash[i] < target[31-i] {
-				return true
-			}
+		if hash[i] > target[31-i] {
+			return false
+		}
+		if hash[i] < target[31-i] {
+			return true
 		}
 	}
 	return true
@@ -384,6 +447,7 @@ func (w *Worker) GetHashrate() float64 {
 
 // GetStats returns worker statistics
 func (w *Worker) GetStats() WorkerStats {
+	threads, alloc := w.allocation()
 	return WorkerStats{
 		WorkerID:      w.cfg.WorkerID,
 		WorkerName:    w.cfg.WorkerName,
@@ -392,8 +456,19 @@ func (w *Worker) GetStats() WorkerStats {
 		SharesInvalid: w.sharesInvalid.Load(),
 		Uptime:        time.Since(w.startTime),
 		Mining:        w.mining.Load(),
-		Threads:       len(w.rxVMs),
+		Threads:       threads,
+		HugePages:     alloc.LargePages,
+		MemoryBytes:   alloc.Bytes(),
+	}
+}
+
+func (w *Worker) allocation() (int, randomx.AllocationInfo) {
+	w.rxMu.RLock()
+	defer w.rxMu.RUnlock()
+	if w.hasher == nil {
+		return w.threads, randomx.AllocationInfo{}
 	}
+	return w.threads, w.hasher.Allocation()
 }
 
 // WorkerStats holds worker statistics
@@ -406,6 +481,8 @@ type WorkerStats struct {
 	Uptime        time.Duration
 	Mining        bool
 	Threads       int
+	HugePages     bool   // Huge pages were obtained for RandomX memory
+	MemoryBytes   uint64 // RandomX memory allocated
 }
 
 func (w *Worker) heartbeatLoop() {
@@ -0,0 +1,47 @@
+//go:build cgo && randomx
+
+package coopmine
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+)
+
+// BenchmarkMiningLoopRandomX compares light-mode hash throughput on the
+// real RandomX library: the old loop calling CalculateHash on one fixed VM
+// for every nonce, against the pipelined mineJob loop. Both use one thread
+// and the worker's flags. Run with
+//
+//	go test -tags randomx -bench MiningLoopRandomX ./coopmine
+func BenchmarkMiningLoopRandomX(b *testing.B) {
+	b.Run("fixed-vm", func(b *testing.B) {
+		seed, _ := hex.DecodeString(benchJob.SeedHash)
+		ctx, err := randomx.NewContext(randomx.FlagDefault | randomx.FlagJIT)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer ctx.Close()
+		if err := ctx.InitCache(seed); err != nil {
+			b.Fatal(err)
+		}
+		vm, err := ctx.CreateVM()
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer vm.Close()
+
+		b.ResetTimer()
+		for nonce := uint32(0); nonce < uint32(b.N); nonce++ {
+			sum := vm.CalculateHash(buildHeader(benchJob, nonce))
+			checkTarget(sum[:], benchJob.Target)
+		}
+		b.StopTimer()
+		b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "H/s")
+	})
+
+	b.Run("pipelined", func(b *testing.B) {
+		benchMineJob(b, randomx.DefaultHasherFactory())
+	})
+}
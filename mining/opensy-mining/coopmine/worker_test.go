@@ -0,0 +1,118 @@
+package coopmine
+
+import (
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+	"github.com/opensyria/opensy-mining/common/randomx/randomxtest"
+)
+
+func TestWorkerMinesWithFakeHasher(t *testing.T) {
+	worker := NewWorker(WorkerConfig{
+		Threads:      2,
+		HeartbeatInt: time.Second,
+		Hashers:      randomxtest.FakeHasherFactory{},
+	})
+
+	type found struct{ jobID, nonce, result string }
+	shares := make(chan found, 16)
+	worker.OnShareFound = func(jobID, nonce, result string) {
+		select {
+		case shares <- found{jobID, nonce, result}:
+		default:
+		}
+	}
+	if err := worker.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer worker.Stop()
+
+	job := &Job{
+		ID:         "job-1",
+		Blob:       strings.Repeat("00", 80),
+		Target:     "ffffffff", // Every hash is a share
+		SeedHash:   strings.Repeat("ab", 32),
+		ExtraNonce: 7,
+	}
+	if err := worker.SetJob(job); err != nil {
+		t.Fatalf("SetJob: %v", err)
+	}
+
+	select {
+	case share := <-shares:
+		nonce, err := strconv.ParseUint(share.nonce, 16, 32)
+		if err != nil {
+			t.Fatalf("bad nonce %q", share.nonce)
+		}
+		seed, _ := hex.DecodeString(job.SeedHash)
+		want := randomxtest.FakeHash(seed, buildHeader(job, uint32(nonce)), 0)
+		if share.jobID != "job-1" || share.result != hex.EncodeToString(want[:]) {
+			t.Errorf("share %+v does not match the fake hash of its header", share)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no share found")
+	}
+	if stats := worker.GetStats(); stats.Threads != 2 {
+		t.Errorf("threads = %d, want 2", stats.Threads)
+	}
+}
+
+// BenchmarkMiningLoop measures the pipelined mineJob loop on the fake
+// hasher, i.e. loop overhead only. BenchmarkMiningLoopRandomX (build tag
+// randomx) compares hash throughput on the real library.
+func BenchmarkMiningLoop(b *testing.B) {
+	benchMineJob(b, randomxtest.FakeHasherFactory{})
+}
+
+// benchJob rarely yields shares, so logging stays out of the way
+var benchJob = &Job{
+	ID:         "bench",
+	Blob:       strings.Repeat("00", 80),
+	Target:     "00000000",
+	SeedHash:   strings.Repeat("ab", 32),
+	ExtraNonce: 7,
+}
+
+// benchMineJob runs mineJob on one thread until it has hashed b.N nonces
+func benchMineJob(b *testing.B, hashers randomx.HasherFactory) {
+	w := NewWorker(WorkerConfig{
+		Threads: 1,
+		Hashers: hashers,
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	defer w.Stop()
+	if _, err := w.updateSeed(benchJob.SeedHash); err != nil {
+		b.Fatalf("updateSeed: %v", err)
+	}
+
+	header, _ := hex.DecodeString(benchJob.Blob)
+	target, _ := hex.DecodeString(benchJob.Target)
+	ws := &workState{
+		job:     benchJob,
+		header:  withExtraNonce(header, benchJob.ExtraNonce),
+		target:  target,
+		hasher:  w.hasher,
+		threads: 1,
+	}
+	w.work.Store(ws)
+	w.mining.Store(true)
+
+	done := make(chan error, 1)
+	b.ResetTimer()
+	go func() { done <- w.mineJob(0, ws) }()
+	for w.hashCount.Load() < uint64(b.N) {
+		time.Sleep(50 * time.Microsecond)
+	}
+	w.mining.Store(false)
+	if err := <-done; err != nil {
+		b.Fatal(err)
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(w.hashCount.Load())/b.Elapsed().Seconds(), "H/s")
+}
@@ -100,6 +100,7 @@ CREATE TABLE blocks (
     confirmations   INTEGER DEFAULT 0,
     orphaned        BOOLEAN DEFAULT FALSE,
     mature          BOOLEAN DEFAULT FALSE,  -- 100+ confirmations
+    submit_result   VARCHAR(255),           -- 'accepted' or the node's reject reason
     
     -- Extra metadata
     difficulty      DECIMAL(30, 10),
@@ -0,0 +1,3 @@
+-- Adds the node's submitblock verdict to blocks. The pool applies this on
+-- startup; run it by hand for databases shared with older pool versions.
+ALTER TABLE blocks ADD COLUMN IF NOT EXISTS submit_result VARCHAR(255);
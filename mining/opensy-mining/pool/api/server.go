@@ -336,7 +336,7 @@ func (s *Server) handleNetworkStats(w http.ResponseWriter, r *http.Request) {
 		NetworkHash:  0,
 		BlockTime:    120,   // 2 minutes
 		BlockReward:  10000, // 10000 SYL
-		Algo:         "RandomX (rx/0)",
+		Algo:         "RandomX (rx/opensy)",
 		SeedInterval: 32,
 	}
 
@@ -4,18 +4,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/opensyria/opensy-mining/common/rpc"
 	"github.com/opensyria/opensy-mining/pool"
+	"github.com/opensyria/opensy-mining/pool/metrics"
+	"github.com/opensyria/opensy-mining/pool/stratum"
 )
 
 // Build info (set via ldflags)
@@ -37,13 +43,19 @@ func main() {
 		"commit", Commit,
 	)
 
+	poolMetrics := metrics.New("")
+
 	// Create pool service
 	poolCfg := pool.Config{
+		PoolAddress: cfg.PoolAddress,
+		CoinbaseTag: cfg.CoinbaseTag,
+
 		StratumAddr:       cfg.StratumAddr,
 		InitialDifficulty: cfg.InitialDifficulty,
 		MinDifficulty:     cfg.MinDifficulty,
 		MaxDifficulty:     cfg.MaxDifficulty,
 		VardiffEnabled:    cfg.VardiffEnabled,
+		NiceHash:          cfg.NiceHash,
 
 		DBHost:     cfg.DBHost,
 		DBPort:     cfg.DBPort,
@@ -54,14 +66,29 @@ func main() {
 		RedisAddr:     cfg.RedisAddr,
 		RedisPassword: cfg.RedisPassword,
 
-		NodeURL:  cfg.NodeURL,
-		NodeUser: cfg.NodeUser,
-		NodePass: cfg.NodePass,
+		NodeURL:          cfg.NodeURL,
+		NodeUser:         cfg.NodeUser,
+		NodePass:         cfg.NodePass,
+		NodeCookie:       cfg.NodeCookie,
+		NodeFailoverURLs: cfg.NodeFailoverURLs,
+		SubmitAllNodes:   cfg.SubmitAllNodes,
+		NodeZMQAddr:      cfg.NodeZMQAddr,
+		ProposeBlocks:    cfg.ProposeBlocks,
+		Network:          cfg.Network,
+		MaxHeaderLag:     cfg.MaxHeaderLag,
+
+		RandomXFullDataset: cfg.RandomXFullDataset,
+		RandomXThreads:     cfg.RandomXThreads,
+		RandomXLargePages:  cfg.RandomXLargePages,
+		RandomXSharedDir:   cfg.RandomXSharedDir,
+		Validators:         cfg.Validators,
+		ValidatorToken:     cfg.ValidatorToken,
 
 		ConfirmationDepth: 100, // OpenSY uses 100-block maturity
 		StatsInterval:     10 * time.Second,
 
-		Logger: logger,
+		Metrics: poolMetrics,
+		Logger:  logger,
 	}
 
 	poolService, err := pool.New(poolCfg)
@@ -77,7 +104,7 @@ func main() {
 	}
 
 	// Start metrics/API server
-	go startAPIServer(cfg.MetricsAddr, poolService, logger)
+	go startAPIServer(cfg.MetricsAddr, cfg.BlockNotifyToken, poolService, poolMetrics, logger)
 
 	// Wait for shutdown signal
 	sigChan := make(chan os.Signal, 1)
@@ -106,12 +133,17 @@ func main() {
 
 // Config holds CLI configuration
 type Config struct {
+	// Coinbase
+	PoolAddress string
+	CoinbaseTag string
+
 	// Stratum
 	StratumAddr       string
 	InitialDifficulty uint64
 	MinDifficulty     uint64
 	MaxDifficulty     uint64
 	VardiffEnabled    bool
+	NiceHash          bool
 
 	// Database
 	DBHost     string
@@ -125,9 +157,25 @@ type Config struct {
 	RedisPassword string
 
 	// Node RPC
-	NodeURL  string
-	NodeUser string
-	NodePass string
+	NodeURL          string
+	NodeUser         string
+	NodePass         string
+	NodeCookie       string
+	NodeFailoverURLs []string
+	SubmitAllNodes   bool
+	NodeZMQAddr      string
+	BlockNotifyToken string
+	ProposeBlocks    bool
+	Network          string
+	MaxHeaderLag     int64
+
+	// RandomX
+	RandomXFullDataset bool
+	RandomXThreads     int
+	RandomXLargePages  bool
+	RandomXSharedDir   string
+	Validators         []string
+	ValidatorToken     string
 
 	// Metrics
 	MetricsAddr string
@@ -140,12 +188,17 @@ type Config struct {
 func parseFlags() Config {
 	cfg := Config{}
 
+	// Coinbase
+	flag.StringVar(&cfg.PoolAddress, "pool-address", "", "Address receiving block rewards (required)")
+	flag.StringVar(&cfg.CoinbaseTag, "coinbase-tag", stratum.DefaultCoinbaseTag, "Tag written into the coinbase scriptSig of found blocks")
+
 	// Stratum
 	flag.StringVar(&cfg.StratumAddr, "stratum-addr", ":3333", "Stratum server listen address")
 	flag.Uint64Var(&cfg.InitialDifficulty, "initial-difficulty", 10000, "Initial mining difficulty")
 	flag.Uint64Var(&cfg.MinDifficulty, "min-difficulty", 1000, "Minimum difficulty")
 	flag.Uint64Var(&cfg.MaxDifficulty, "max-difficulty", 1000000000, "Maximum difficulty")
 	flag.BoolVar(&cfg.VardiffEnabled, "vardiff", true, "Enable variable difficulty")
+	flag.BoolVar(&cfg.NiceHash, "nicehash", false, "Advertise the nicehash extension so proxies can split each job's top nonce byte")
 
 	// Database
 	flag.StringVar(&cfg.DBHost, "db-host", "localhost", "PostgreSQL host")
@@ -162,6 +215,22 @@ func parseFlags() Config {
 	flag.StringVar(&cfg.NodeURL, "node-url", "http://127.0.0.1:8332", "OpenSY node RPC URL")
 	flag.StringVar(&cfg.NodeUser, "node-user", "", "Node RPC username")
 	flag.StringVar(&cfg.NodePass, "node-pass", "", "Node RPC password")
+	flag.StringVar(&cfg.NodeCookie, "node-cookie", "", "Node RPC .cookie file (overrides -node-user/-node-pass)")
+	nodeFailover := flag.String("node-failover-urls", "", "Comma-separated failover node RPC URLs (same credentials)")
+	flag.BoolVar(&cfg.SubmitAllNodes, "node-submit-all", true, "Submit found blocks to every node at once")
+	flag.StringVar(&cfg.NodeZMQAddr, "node-zmq", "", "Node ZMQ hashblock endpoint (e.g. tcp://127.0.0.1:28332)")
+	flag.StringVar(&cfg.BlockNotifyToken, "blocknotify-token", "", "Bearer token enabling the /blocknotify/<hash> endpoint")
+	flag.StringVar(&cfg.Network, "network", "main", "Chain the node must be on (main, test, regtest)")
+	flag.Int64Var(&cfg.MaxHeaderLag, "max-header-lag", pool.DefaultMaxHeaderLag, "Pause jobs while node headers are this many blocks ahead")
+	flag.BoolVar(&cfg.ProposeBlocks, "propose-blocks", true, "Validate new templates with getblocktemplate proposal mode before mining")
+
+	// RandomX
+	flag.BoolVar(&cfg.RandomXFullDataset, "randomx-full-dataset", false, "Validate shares with the 2 GB RandomX dataset (falls back to light mode)")
+	flag.IntVar(&cfg.RandomXThreads, "randomx-threads", 0, "RandomX dataset init threads (0 = all CPUs)")
+	flag.BoolVar(&cfg.RandomXLargePages, "randomx-large-pages", false, "Back RandomX memory with large pages (falls back to normal pages)")
+	validators := flag.String("validators", "", "Comma-separated share validator addresses (hashes locally if none answer)")
+	flag.StringVar(&cfg.ValidatorToken, "validator-token", "", "Token shared with the validators (required with -validators)")
+	flag.StringVar(&cfg.RandomXSharedDir, "randomx-shared-dataset", "", "Directory (e.g. /dev/shm) for full datasets shared with workers on this host")
 
 	// Metrics
 	flag.StringVar(&cfg.MetricsAddr, "metrics-addr", ":9100", "Metrics/API server address")
@@ -181,6 +250,9 @@ func parseFlags() Config {
 	}
 
 	// Environment variable overrides
+	if v := os.Getenv("OPENSY_POOL_ADDRESS"); v != "" {
+		cfg.PoolAddress = v
+	}
 	if v := os.Getenv("OPENSY_NODE_URL"); v != "" {
 		cfg.NodeURL = v
 	}
@@ -190,6 +262,29 @@ func parseFlags() Config {
 	if v := os.Getenv("OPENSY_NODE_PASS"); v != "" {
 		cfg.NodePass = v
 	}
+	if v := os.Getenv("OPENSY_NODE_COOKIE"); v != "" {
+		cfg.NodeCookie = v
+	}
+	if v := os.Getenv("OPENSY_NODE_FAILOVER_URLS"); v != "" {
+		*nodeFailover = v
+	}
+	cfg.NodeFailoverURLs = splitList(*nodeFailover)
+	if v := os.Getenv("OPENSY_NODE_ZMQ"); v != "" {
+		cfg.NodeZMQAddr = v
+	}
+	if v := os.Getenv("OPENSY_VALIDATORS"); v != "" {
+		*validators = v
+	}
+	cfg.Validators = splitList(*validators)
+	if v := os.Getenv("OPENSY_VALIDATOR_TOKEN"); v != "" {
+		cfg.ValidatorToken = v
+	}
+	if v := os.Getenv("OPENSY_NETWORK"); v != "" {
+		cfg.Network = v
+	}
+	if v := os.Getenv("OPENSY_BLOCKNOTIFY_TOKEN"); v != "" {
+		cfg.BlockNotifyToken = v
+	}
 	if v := os.Getenv("OPENSY_DB_HOST"); v != "" {
 		cfg.DBHost = v
 	}
@@ -203,6 +298,17 @@ func parseFlags() Config {
 	return cfg
 }
 
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func setupLogger(level, format string) *slog.Logger {
 	var logLevel slog.Level
 	switch level {
@@ -232,16 +338,76 @@ func setupLogger(level, format string) *slog.Logger {
 	return slog.New(handler)
 }
 
-func startAPIServer(addr string, poolService *pool.Service, logger *slog.Logger) {
+// nodeHealth is the public view of a node backend: no URL, and no last
+// error since transport errors quote the node address
+type nodeHealth struct {
+	Name        string           `json:"name"`
+	Circuit     rpc.CircuitState `json:"circuit"`
+	Usable      bool             `json:"usable"`
+	LatencyMs   float64          `json:"latency_ms"`
+	ErrorRate   float64          `json:"error_rate"`
+	TipHeight   int64            `json:"tip_height"`
+	LastSuccess time.Time        `json:"last_success"`
+}
+
+func healthNodes(nodes []rpc.NodeStatus) []nodeHealth {
+	out := make([]nodeHealth, len(nodes))
+	for i, n := range nodes {
+		out[i] = nodeHealth{
+			Name:        n.Name,
+			Circuit:     n.Circuit,
+			Usable:      n.Usable,
+			LatencyMs:   n.LatencyMs,
+			ErrorRate:   n.ErrorRate,
+			TipHeight:   n.TipHeight,
+			LastSuccess: n.LastSuccess,
+		}
+	}
+	return out
+}
+
+func startAPIServer(addr, blockNotifyToken string, poolService *pool.Service, poolMetrics *metrics.Metrics, logger *slog.Logger) {
 	mux := http.NewServeMux()
 
-	// Prometheus metrics
-	mux.Handle("/metrics", promhttp.Handler())
+	// Prometheus metrics: process/Go collectors plus pool metrics
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, poolMetrics.Registry()}
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{}))
+
+	// Node -blocknotify hook
+	if blockNotifyToken != "" {
+		mux.Handle(rpc.BlockNotifyPath, rpc.BlockNotifyHandler(blockNotifyToken, poolService.NotifyNewBlock))
+	}
 
-	// Health check
+	// Health check, including per-node circuit state
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintln(w, "OK")
+		nodes := poolService.NodeStatus()
+		status := "ok"
+		code := http.StatusOK
+		usable := 0
+		for _, n := range nodes {
+			if n.Usable {
+				usable++
+			}
+		}
+		nodeState := poolService.NodeState()
+		if usable == 0 {
+			status = "unhealthy"
+			code = http.StatusServiceUnavailable
+		} else if !nodeState.Ready {
+			status = "paused"
+			code = http.StatusServiceUnavailable
+		} else if usable < len(nodes) {
+			status = "degraded"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":     status,
+			"reason":     nodeState.Reason,
+			"node_state": nodeState,
+			"nodes":      healthNodes(nodes),
+		})
 	})
 
 	// Pool stats
@@ -259,7 +425,10 @@ func startAPIServer(addr string, poolService *pool.Service, logger *slog.Logger)
 	"hashrate": %.2f,
 	"blocks_found": %d,
 	"last_block_height": %d,
-	"network_difficulty": %d
+	"network_difficulty": %d,
+	"randomx_mode": %q,
+	"randomx_large_pages": %t,
+	"randomx_memory_bytes": %d
 }`,
 			stats.OnlineMiners,
 			stats.OnlineWorkers,
@@ -267,6 +436,9 @@ func startAPIServer(addr string, poolService *pool.Service, logger *slog.Logger)
 			stats.BlocksFound,
 			stats.LastBlockHeight,
 			stats.NetworkDiff,
+			stats.RandomXMode,
+			stats.RandomXLargePages,
+			stats.RandomXMemory,
 		)
 	})
 
@@ -0,0 +1,132 @@
+// OpenSY Share Validator
+// Hashes Stratum shares with RandomX for pool frontends started with
+// -validators, so validation scales separately from Stratum connections
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+	"github.com/opensyria/opensy-mining/pool/validator"
+)
+
+// Build info (set via ldflags)
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+func main() {
+	var (
+		listenAddr     = flag.String("listen", ":7777", "gRPC listen address")
+		token          = flag.String("token", "", "Token pool frontends must present (or OPENSY_VALIDATOR_TOKEN); required")
+		vms            = flag.Int("vms", runtime.NumCPU(), "RandomX VMs per seed (shares hashed in parallel)")
+		maxBatch       = flag.Int("max-batch", 1024, "Largest batch accepted per request")
+		grace          = flag.Duration("seed-grace", 5*time.Minute, "How long the previous seed stays usable after a switch")
+		fullDataset    = flag.Bool("full-dataset", false, "Hash with the 2 GB RandomX dataset (falls back to light mode)")
+		datasetThreads = flag.Int("dataset-threads", 0, "Dataset init threads (0 = all CPUs)")
+		largePages     = flag.Bool("large-pages", false, "Back RandomX memory with large pages (falls back to normal pages)")
+		sharedDataset  = flag.String("shared-dataset", "", "Directory (e.g. /dev/shm) for full datasets shared with other processes")
+		tlsCert        = flag.String("tls-cert", "", "TLS certificate file")
+		tlsKey         = flag.String("tls-key", "", "TLS private key file")
+		logLevel       = flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+		logFormat      = flag.String("log-format", "text", "Log format (text, json)")
+		showVersion    = flag.Bool("version", false, "Show version and exit")
+	)
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Printf("OpenSY Share Validator %s (%s) built %s\n", Version, Commit, BuildDate)
+		os.Exit(0)
+	}
+
+	logger := setupLogger(*logLevel, *logFormat)
+	slog.SetDefault(logger)
+
+	cfg := validator.DefaultServerConfig()
+	cfg.ListenAddr = *listenAddr
+	cfg.Token = *token
+	if v := os.Getenv("OPENSY_VALIDATOR_TOKEN"); v != "" {
+		cfg.Token = v
+	}
+	cfg.MaxBatch = *maxBatch
+	cfg.TLSCert = *tlsCert
+	cfg.TLSKey = *tlsKey
+	cfg.Seeds.VMs = *vms
+	cfg.Seeds.GraceWindow = *grace
+	cfg.Seeds.FullDataset = *fullDataset
+	cfg.Seeds.DatasetThreads = *datasetThreads
+	cfg.Seeds.SharedDatasetDir = *sharedDataset
+	if *largePages {
+		cfg.Seeds.Flags |= randomx.FlagLargePages
+	}
+	cfg.Seeds.Logger = logger
+	cfg.Logger = logger
+
+	logger.Info("Starting OpenSY share validator",
+		"version", Version,
+		"vms", *vms,
+		"full_dataset", *fullDataset,
+	)
+
+	srv := validator.NewServer(cfg)
+	if err := srv.Start(); err != nil {
+		logger.Error("Failed to start validator", "error", err)
+		os.Exit(1)
+	}
+
+	go statsReporter(srv, logger)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigCh
+	logger.Info("Received shutdown signal", "signal", sig)
+
+	srv.Stop()
+}
+
+func statsReporter(srv *validator.Server, logger *slog.Logger) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		stats := srv.Stats()
+		logger.Info("Validator stats",
+			"batches", stats.Batches,
+			"shares", stats.Shares,
+			"invalid", stats.Invalid,
+			"failed", stats.Failed,
+			"mode", stats.Mode,
+			"vms_in_use", stats.VMs.InUse,
+			"vm_waits", stats.VMs.Waits,
+		)
+	}
+}
+
+func setupLogger(level, format string) *slog.Logger {
+	var lvl slog.Level
+	switch level {
+	case "debug":
+		lvl = slog.LevelDebug
+	case "warn":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		lvl = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	if format == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stdout, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stdout, opts))
+}
@@ -25,7 +25,13 @@ type DB struct {
 	pool *pgxpool.Pool
 }
 
-// New creates a new database connection pool
+// schemaUpgrades bring databases created from an older init-db.sql up to
+// date. Each statement must be idempotent; they run on every start.
+var schemaUpgrades = []string{
+	`ALTER TABLE blocks ADD COLUMN IF NOT EXISTS submit_result VARCHAR(255)`,
+}
+
+// New creates a new database connection pool and applies schema upgrades
 func New(cfg Config) (*DB, error) {
 	connStr := fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable pool_max_conns=%d",
@@ -48,6 +54,13 @@ func New(cfg Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	for _, stmt := range schemaUpgrades {
+		if _, err := pool.Exec(context.Background(), stmt); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("failed to upgrade schema: %w", err)
+		}
+	}
+
 	return &DB{pool: pool}, nil
 }
 
@@ -94,12 +107,15 @@ type Share struct {
 	IsBlock    bool
 }
 
+// SubmitAccepted is Block.SubmitResult for blocks the node accepted
+const SubmitAccepted = "accepted"
+
 // Block represents a found block
 type Block struct {
 	ID          int64
 	Height      int64
 	Hash        string
-	MinerID     int64
+	MinerID     int64 // 0 when the finder could not be resolved
 	WorkerID    int64
 	Reward      int64
 	Difficulty  float64
@@ -107,6 +123,9 @@ type Block struct {
 	Confirmed   bool
 	ConfirmedAt *time.Time
 	Orphaned    bool
+	// SubmitResult is SubmitAccepted, the node's rejection reason, or the
+	// error from a failed submission
+	SubmitResult string
 }
 
 // GetOrCreateMiner gets or creates a miner by address
@@ -209,14 +228,18 @@ func (db *DB) RecordBlock(ctx context.Context, block *Block) error {
 
 	// Insert block
 	err = tx.QueryRow(ctx, `
-		INSERT INTO blocks (height, hash, miner_id, worker_id, reward, difficulty, found_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO blocks (height, hash, miner_id, worker_id, reward, difficulty, found_at, orphaned, submit_result)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id
-	`, block.Height, block.Hash, block.MinerID, block.WorkerID, block.Reward, block.Difficulty, block.FoundAt).Scan(&block.ID)
+	`, block.Height, block.Hash, nullID(block.MinerID), nullID(block.WorkerID), block.Reward, block.Difficulty, block.FoundAt,
+		block.Orphaned, block.SubmitResult).Scan(&block.ID)
 
 	if err != nil {
 		return fmt.Errorf("failed to insert block: %w", err)
 	}
+	if block.Orphaned || block.MinerID == 0 {
+		return tx.Commit(ctx) // Rejected blocks do not count for the miner
+	}
 
 	// Update miner block count
 	_, err = tx.Exec(ctx, `
@@ -230,10 +253,18 @@ func (db *DB) RecordBlock(ctx context.Context, block *Block) error {
 	return tx.Commit(ctx)
 }
 
+// nullID stores an unknown (zero) miner or worker ID as NULL
+func nullID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
 // GetUnconfirmedBlocks returns blocks pending confirmation
 func (db *DB) GetUnconfirmedBlocks(ctx context.Context) ([]*Block, error) {
 	rows, err := db.pool.Query(ctx, `
-		SELECT id, height, hash, miner_id, worker_id, reward, difficulty, found_at, confirmed, orphaned
+		SELECT id, height, hash, COALESCE(miner_id, 0), COALESCE(worker_id, 0), reward, difficulty, found_at, confirmed, orphaned
 		FROM blocks
 		WHERE confirmed = false AND orphaned = false
 		ORDER BY height ASC
@@ -31,11 +31,18 @@ type Metrics struct {
 	BlocksOrphaned prometheus.Counter
 	BlockReward    prometheus.Gauge
 
+	BlocksRejected         *prometheus.CounterVec
+	BlockProposalsRejected *prometheus.CounterVec
+
 	// Job metrics
 	JobsTotal  prometheus.Counter
 	JobsActive prometheus.Gauge
 	JobLatency prometheus.Histogram
 
+	// Share validation metrics
+	ValidationVMs    *prometheus.GaugeVec
+	ValidationVMWait prometheus.Histogram
+
 	// Payout metrics
 	PayoutsTotal   prometheus.Counter
 	PayoutsAmount  prometheus.Counter
@@ -163,6 +170,18 @@ func New(namespace string) *Metrics {
 		Help:      "Current block reward in satoshis",
 	})
 
+	m.BlocksRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "blocks_rejected_total",
+		Help:      "Found blocks the node refused in submitblock",
+	}, []string{"reason"})
+
+	m.BlockProposalsRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "block_proposals_rejected_total",
+		Help:      "Templates whose block proposal was rejected by the node",
+	}, []string{"reason"})
+
 	// Job metrics
 	m.JobsTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: namespace,
@@ -183,6 +202,20 @@ func New(namespace string) *Metrics {
 		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 12),
 	})
 
+	// Share validation metrics
+	m.ValidationVMs = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "validation_vms",
+		Help:      "RandomX validation VMs by state",
+	}, []string{"state"}) // max, allocated, in_use
+
+	m.ValidationVMWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "validation_vm_wait_seconds",
+		Help:      "Time shares waited for a free RandomX VM",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 14),
+	})
+
 	// Payout metrics
 	m.PayoutsTotal = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: namespace,
@@ -333,9 +366,13 @@ func New(namespace string) *Metrics {
 		m.BlocksFound,
 		m.BlocksOrphaned,
 		m.BlockReward,
+		m.BlocksRejected,
+		m.BlockProposalsRejected,
 		m.JobsTotal,
 		m.JobsActive,
 		m.JobLatency,
+		m.ValidationVMs,
+		m.ValidationVMWait,
 		m.PayoutsTotal,
 		m.PayoutsAmount,
 		m.PayoutsPending,
@@ -403,6 +440,28 @@ func (m *Metrics) RecordBlock(orphaned bool) {
 	}
 }
 
+// RecordBlockRejected records a found block refused by the node
+func (m *Metrics) RecordBlockRejected(reason string) {
+	m.BlocksRejected.WithLabelValues(reason).Inc()
+}
+
+// RecordProposalRejected records a template rejected in proposal mode
+func (m *Metrics) RecordProposalRejected(reason string) {
+	m.BlockProposalsRejected.WithLabelValues(reason).Inc()
+}
+
+// RecordValidationVMs records RandomX validation VM pool usage
+func (m *Metrics) RecordValidationVMs(max, allocated, inUse int) {
+	m.ValidationVMs.WithLabelValues("max").Set(float64(max))
+	m.ValidationVMs.WithLabelValues("allocated").Set(float64(allocated))
+	m.ValidationVMs.WithLabelValues("in_use").Set(float64(inUse))
+}
+
+// RecordValidationVMWait records how long a share waited for a VM
+func (m *Metrics) RecordValidationVMWait(seconds float64) {
+	m.ValidationVMWait.Observe(seconds)
+}
+
 // RecordRPC records an RPC call
 func (m *Metrics) RecordRPC(method string, latency float64, err error) {
 	m.RPCRequests.WithLabelValues(method).Inc()
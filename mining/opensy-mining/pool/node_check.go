@@ -0,0 +1,130 @@
+// Package pool - node_check.go gates job distribution on node sync state
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Defaults for node readiness checks
+const (
+	DefaultMaxHeaderLag      = 6
+	DefaultNodeCheckInterval = 15 * time.Second
+)
+
+// ErrWrongChain is returned when the node serves a different chain than the
+// pool is configured for
+var ErrWrongChain = errors.New("node is on the wrong chain")
+
+// NodeState is the node's sync and connectivity state as last checked
+type NodeState struct {
+	Chain                string    `json:"chain"`
+	Blocks               int64     `json:"blocks"`
+	Headers              int64     `json:"headers"`
+	InitialBlockDownload bool      `json:"initial_block_download"`
+	Connections          int       `json:"connections"`
+	Ready                bool      `json:"ready"`
+	Reason               string    `json:"reason,omitempty"` // Why jobs are paused
+	CheckedAt            time.Time `json:"checked_at"`
+}
+
+// checkNode queries the node and works out whether miners may be given work.
+// A chain mismatch is returned as ErrWrongChain; every other problem is
+// reported through NodeState.Reason.
+func (s *Service) checkNode(ctx context.Context) (NodeState, error) {
+	state := NodeState{CheckedAt: time.Now()}
+
+	chainInfo, err := s.rpc.GetBlockchainInfo(ctx)
+	if err != nil {
+		state.Reason = "node unreachable"
+		return state, fmt.Errorf("getblockchaininfo: %w", err)
+	}
+	state.Chain = chainInfo.Chain
+	state.Blocks = chainInfo.Blocks
+	state.Headers = chainInfo.Headers
+	state.InitialBlockDownload = chainInfo.InitialBlockDownload
+
+	if s.cfg.Network != "" && chainInfo.Chain != s.cfg.Network {
+		state.Reason = fmt.Sprintf("node is on %q, pool expects %q", chainInfo.Chain, s.cfg.Network)
+		return state, fmt.Errorf("%w: %s", ErrWrongChain, state.Reason)
+	}
+
+	netInfo, err := s.rpc.GetNetworkInfo(ctx)
+	if err != nil {
+		state.Reason = "node unreachable"
+		return state, fmt.Errorf("getnetworkinfo: %w", err)
+	}
+	state.Connections = netInfo.Connections
+
+	switch {
+	case chainInfo.InitialBlockDownload:
+		state.Reason = "node in initial block download"
+	case chainInfo.Headers-chainInfo.Blocks > s.cfg.MaxHeaderLag:
+		state.Reason = fmt.Sprintf("node syncing, %d blocks behind headers", chainInfo.Headers-chainInfo.Blocks)
+	case !netInfo.NetworkActive || netInfo.Connections == 0:
+		state.Reason = "node has no peers"
+	default:
+		state.Ready = true
+	}
+	return state, nil
+}
+
+// updateNodeState runs a node check and pauses or resumes Stratum job
+// distribution to match
+func (s *Service) updateNodeState() error {
+	ctx, cancel := context.WithTimeout(s.ctx, 10*time.Second)
+	defer cancel()
+
+	state, err := s.checkNode(ctx)
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	s.mu.Lock()
+	prev := s.nodeState
+	s.nodeState = state
+	s.mu.Unlock()
+
+	if state.Ready {
+		if !prev.Ready && !prev.CheckedAt.IsZero() {
+			s.logger.Info("Node ready", "chain", state.Chain, "height", state.Blocks, "peers", state.Connections)
+			// The template may be stale or missing after a pause
+			if err := s.jobMgr.RefreshTemplate(); err != nil {
+				s.logger.Warn("Failed to refresh template after resume", "error", err)
+			}
+		}
+		s.stratum.Resume()
+		return nil
+	}
+
+	if state.Reason != prev.Reason {
+		s.logger.Warn("Node not ready, pausing jobs", "reason", state.Reason, "error", err)
+	}
+	s.stratum.Pause(state.Reason)
+	return err
+}
+
+func (s *Service) nodeCheckLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.NodeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.updateNodeState()
+		}
+	}
+}
+
+// NodeState returns the node state from the most recent check
+func (s *Service) NodeState() NodeState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nodeState
+}
@@ -0,0 +1,43 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+	"github.com/opensyria/opensy-mining/common/rpc/rpctest"
+)
+
+func TestCheckNode(t *testing.T) {
+	node := rpctest.NewServer(rpctest.DefaultConfig())
+	defer node.Close()
+
+	rpcCfg := rpc.DefaultClientConfig(node.URL(), "", "")
+	rpcCfg.RetryAttempts = 0
+	s := &Service{
+		cfg: Config{Network: "regtest", MaxHeaderLag: DefaultMaxHeaderLag},
+		rpc: rpc.NewClientWithConfig(rpcCfg),
+	}
+	ctx := context.Background()
+
+	state, err := s.checkNode(ctx)
+	if err != nil || !state.Ready {
+		t.Fatalf("healthy node: state %+v, err %v", state, err)
+	}
+
+	node.SetConnections(0)
+	if state, _ = s.checkNode(ctx); state.Ready || state.Reason != "node has no peers" {
+		t.Errorf("no peers: got %+v", state)
+	}
+
+	node.SetInitialBlockDownload(true)
+	if state, _ = s.checkNode(ctx); state.Ready || state.Reason != "node in initial block download" {
+		t.Errorf("IBD: got %+v", state)
+	}
+
+	s.cfg.Network = "main"
+	if _, err = s.checkNode(ctx); !errors.Is(err, ErrWrongChain) {
+		t.Errorf("wrong chain: got %v, want ErrWrongChain", err)
+	}
+}
@@ -3,26 +3,35 @@ package pool
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/opensyria/opensy-mining/common/randomx"
 	"github.com/opensyria/opensy-mining/common/rpc"
 	"github.com/opensyria/opensy-mining/pool/cache"
 	"github.com/opensyria/opensy-mining/pool/db"
+	"github.com/opensyria/opensy-mining/pool/metrics"
 	"github.com/opensyria/opensy-mining/pool/stratum"
+	"github.com/opensyria/opensy-mining/pool/validator"
 )
 
 // Config holds pool service configuration
 type Config struct {
+	// Coinbase
+	PoolAddress string // Receives block rewards; must be valid on the node
+	CoinbaseTag string // Identifies the pool in coinbase scriptSigs
+
 	// Stratum
 	StratumAddr       string
 	InitialDifficulty uint64
 	MinDifficulty     uint64
 	MaxDifficulty     uint64
 	VardiffEnabled    bool
+	NiceHash          bool // Advertise the nicehash extension to proxies
 
 	// Database
 	DBHost     string
@@ -36,15 +45,46 @@ type Config struct {
 	RedisPassword string
 
 	// Node
-	NodeURL  string
-	NodeUser string
-	NodePass string
+	NodeURL          string
+	NodeUser         string
+	NodePass         string
+	NodeCookie       string   // Node .cookie file; overrides NodeUser/NodePass
+	NodeFailoverURLs []string // Additional nodes sharing the primary's credentials
+	SubmitAllNodes   bool     // Broadcast found blocks to every node
+	NodeZMQAddr      string   // Node -zmqpubhashblock endpoint (optional)
+	ProposeBlocks    bool     // Validate templates with getblocktemplate proposals
+
+	// Node readiness: jobs are paused while the node is syncing or has no
+	// peers, and startup fails if it serves a chain other than Network
+	Network           string // Expected chain name ("main", "test", "regtest"); empty skips the check
+	MaxHeaderLag      int64  // Pause when headers are this far ahead of blocks
+	NodeCheckInterval time.Duration
+
+	// RandomX share validation
+	RandomXFullDataset bool                  // Use the 2 GB dataset, falling back to light mode
+	RandomXThreads     int                   // Dataset init threads (0 = all CPUs)
+	RandomXLargePages  bool                  // Try large pages, falling back to normal pages
+	RandomXSharedDir   string                // Share the dataset with other processes via files in this dir
+	RandomXHashers     randomx.HasherFactory // nil uses the RandomX library
+	// Validators offloads share hashing to validator services at these
+	// addresses, falling back to local hashing when none answers
+	Validators     []string
+	ValidatorToken string // Shared token the validators require
 
 	// Block confirmation
 	ConfirmationDepth int64
 	StatsInterval     time.Duration
 
-	Logger *slog.Logger
+	Metrics *metrics.Metrics // Optional
+	Logger  *slog.Logger
+}
+
+// shareStore is the part of the database that records shares and blocks
+type shareStore interface {
+	GetOrCreateMiner(ctx context.Context, address string) (*db.Miner, error)
+	GetOrCreateWorker(ctx context.Context, minerID int64, name, agent string) (*db.Worker, error)
+	RecordShare(ctx context.Context, share *db.Share) error
+	RecordBlock(ctx context.Context, block *db.Block) error
 }
 
 // Service is the main pool service
@@ -53,15 +93,18 @@ type Service struct {
 	logger *slog.Logger
 
 	// Components
-	db      *db.DB
-	cache   *cache.Cache
-	rpc     *rpc.Client
-	stratum *stratum.Server
-	jobMgr  *stratum.JobManager
+	db         *db.DB
+	shares     shareStore // db, narrowed for the share and block handlers
+	cache      *cache.Cache
+	rpc        *rpc.Client
+	stratum    *stratum.Server
+	jobMgr     *stratum.JobManager
+	validators *validator.Client // nil when hashing locally only
 
 	// State
 	currentHeight int64
 	networkDiff   float64
+	nodeState     NodeState
 	mu            sync.RWMutex
 
 	// Control
@@ -78,6 +121,10 @@ type Stats struct {
 	BlocksFound     int64
 	LastBlockHeight int64
 	NetworkDiff     uint64
+	RandomXMode     string // "full" or "light" share validation
+	// RandomX memory for the current seed
+	RandomXLargePages bool   // Large pages were obtained
+	RandomXMemory     uint64 // Bytes allocated
 }
 
 // New creates a new pool service
@@ -85,6 +132,12 @@ func New(cfg Config) (*Service, error) {
 	if cfg.Logger == nil {
 		cfg.Logger = slog.Default()
 	}
+	if cfg.MaxHeaderLag <= 0 {
+		cfg.MaxHeaderLag = DefaultMaxHeaderLag
+	}
+	if cfg.NodeCheckInterval <= 0 {
+		cfg.NodeCheckInterval = DefaultNodeCheckInterval
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -109,6 +162,7 @@ func New(cfg Config) (*Service, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 	s.db = database
+	s.shares = database
 	s.logger.Info("Connected to PostgreSQL")
 
 	// Initialize Redis cache
@@ -125,12 +179,46 @@ func New(cfg Config) (*Service, error) {
 	s.logger.Info("Connected to Redis")
 
 	// Initialize RPC client
-	s.rpc = rpc.NewClient(cfg.NodeURL, cfg.NodeUser, cfg.NodePass)
-	s.logger.Info("RPC client initialized", "url", cfg.NodeURL)
+	rpcCfg := rpc.DefaultClientConfig(cfg.NodeURL, cfg.NodeUser, cfg.NodePass)
+	rpcCfg.CookiePath = cfg.NodeCookie
+	rpcCfg.Logger = cfg.Logger
+	rpcCfg.BroadcastSubmit = cfg.SubmitAllNodes
+	// Named so that health output never carries node URLs
+	rpcCfg.Nodes = []rpc.NodeConfig{{Name: "primary", URL: cfg.NodeURL, User: cfg.NodeUser, Password: cfg.NodePass, CookiePath: cfg.NodeCookie}}
+	for i, url := range cfg.NodeFailoverURLs {
+		rpcCfg.Nodes = append(rpcCfg.Nodes, rpc.NodeConfig{Name: fmt.Sprintf("failover-%d", i+1), URL: url, User: cfg.NodeUser, Password: cfg.NodePass, CookiePath: cfg.NodeCookie})
+	}
+	s.rpc = rpc.NewClientWithConfig(rpcCfg)
+	s.logger.Info("RPC client initialized", "url", cfg.NodeURL, "failover_nodes", len(cfg.NodeFailoverURLs))
 
 	// Initialize job manager
 	jmCfg := stratum.DefaultJobManagerConfig()
+	jmCfg.PoolAddress = cfg.PoolAddress
+	if cfg.CoinbaseTag != "" {
+		jmCfg.CoinbaseTag = cfg.CoinbaseTag
+	}
+	jmCfg.ProposeBlocks = cfg.ProposeBlocks
+	jmCfg.FullDataset = cfg.RandomXFullDataset
+	jmCfg.DatasetThreads = cfg.RandomXThreads
+	jmCfg.LargePages = cfg.RandomXLargePages
+	jmCfg.SharedDataset = cfg.RandomXSharedDir
+	jmCfg.Hashers = cfg.RandomXHashers
 	jmCfg.Logger = cfg.Logger
+	if len(cfg.Validators) > 0 {
+		vCfg := validator.DefaultClientConfig()
+		vCfg.Addrs = cfg.Validators
+		vCfg.Token = cfg.ValidatorToken
+		vCfg.Logger = cfg.Logger
+		s.validators, err = validator.NewClient(vCfg)
+		if err != nil {
+			redisCache.Close()
+			database.Close()
+			cancel()
+			return nil, fmt.Errorf("failed to create validator client: %w", err)
+		}
+		jmCfg.Verifier = s.validators
+		s.logger.Info("Share validation offloaded", "validators", len(cfg.Validators))
+	}
 	s.jobMgr = stratum.NewJobManager(jmCfg, s.rpc)
 
 	// Initialize Stratum server with defaults then override
@@ -140,6 +228,7 @@ func New(cfg Config) (*Service, error) {
 	stratumCfg.MinDifficulty = cfg.MinDifficulty
 	stratumCfg.MaxDifficulty = cfg.MaxDifficulty
 	stratumCfg.VardiffEnabled = cfg.VardiffEnabled
+	stratumCfg.NiceHash = cfg.NiceHash
 	stratumCfg.Logger = cfg.Logger
 	s.stratum = stratum.NewServer(stratumCfg, s.jobMgr)
 
@@ -149,11 +238,26 @@ func New(cfg Config) (*Service, error) {
 	s.stratum.OnShareSubmit = s.handleShareSubmit
 	s.stratum.OnBlockFound = s.handleBlockFound
 
+	// Push new work to miners as soon as the template changes
+	s.jobMgr.OnTemplate = s.handleTemplate
+	s.jobMgr.OnProposalRejected = s.handleProposalRejected
+	if cfg.Metrics != nil {
+		s.jobMgr.SeedManager().OnVMWait = func(wait time.Duration) {
+			cfg.Metrics.RecordValidationVMWait(wait.Seconds())
+		}
+	}
+
 	return s, nil
 }
 
 // Start starts the pool service
 func (s *Service) Start() error {
+	// Refuse to start against the wrong chain; start paused if the node is
+	// unreachable or still syncing
+	if err := s.updateNodeState(); errors.Is(err, ErrWrongChain) {
+		return err
+	}
+
 	// Start job manager
 	if err := s.jobMgr.Start(); err != nil {
 		return fmt.Errorf("failed to start job manager: %w", err)
@@ -168,9 +272,23 @@ func (s *Service) Start() error {
 
 	// Start background loops
 	s.wg.Add(3)
-	go s.templateRefreshLoop()
 	go s.blockConfirmationLoop()
 	go s.statsLoop()
+	go s.nodeCheckLoop()
+
+	if s.cfg.NodeZMQAddr != "" {
+		zmqCfg := rpc.DefaultZMQConfig(s.cfg.NodeZMQAddr)
+		zmqCfg.Logger = s.cfg.Logger
+		sub := rpc.NewZMQSubscriber(zmqCfg)
+		sub.OnMessage = s.handleZMQMessage
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			sub.Run(s.ctx)
+		}()
+		s.logger.Info("Subscribed to node ZMQ notifications", "addr", s.cfg.NodeZMQAddr)
+	}
 
 	s.logger.Info("Pool service started")
 	return nil
@@ -194,6 +312,9 @@ func (s *Service) Stop() {
 	// Stop job manager
 	s.logger.Info("Stopping job manager...")
 	s.jobMgr.Stop()
+	if s.validators != nil {
+		s.validators.Close()
+	}
 
 	// Wait for background goroutines with timeout
 	done := make(chan struct{})
@@ -248,6 +369,7 @@ func (s *Service) Stats() (*Stats, error) {
 	currentHeight := s.currentHeight
 	s.mu.RUnlock()
 
+	alloc := s.jobMgr.SeedManager().Allocation()
 	return &Stats{
 		OnlineMiners:    dbStats.OnlineMiners,
 		OnlineWorkers:   dbStats.OnlineWorkers,
@@ -255,6 +377,10 @@ func (s *Service) Stats() (*Stats, error) {
 		BlocksFound:     dbStats.TotalBlocks,
 		LastBlockHeight: currentHeight,
 		NetworkDiff:     uint64(networkDiff),
+		RandomXMode:     s.jobMgr.SeedManager().Mode(),
+
+		RandomXLargePages: alloc.LargePages,
+		RandomXMemory:     alloc.Bytes(),
 	}, nil
 }
 
@@ -263,6 +389,11 @@ func (s *Service) ActiveMiners() int {
 	return s.stratum.SessionCount()
 }
 
+// NodeStatus returns the health and circuit state of every node backend
+func (s *Service) NodeStatus() []rpc.NodeStatus {
+	return s.rpc.Nodes()
+}
+
 // Callback handlers
 
 func (s *Service) handleMinerConnect(session *stratum.Session) {
@@ -286,12 +417,12 @@ func (s *Service) handleShareSubmit(session *stratum.Session, jobID, nonce, resu
 	ctx := context.Background()
 
 	// Get or create miner/worker records
-	miner, err := s.db.GetOrCreateMiner(ctx, session.Login)
+	miner, err := s.shares.GetOrCreateMiner(ctx, session.Login)
 	if err != nil {
 		return fmt.Errorf("failed to get miner: %w", err)
 	}
 
-	worker, err := s.db.GetOrCreateWorker(ctx, miner.ID, session.WorkerName, session.Agent)
+	worker, err := s.shares.GetOrCreateWorker(ctx, miner.ID, session.WorkerName, session.Agent)
 	if err != nil {
 		return fmt.Errorf("failed to get worker: %w", err)
 	}
@@ -317,7 +448,7 @@ func (s *Service) handleShareSubmit(session *stratum.Session, jobID, nonce, resu
 		IsBlock:    isBlock,
 	}
 
-	if err := s.db.RecordShare(ctx, share); err != nil {
+	if err := s.shares.RecordShare(ctx, share); err != nil {
 		s.logger.Error("Failed to record share", "error", err)
 	}
 
@@ -328,71 +459,97 @@ func (s *Service) handleShareSubmit(session *stratum.Session, jobID, nonce, resu
 	return nil
 }
 
-func (s *Service) handleBlockFound(session *stratum.Session, height int64, hash string) {
+func (s *Service) handleBlockFound(session *stratum.Session, found *stratum.FoundBlock) {
 	ctx := context.Background()
 
 	s.logger.Info("BLOCK FOUND!",
-		"height", height,
-		"hash", hash,
+		"height", found.Height,
+		"hash", found.Hash,
+		"accepted", found.Accepted,
+		"reason", found.Reason,
 		"miner", session.Login,
 		"worker", session.WorkerName,
 	)
-
-	// Get miner/worker IDs
-	sessionData, _ := s.cache.GetSession(ctx, session.ID)
-	if sessionData == nil {
-		s.logger.Error("Session data not found for block", "session", session.ID)
-		return
+	if s.cfg.Metrics != nil {
+		switch {
+		case found.Accepted:
+			s.cfg.Metrics.RecordBlock(false)
+		case found.Rejected:
+			s.cfg.Metrics.RecordBlockRejected(found.Reason)
+		default:
+			s.cfg.Metrics.RecordBlockRejected("submit-failed")
+		}
 	}
 
-	// Get block reward from template (10000 SYL for OpenSY)
-	reward := int64(10000_00000000) // 10000 SYL in satoshis
+	// Look the finder up directly: the session cache is only filled once a
+	// share has been recorded, and the block must be saved even if that
+	// failed. Without a finder the block is saved unattributed.
+	var minerID, workerID int64
+	if miner, err := s.shares.GetOrCreateMiner(ctx, session.Login); err != nil {
+		s.logger.Error("Failed to get miner for block", "error", err, "miner", session.Login)
+	} else {
+		minerID = miner.ID
+		if worker, err := s.shares.GetOrCreateWorker(ctx, miner.ID, session.WorkerName, session.Agent); err != nil {
+			s.logger.Error("Failed to get worker for block", "error", err, "worker", session.WorkerName)
+		} else {
+			workerID = worker.ID
+		}
+	}
 
+	// A block the node refused never enters the chain; record it as
+	// orphaned so it is never paid out. After a failed submission the node
+	// may still have the block, so confirmation decides.
+	submitResult := db.SubmitAccepted
+	if !found.Accepted {
+		submitResult = found.Reason
+	}
 	block := &db.Block{
-		Height:     height,
-		Hash:       hash,
-		MinerID:    sessionData.MinerID,
-		WorkerID:   sessionData.WorkerID,
-		Reward:     reward,
-		Difficulty: s.networkDiff,
-		FoundAt:    time.Now(),
+		Height:       found.Height,
+		Hash:         found.Hash,
+		MinerID:      minerID,
+		WorkerID:     workerID,
+		Reward:       found.Reward,
+		Difficulty:   s.networkDiff,
+		FoundAt:      time.Now(),
+		Orphaned:     found.Rejected,
+		SubmitResult: submitResult,
 	}
 
-	if err := s.db.RecordBlock(ctx, block); err != nil {
+	if err := s.shares.RecordBlock(ctx, block); err != nil {
 		s.logger.Error("Failed to record block", "error", err)
 	}
 }
 
-// Background loops
+// NotifyNewBlock triggers an immediate template refresh, e.g. from the
+// node's -blocknotify hook
+func (s *Service) NotifyNewBlock(hash string) {
+	s.jobMgr.NotifyNewBlock(hash)
+}
 
-func (s *Service) templateRefreshLoop() {
-	defer s.wg.Done()
+func (s *Service) handleZMQMessage(msg *rpc.ZMQMessage) {
+	if hash, ok := msg.BlockHash(); ok {
+		s.jobMgr.NotifyNewBlock(hash)
+	}
+}
 
-	ticker := time.NewTicker(time.Second)
-	defer ticker.Stop()
+// Background loops
 
-	for {
-		select {
-		case <-s.ctx.Done():
-			return
-		case <-ticker.C:
-			if err := s.jobMgr.RefreshTemplate(); err != nil {
-				s.logger.Error("Failed to refresh template", "error", err)
-			}
+func (s *Service) handleTemplate(template *rpc.BlockTemplate, newBlock bool) {
+	if newBlock {
+		s.mu.Lock()
+		s.currentHeight = template.Height
+		s.mu.Unlock()
+	}
 
-			// Broadcast to all miners when block changes
-			job := s.jobMgr.GetCurrentJob(s.cfg.InitialDifficulty)
-			if job != nil {
-				s.mu.Lock()
-				if job.Height != s.currentHeight {
-					s.currentHeight = job.Height
-					s.mu.Unlock()
-					s.stratum.BroadcastJob()
-				} else {
-					s.mu.Unlock()
-				}
-			}
-		}
+	// Broadcast to all miners on new blocks and mempool updates
+	s.stratum.BroadcastJob()
+}
+
+// handleProposalRejected counts templates the node refused in proposal
+// mode; the job manager has already logged the rejection
+func (s *Service) handleProposalRejected(template *rpc.BlockTemplate, reason string) {
+	if s.cfg.Metrics != nil {
+		s.cfg.Metrics.RecordProposalRejected(reason)
 	}
 }
 
@@ -429,31 +586,40 @@ func (s *Service) checkBlockConfirmations() {
 		return
 	}
 
+	// Collect blocks deep enough to settle
+	var mature []*db.Block
+	var heights []int64
 	for _, block := range blocks {
-		confirmations := blockCount - block.Height
-
-		if confirmations >= s.cfg.ConfirmationDepth {
-			// Verify block is still in main chain
-			chainHash, err := s.rpc.GetBlockHash(ctx, block.Height)
-			if err != nil {
-				s.logger.Error("Failed to get block hash", "height", block.Height, "error", err)
-				continue
-			}
+		if blockCount-block.Height >= s.cfg.ConfirmationDepth {
+			mature = append(mature, block)
+			heights = append(heights, block.Height)
+		}
+	}
+	if len(mature) == 0 {
+		return
+	}
+
+	// Verify blocks are still in main chain with one batched lookup
+	chainHashes, err := s.rpc.GetBlockHashes(ctx, heights)
+	if err != nil {
+		s.logger.Error("Failed to get block hashes", "count", len(heights), "error", err)
+		return
+	}
 
-			if chainHash == block.Hash {
-				// Block confirmed
-				if err := s.db.ConfirmBlock(ctx, block.ID); err != nil {
-					s.logger.Error("Failed to confirm block", "id", block.ID, "error", err)
-				} else {
-					s.logger.Info("Block confirmed", "height", block.Height, "hash", block.Hash)
-				}
+	for i, block := range mature {
+		if chainHashes[i] == block.Hash {
+			// Block confirmed
+			if err := s.db.ConfirmBlock(ctx, block.ID); err != nil {
+				s.logger.Error("Failed to confirm block", "id", block.ID, "error", err)
+			} else {
+				s.logger.Info("Block confirmed", "height", block.Height, "hash", block.Hash)
+			}
+		} else {
+			// Block orphaned
+			if err := s.db.OrphanBlock(ctx, block.ID); err != nil {
+				s.logger.Error("Failed to orphan block", "id", block.ID, "error", err)
 			} else {
-				// Block orphaned
-				if err := s.db.OrphanBlock(ctx, block.ID); err != nil {
-					s.logger.Error("Failed to orphan block", "id", block.ID, "error", err)
-				} else {
-					s.logger.Warn("Block orphaned", "height", block.Height, "hash", block.Hash)
-				}
+				s.logger.Warn("Block orphaned", "height", block.Height, "hash", block.Hash)
 			}
 		}
 	}
@@ -478,6 +644,9 @@ func (s *Service) statsLoop() {
 func (s *Service) updateStats() {
 	ctx := context.Background()
 
+	// Refresh node tip heights for failover scoring
+	s.rpc.UpdateNodeHealth(ctx)
+
 	// Get mining info
 	info, err := s.rpc.GetMiningInfo(ctx)
 	if err != nil {
@@ -493,6 +662,11 @@ func (s *Service) updateStats() {
 	// Get pool hashrate
 	hashrate, _ := s.cache.GetPoolHashrate(ctx, 5)
 
+	if s.cfg.Metrics != nil {
+		vms := s.jobMgr.SeedManager().VMStats()
+		s.cfg.Metrics.RecordValidationVMs(vms.Size, vms.Created, vms.InUse)
+	}
+
 	s.logger.Debug("Pool stats",
 		"height", info.Blocks,
 		"network_diff", info.Difficulty,
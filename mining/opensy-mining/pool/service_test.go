@@ -0,0 +1,59 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/opensyria/opensy-mining/pool/db"
+	"github.com/opensyria/opensy-mining/pool/stratum"
+)
+
+// fakeShareStore fails miner lookups while failMiner is set
+type fakeShareStore struct {
+	failMiner bool
+	blocks    []*db.Block
+}
+
+func (f *fakeShareStore) GetOrCreateMiner(_ context.Context, address string) (*db.Miner, error) {
+	if f.failMiner {
+		return nil, errors.New("database unavailable")
+	}
+	return &db.Miner{ID: 7, Address: address}, nil
+}
+
+func (f *fakeShareStore) GetOrCreateWorker(_ context.Context, minerID int64, name, _ string) (*db.Worker, error) {
+	return &db.Worker{ID: 9, MinerID: minerID, Name: name}, nil
+}
+
+func (f *fakeShareStore) RecordShare(context.Context, *db.Share) error {
+	return errors.New("database unavailable")
+}
+
+func (f *fakeShareStore) RecordBlock(_ context.Context, block *db.Block) error {
+	f.blocks = append(f.blocks, block)
+	return nil
+}
+
+func TestBlockSavedWhenShareRecordingFails(t *testing.T) {
+	store := &fakeShareStore{failMiner: true}
+	s := &Service{logger: slog.Default(), shares: store}
+	session := &stratum.Session{ID: "s1", Login: "syl1miner", WorkerName: "rig1"}
+	found := &stratum.FoundBlock{Height: 12, Hash: "00ab", Accepted: true, Reward: 5000}
+
+	if err := s.handleShareSubmit(session, "job", "2a000000", "", true); err == nil {
+		t.Fatal("share recorded despite the failing database")
+	}
+	s.handleBlockFound(session, found)
+	if len(store.blocks) != 1 || store.blocks[0].Hash != "00ab" || store.blocks[0].MinerID != 0 {
+		t.Fatalf("blocks = %+v, want 00ab saved without a finder", store.blocks)
+	}
+
+	// Once the miner resolves, the block is attributed without the cache
+	store.failMiner = false
+	s.handleBlockFound(session, found)
+	if b := store.blocks[1]; b.MinerID != 7 || b.WorkerID != 9 || b.SubmitResult != db.SubmitAccepted {
+		t.Errorf("block = %+v, want miner 7 worker 9 accepted", b)
+	}
+}
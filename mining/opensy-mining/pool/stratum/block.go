@@ -0,0 +1,146 @@
+// Package stratum - block.go serializes full blocks from job data
+package stratum
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+// blockSubmitTimeout bounds submitblock for a found block. It is not tied
+// to the JobManager's lifetime so a block found during shutdown still goes
+// out.
+const blockSubmitTimeout = 30 * time.Second
+
+// FoundBlock is a block solved by a miner and submitted to the node
+type FoundBlock struct {
+	Height   int64
+	Hash     string // Block hash (RPC byte order)
+	PowHash  string // RandomX result submitted by the miner
+	Reward   int64  // Coinbase value in satoshis
+	Accepted bool
+	Rejected bool   // The node refused the block
+	Reason   string // Node's rejection reason, or the error if submission failed
+}
+
+// appendVarInt appends a Bitcoin CompactSize integer
+func appendVarInt(b []byte, v uint64) []byte {
+	switch {
+	case v < 0xfd:
+		return append(b, byte(v))
+	case v <= 0xffff:
+		b = append(b, 0xfd)
+		return binary.LittleEndian.AppendUint16(b, uint16(v))
+	case v <= 0xffffffff:
+		b = append(b, 0xfe)
+		return binary.LittleEndian.AppendUint32(b, uint32(v))
+	default:
+		b = append(b, 0xff)
+		return binary.LittleEndian.AppendUint64(b, v)
+	}
+}
+
+// serializeBlock assembles header || tx count || coinbase || template txs
+func serializeBlock(header, coinbase []byte, txs []rpc.TxTemplate) ([]byte, error) {
+	if len(header) != 80 {
+		return nil, fmt.Errorf("header must be 80 bytes, got %d", len(header))
+	}
+	if len(coinbase) == 0 {
+		return nil, fmt.Errorf("missing coinbase transaction")
+	}
+
+	block := make([]byte, 0, 80+9+len(coinbase)+len(txs)*256)
+	block = append(block, header...)
+	block = appendVarInt(block, uint64(len(txs)+1))
+	block = append(block, coinbase...)
+
+	for i, tx := range txs {
+		raw, err := hex.DecodeString(tx.Data)
+		if err != nil {
+			return nil, fmt.Errorf("template tx %d: %w", i, err)
+		}
+		block = append(block, raw...)
+	}
+
+	return block, nil
+}
+
+// blockHash returns the block hash (RPC byte order) of an 80-byte header
+func blockHash(header []byte) string {
+	hash := doubleSHA256(header[:80])
+	for l, r := 0, len(hash)-1; l < r; l, r = l+1, r-1 {
+		hash[l], hash[r] = hash[r], hash[l]
+	}
+	return hex.EncodeToString(hash)
+}
+
+// SubmitBlock assembles the block for a share that met the network target
+// and submits it to the node, or to every node when the RPC client
+// broadcasts submissions. An error means the block could not be assembled;
+// the node's verdict is reported in the FoundBlock.
+func (jm *JobManager) SubmitBlock(jobID, nonce, powHash string) (*FoundBlock, error) {
+	jm.jobsMu.RLock()
+	jobData, ok := jm.jobs[jobID]
+	jm.jobsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("job not found")
+	}
+
+	nonceBytes, err := hex.DecodeString(nonce)
+	if err != nil || len(nonceBytes) != 4 {
+		return nil, fmt.Errorf("invalid nonce")
+	}
+	header := make([]byte, len(jobData.HeaderBlob))
+	copy(header, jobData.HeaderBlob)
+	copy(header[NonceOffset:], nonceBytes)
+
+	raw, err := serializeBlock(header, jobData.Coinbase, jobData.Template.Transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	found := &FoundBlock{
+		Height:  jobData.Job.Height,
+		Hash:    blockHash(header),
+		PowHash: powHash,
+		Reward:  jobData.Template.CoinbaseValue,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), blockSubmitTimeout)
+	defer cancel()
+	start := time.Now()
+	err = jm.rpc.SubmitBlock(ctx, hex.EncodeToString(raw))
+
+	var rejected *rpc.BlockRejectedError
+	switch {
+	case err == nil:
+		found.Accepted = true
+		jm.logger.Info("Block accepted by node",
+			"height", found.Height,
+			"hash", found.Hash,
+			"txs", len(jobData.Template.Transactions)+1,
+			"duration", time.Since(start),
+		)
+	case errors.As(err, &rejected):
+		found.Rejected = true
+		found.Reason = rejected.Reason
+		jm.logger.Error("Node rejected block",
+			"height", found.Height,
+			"hash", found.Hash,
+			"reason", rejected.Reason,
+		)
+	default:
+		found.Reason = err.Error()
+		jm.logger.Error("Failed to submit block",
+			"height", found.Height,
+			"hash", found.Hash,
+			"error", err,
+		)
+	}
+	return found, nil
+}
@@ -0,0 +1,50 @@
+package stratum
+
+import (
+	"testing"
+
+	"github.com/opensyria/opensy-mining/common/rpc/rpctest"
+)
+
+func TestSubmitBlock(t *testing.T) {
+	jm, node, _ := newTestJobManager(t)
+
+	node.Mine(10)
+	node.AddMempoolTx(rpctest.MempoolTx{Data: testTx(1), Fee: 500})
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+	job := jm.GetCurrentJob(&Session{ID: "session"}, 1000)
+	if job == nil {
+		t.Fatal("no job for the template")
+	}
+
+	found, err := jm.SubmitBlock(job.JobID, "2a000000", "powhash")
+	if err != nil {
+		t.Fatalf("SubmitBlock: %v", err)
+	}
+	if !found.Accepted || found.Rejected {
+		t.Fatalf("block not accepted: %+v", found)
+	}
+	tip := node.Tip()
+	if tip.Height != 11 || tip.Hash != found.Hash || tip.Nonce != 42 {
+		t.Errorf("node tip %d %s nonce %d, want 11 %s nonce 42", tip.Height, tip.Hash, tip.Nonce, found.Hash)
+	}
+	if found.Reward != jm.jobs[job.JobID].Template.CoinbaseValue {
+		t.Errorf("reward %d, want the template's coinbase value", found.Reward)
+	}
+
+	// A refused block carries the node's reason
+	node.SetSubmitResult("high-hash")
+	found, err = jm.SubmitBlock(job.JobID, "2b000000", "powhash")
+	if err != nil {
+		t.Fatalf("SubmitBlock: %v", err)
+	}
+	if found.Accepted || !found.Rejected || found.Reason != "high-hash" {
+		t.Errorf("rejected block reported as %+v", found)
+	}
+
+	if _, err := jm.SubmitBlock("missing", "2a000000", "powhash"); err == nil {
+		t.Error("SubmitBlock accepted an unknown job")
+	}
+}
@@ -0,0 +1,252 @@
+// Package stratum - coinbase.go builds the pool's coinbase transaction and
+// the merkle root that commits to it. Every job gets its own extranonce in
+// the coinbase, so no two jobs share a header blob or a nonce space.
+package stratum
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+const (
+	// DefaultCoinbaseTag identifies the pool in coinbase scriptSigs
+	DefaultCoinbaseTag = "/OpenSY Pool/"
+	// DefaultExtraNonceSize is the extranonce space reserved in the coinbase
+	DefaultExtraNonceSize = 8
+
+	maxExtraNonceSize     = 32
+	maxCoinbaseScriptSize = 100 // Consensus limit on the coinbase scriptSig
+	coinbaseVersion       = 2
+)
+
+// coinbaseTx is a serialized coinbase transaction
+type coinbaseTx struct {
+	stripped         []byte // Serialization without witness, hashed for the txid
+	extraNonceOffset int    // Start of the reserved extranonce bytes in stripped
+	extraNonceSize   int
+	witness          bool // Carries the witness reserved value for the commitment
+}
+
+// blockWork is what every job built from one template shares: the coinbase
+// with zeroed extranonce space and the merkle branch from the coinbase to
+// the root
+type blockWork struct {
+	coinbase *coinbaseTx
+	branch   [][]byte
+}
+
+// withExtraNonce returns the coinbase carrying extraNonce and the merkle
+// root (internal byte order, as in the header) that commits to it
+func (w *blockWork) withExtraNonce(extraNonce []byte) (*coinbaseTx, []byte) {
+	coinbase := w.coinbase.withExtraNonce(extraNonce)
+	return coinbase, merkleRootFromBranch(coinbase.txID(), w.branch)
+}
+
+// buildCoinbase builds the coinbase for a template: the BIP34 height, the
+// template's coinbaseaux flags, zeroed extranonce space and the pool tag in
+// the scriptSig, the reward paid to payoutScript, and the witness
+// commitment output when the template has one
+func buildCoinbase(template *rpc.BlockTemplate, payoutScript []byte, tag string, extraNonceSize int) (*coinbaseTx, error) {
+	if template.Height < 1 {
+		return nil, fmt.Errorf("bad template height %d", template.Height)
+	}
+	if len(payoutScript) == 0 {
+		return nil, fmt.Errorf("missing payout script")
+	}
+	if extraNonceSize <= 0 || extraNonceSize > maxExtraNonceSize {
+		return nil, fmt.Errorf("extranonce size %d out of range 1-%d", extraNonceSize, maxExtraNonceSize)
+	}
+
+	script := appendScriptInt(nil, template.Height)
+	if template.CoinbaseAux.Flags != "" {
+		flags, err := hex.DecodeString(template.CoinbaseAux.Flags)
+		if err != nil {
+			return nil, fmt.Errorf("coinbaseaux flags: %w", err)
+		}
+		script = appendPush(script, flags)
+	}
+	script = append(script, byte(extraNonceSize))
+	extraNonceOffset := len(script)
+	script = append(script, make([]byte, extraNonceSize)...)
+	if tag != "" {
+		script = appendPush(script, []byte(tag))
+	}
+	if len(script) > maxCoinbaseScriptSize {
+		return nil, fmt.Errorf("coinbase script is %d bytes, limit is %d (shorten the coinbase tag)", len(script), maxCoinbaseScriptSize)
+	}
+
+	var commitment []byte
+	if template.DefaultWitnessCommit != "" {
+		var err error
+		commitment, err = hex.DecodeString(template.DefaultWitnessCommit)
+		if err != nil {
+			return nil, fmt.Errorf("witness commitment: %w", err)
+		}
+	}
+
+	tx := make([]byte, 0, 128+len(script)+len(payoutScript)+len(commitment))
+	tx = binary.LittleEndian.AppendUint32(tx, coinbaseVersion)
+
+	// Single input spending the null outpoint
+	tx = appendVarInt(tx, 1)
+	tx = append(tx, make([]byte, 32)...)
+	tx = binary.LittleEndian.AppendUint32(tx, 0xffffffff)
+	tx = appendVarInt(tx, uint64(len(script)))
+	extraNonceOffset += len(tx)
+	tx = append(tx, script...)
+	tx = binary.LittleEndian.AppendUint32(tx, 0xffffffff)
+
+	outputs := 1
+	if commitment != nil {
+		outputs++
+	}
+	tx = appendVarInt(tx, uint64(outputs))
+	tx = binary.LittleEndian.AppendUint64(tx, uint64(template.CoinbaseValue))
+	tx = appendVarInt(tx, uint64(len(payoutScript)))
+	tx = append(tx, payoutScript...)
+	if commitment != nil {
+		tx = binary.LittleEndian.AppendUint64(tx, 0)
+		tx = appendVarInt(tx, uint64(len(commitment)))
+		tx = append(tx, commitment...)
+	}
+
+	tx = binary.LittleEndian.AppendUint32(tx, 0) // Lock time
+
+	return &coinbaseTx{
+		stripped:         tx,
+		extraNonceOffset: extraNonceOffset,
+		extraNonceSize:   extraNonceSize,
+		witness:          commitment != nil,
+	}, nil
+}
+
+// withExtraNonce returns a copy with extraNonce written into the reserved
+// space, zero-padded or truncated to its size
+func (c *coinbaseTx) withExtraNonce(extraNonce []byte) *coinbaseTx {
+	out := *c
+	out.stripped = append([]byte(nil), c.stripped...)
+	space := out.stripped[c.extraNonceOffset : c.extraNonceOffset+c.extraNonceSize]
+	clear(space)
+	copy(space, extraNonce)
+	return &out
+}
+
+// txID returns the coinbase txid in internal byte order
+func (c *coinbaseTx) txID() []byte {
+	return doubleSHA256(c.stripped)
+}
+
+// serialize returns the coinbase as it appears in a block. With a witness
+// commitment the single input carries the 32-byte witness reserved value.
+func (c *coinbaseTx) serialize() []byte {
+	if !c.witness {
+		return append([]byte(nil), c.stripped...)
+	}
+
+	body := c.stripped[4 : len(c.stripped)-4]
+	tx := make([]byte, 0, len(c.stripped)+2+2+32)
+	tx = append(tx, c.stripped[:4]...)
+	tx = append(tx, 0x00, 0x01) // Segwit marker and flag
+	tx = append(tx, body...)
+	tx = append(tx, 1, 32) // One witness item of 32 bytes
+	tx = append(tx, make([]byte, 32)...)
+	return append(tx, c.stripped[len(c.stripped)-4:]...)
+}
+
+// templateTxIDs returns the template's txids in internal byte order
+func templateTxIDs(txs []rpc.TxTemplate) ([][]byte, error) {
+	ids := make([][]byte, len(txs))
+	for i, tx := range txs {
+		id, err := hex.DecodeString(tx.TxID)
+		if err != nil || len(id) != 32 {
+			return nil, fmt.Errorf("template tx %d: bad txid %q", i, tx.TxID)
+		}
+		for l, r := 0, len(id)-1; l < r; l, r = l+1, r-1 {
+			id[l], id[r] = id[r], id[l]
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// merkleBranch returns the hashes the coinbase is paired with on the way
+// to the root of a Bitcoin merkle tree whose other leaves are txids. Odd
+// levels duplicate their last hash.
+func merkleBranch(txids [][]byte) [][]byte {
+	var branch [][]byte
+	level := append([][]byte{nil}, txids...) // nil stands for the coinbase
+	for len(level) > 1 {
+		branch = append(branch, level[1])
+		next := [][]byte{nil}
+		for i := 2; i < len(level); i += 2 {
+			right := level[i]
+			if i+1 < len(level) {
+				right = level[i+1]
+			}
+			next = append(next, hashPair(level[i], right))
+		}
+		level = next
+	}
+	return branch
+}
+
+// merkleRootFromBranch folds a merkle branch into the coinbase txid
+func merkleRootFromBranch(coinbaseTxID []byte, branch [][]byte) []byte {
+	root := coinbaseTxID
+	for _, h := range branch {
+		root = hashPair(root, h)
+	}
+	return root
+}
+
+func hashPair(left, right []byte) []byte {
+	pair := make([]byte, 0, 64)
+	pair = append(pair, left...)
+	pair = append(pair, right...)
+	return doubleSHA256(pair)
+}
+
+func doubleSHA256(b []byte) []byte {
+	first := sha256.Sum256(b)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+// appendScriptInt appends n the way the node's CScript << n does, which is
+// what BIP34 requires for the height: OP_0 and OP_1..OP_16 for small values,
+// otherwise a minimal little-endian script number push
+func appendScriptInt(script []byte, n int64) []byte {
+	switch {
+	case n == 0:
+		return append(script, 0x00)
+	case n >= 1 && n <= 16:
+		return append(script, byte(0x50+n))
+	}
+
+	var num []byte
+	for v := n; v > 0; v >>= 8 {
+		num = append(num, byte(v))
+	}
+	if num[len(num)-1]&0x80 != 0 {
+		num = append(num, 0x00) // Keep the number positive
+	}
+	return appendPush(script, num)
+}
+
+// appendPush appends a minimal data push
+func appendPush(script, data []byte) []byte {
+	switch {
+	case len(data) < 0x4c:
+		script = append(script, byte(len(data)))
+	case len(data) <= 0xff:
+		script = append(script, 0x4c, byte(len(data)))
+	default:
+		script = append(script, 0x4d)
+		script = binary.LittleEndian.AppendUint16(script, uint16(len(data)))
+	}
+	return append(script, data...)
+}
@@ -0,0 +1,233 @@
+package stratum
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/opensyria/opensy-mining/common/randomx/randomxtest"
+	"github.com/opensyria/opensy-mining/common/rpc"
+	"github.com/opensyria/opensy-mining/common/rpc/rpctest"
+)
+
+func TestAppendScriptInt(t *testing.T) {
+	// Heights as the node's CScript() << nHeight encodes them
+	vectors := []struct {
+		height int64
+		want   string
+	}{
+		{1, "51"},
+		{16, "60"},
+		{17, "0111"},
+		{127, "017f"},
+		{128, "028000"},
+		{255, "02ff00"},
+		{256, "020001"},
+		{32768, "03008000"},
+		{840000, "0340d10c"},
+	}
+	for _, v := range vectors {
+		if got := hex.EncodeToString(appendScriptInt(nil, v.height)); got != v.want {
+			t.Errorf("height %d: %s, want %s", v.height, got, v.want)
+		}
+	}
+}
+
+// testTx returns a minimal serialized non-witness transaction
+func testTx(i byte) string {
+	tx := binary.LittleEndian.AppendUint32(nil, 2)
+	tx = append(tx, 1)
+	tx = append(tx, bytes.Repeat([]byte{i}, 32)...)
+	tx = append(tx, 0, 0, 0, 0, 0) // vout 0, empty scriptSig
+	tx = append(tx, 0xff, 0xff, 0xff, 0xff)
+	tx = append(tx, 1)
+	tx = binary.LittleEndian.AppendUint64(tx, 1000)
+	tx = append(tx, 1, 0x51) // OP_TRUE
+	tx = append(tx, 0, 0, 0, 0)
+	return hex.EncodeToString(tx)
+}
+
+// checkMerkleRoot checks that a job's header commits to its coinbase and
+// every template tx, using the fake node's txid and merkle code
+func checkMerkleRoot(t *testing.T, data *JobData) {
+	t.Helper()
+	txids := make([][]byte, 0, len(data.Template.Transactions)+1)
+	for _, id := range append([]string{rpctest.TxID(hex.EncodeToString(data.Coinbase))}, txIDs(data.Template)...) {
+		raw, _ := hex.DecodeString(id)
+		for l, r := 0, len(raw)-1; l < r; l, r = l+1, r-1 {
+			raw[l], raw[r] = raw[r], raw[l]
+		}
+		txids = append(txids, raw)
+	}
+	if want := rpctest.MerkleRoot(txids); !bytes.Equal(data.HeaderBlob[36:68], want) {
+		t.Errorf("job %s merkle root %x, want %x", data.Job.JobID, data.HeaderBlob[36:68], want)
+	}
+}
+
+func txIDs(template *rpc.BlockTemplate) []string {
+	ids := make([]string, len(template.Transactions))
+	for i, tx := range template.Transactions {
+		ids[i] = tx.TxID
+	}
+	return ids
+}
+
+const testPoolAddress = "rsyl1qpooltestaddress"
+
+// newTestJobManager returns a job manager paying testPoolAddress on a fake
+// node; proposal rejections fail the test
+func newTestJobManager(t *testing.T) (*JobManager, *rpctest.Node, *rpc.Client) {
+	t.Helper()
+	node := rpctest.NewServer(rpctest.DefaultConfig())
+	t.Cleanup(node.Close)
+	client := rpc.NewClient(node.URL(), "", "")
+
+	cfg := DefaultJobManagerConfig()
+	cfg.PoolAddress = testPoolAddress
+	cfg.Hashers = randomxtest.FakeHasherFactory{}
+	jm := NewJobManager(cfg, client)
+	t.Cleanup(jm.Stop)
+	jm.OnProposalRejected = func(_ *rpc.BlockTemplate, reason string) {
+		t.Errorf("node rejected proposal: %s", reason)
+	}
+	return jm, node, client
+}
+
+func TestJobCommitsToCoinbase(t *testing.T) {
+	jm, node, client := newTestJobManager(t)
+
+	node.Mine(40)
+	for i := byte(1); i <= 5; i++ {
+		node.AddMempoolTx(rpctest.MempoolTx{Data: testTx(i), Fee: 500})
+	}
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+	job := jm.GetCurrentJob(&Session{ID: "session"}, 1000)
+	if job == nil {
+		t.Fatal("no job for the template")
+	}
+	data := jm.jobs[job.JobID]
+	template, header, coinbase := data.Template, data.HeaderBlob, data.Coinbase
+
+	if len(template.Transactions) != 5 {
+		t.Fatalf("%d template txs, want 5", len(template.Transactions))
+	}
+	checkMerkleRoot(t, data)
+	if got := hex.EncodeToString(header[72:76]); got != "ffff7f20" {
+		t.Errorf("header bits %s, want little-endian 207fffff", got)
+	}
+
+	// Segwit serialization; the scriptSig starts with the BIP34 height 41
+	if !bytes.Equal(coinbase[4:6], []byte{0x00, 0x01}) {
+		t.Errorf("coinbase is not witness serialized: %x", coinbase[:8])
+	}
+	if !bytes.Equal(coinbase[44:46], []byte{0x01, 41}) {
+		t.Errorf("scriptSig starts %x, want height push 0129", coinbase[43:47])
+	}
+	payout, _ := hex.DecodeString(rpctest.ScriptPubKey(testPoolAddress))
+	commitment, _ := hex.DecodeString(template.DefaultWitnessCommit)
+	value := binary.LittleEndian.AppendUint64(nil, uint64(template.CoinbaseValue))
+	for name, want := range map[string][]byte{
+		"pool tag":           []byte(DefaultCoinbaseTag),
+		"payout output":      append(append(value, byte(len(payout))), payout...),
+		"witness commitment": commitment,
+	} {
+		if !bytes.Contains(coinbase, want) {
+			t.Errorf("coinbase %x lacks %s", coinbase, name)
+		}
+	}
+
+	block, err := serializeBlock(header, coinbase, template.Transactions)
+	if err != nil {
+		t.Fatalf("serializeBlock: %v", err)
+	}
+	if err := client.ProposeBlock(context.Background(), hex.EncodeToString(block)); err != nil {
+		t.Errorf("ProposeBlock: %v", err)
+	}
+
+	// An address the node refuses stops startup
+	cfg := DefaultJobManagerConfig()
+	cfg.PoolAddress = "1notanaddress"
+	bad := NewJobManager(cfg, client)
+	defer bad.Stop()
+	if err := bad.Start(); !errors.Is(err, ErrPoolAddress) {
+		t.Errorf("Start with invalid address: %v, want ErrPoolAddress", err)
+	}
+}
+
+func TestJobsHaveUniqueExtraNonce(t *testing.T) {
+	jm, node, _ := newTestJobManager(t)
+
+	node.Mine(3)
+	for i := byte(1); i <= 3; i++ {
+		node.AddMempoolTx(rpctest.MempoolTx{Data: testTx(i), Fee: 500})
+	}
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+
+	// Two jobs for one session and one for another
+	blobs := make(map[string]bool)
+	var jobs []*JobData
+	for _, sessionID := range []string{"a", "a", "b"} {
+		job := jm.GetCurrentJob(&Session{ID: sessionID}, 1000)
+		if blobs[job.Blob] {
+			t.Fatalf("job %s repeats a blob", job.JobID)
+		}
+		blobs[job.Blob] = true
+
+		data := jm.jobs[job.JobID]
+		if !bytes.Contains(data.Coinbase, data.ExtraNonce) || len(data.ExtraNonce) != DefaultExtraNonceSize {
+			t.Errorf("coinbase %x lacks extranonce %x", data.Coinbase, data.ExtraNonce)
+		}
+		checkMerkleRoot(t, data)
+		jobs = append(jobs, data)
+	}
+
+	// A job is only valid for the session it was sent to
+	other := &Session{ID: "b", Difficulty: 1}
+	if _, err := jm.ValidateShare(other, jobs[0].Job.JobID, "00000000", hex.EncodeToString(make([]byte, 32))); err == nil || err.Error() != "job not found" {
+		t.Errorf("share on another session's job: %v, want job not found", err)
+	}
+
+	// The branch folds to the same root as the full tree for any tx count
+	for n := 0; n <= 8; n++ {
+		leaves := [][]byte{bytes.Repeat([]byte{0xcb}, 32)}
+		for i := 0; i < n; i++ {
+			leaves = append(leaves, bytes.Repeat([]byte{byte(i)}, 32))
+		}
+		got := merkleRootFromBranch(leaves[0], merkleBranch(leaves[1:]))
+		if want := rpctest.MerkleRoot(leaves); !bytes.Equal(got, want) {
+			t.Errorf("%d txs: root %x, want %x", n, got, want)
+		}
+	}
+}
+
+func TestNiceHashLeavesTopNonceByte(t *testing.T) {
+	jm, node, _ := newTestJobManager(t)
+
+	node.Mine(3)
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+
+	session := &Session{ID: "proxy", Difficulty: 1, NiceHash: true}
+	job := jm.GetCurrentJob(session, 1000)
+	blob, _ := hex.DecodeString(job.Blob)
+	if blob[NonceOffset+3] != 0 {
+		t.Fatalf("blob nonce %x presets the top byte", blob[NonceOffset:])
+	}
+
+	// The proxy owns the top byte: any value validates
+	nonce := []byte{0x01, 0x02, 0x03, 0x7a}
+	copy(blob[NonceOffset:], nonce)
+	seed, _ := hex.DecodeString(job.SeedHash)
+	hash := randomxtest.FakeHash(seed, blob, 0)
+	if _, err := jm.ValidateShare(session, job.JobID, hex.EncodeToString(nonce), hex.EncodeToString(hash[:])); err != nil {
+		t.Errorf("share with proxy nonce byte 7a: %v", err)
+	}
+}
@@ -6,27 +6,76 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/opensyria/opensy-mining/common/randomx"
 	"github.com/opensyria/opensy-mining/common/rpc"
 )
 
+// ErrPoolAddress is returned by Start when the pool address is missing or
+// the node does not accept it
+var ErrPoolAddress = errors.New("invalid pool address")
+
+// ShareVerifier hashes shares outside this process, e.g. on a set of
+// validator services. ValidateShare falls back to the local SeedManager
+// whenever it returns an error other than ErrUnknownSeed.
+type ShareVerifier interface {
+	// SetSeeds tells the verifier the current template's seed and the next
+	// one (nil if unknown); shares for other seeds may fail with
+	// ErrUnknownSeed
+	SetSeeds(current, next []byte)
+	VerifyShare(ctx context.Context, seed, blob []byte, nonce uint32, claimed []byte) ([randomx.HashSize]byte, error)
+}
+
 // JobManagerConfig holds job manager configuration
 type JobManagerConfig struct {
+	// PoolAddress receives block rewards; its scriptPubKey is looked up
+	// with validateaddress
+	PoolAddress    string
+	CoinbaseTag    string // Pushed into the coinbase scriptSig to identify the pool
+	ExtraNonceSize int    // Bytes reserved in the coinbase scriptSig for extranonces
+
 	SeedInterval    int64         // Blocks between RandomX seed changes (32 for OpenSY)
-	TemplateRefresh time.Duration // How often to refresh block template
-	Logger          *slog.Logger
+	LongPoll        bool          // Follow templates with getblocktemplate long polling
+	TemplateRefresh time.Duration // Poll interval when long polling is off or unsupported
+	// SeedGraceWindow keeps the previous RandomX seed usable after a key
+	// change so late shares on old jobs still validate
+	SeedGraceWindow time.Duration
+	ValidationVMs   int // RandomX VMs per seed for parallel share validation
+	// FullDataset validates shares against the 2 GB RandomX dataset,
+	// falling back to light mode if it cannot be allocated
+	FullDataset    bool
+	DatasetThreads int
+	LargePages     bool   // Back RandomX memory with large pages if available
+	SharedDataset  string // Directory for datasets shared with workers on the host
+	// Hashers builds RandomX hashers for share validation; nil uses the
+	// RandomX library. Tests use randomxtest.FakeHasherFactory.
+	Hashers randomx.HasherFactory
+	// Verifier offloads share hashing to remote validators; nil hashes
+	// every share locally
+	Verifier ShareVerifier
+	// ProposeBlocks validates every template-derived block skeleton with
+	// getblocktemplate proposal mode before jobs are broadcast
+	ProposeBlocks bool
+	Logger        *slog.Logger
 }
 
 // DefaultJobManagerConfig returns default configuration
 func DefaultJobManagerConfig() JobManagerConfig {
 	return JobManagerConfig{
+		CoinbaseTag:     DefaultCoinbaseTag,
+		ExtraNonceSize:  DefaultExtraNonceSize,
 		SeedInterval:    32, // OpenSY uses 32-block seed interval
+		LongPoll:        true,
 		TemplateRefresh: time.Second,
+		SeedGraceWindow: DefaultSeedManagerConfig().GraceWindow,
+		ValidationVMs:   DefaultSeedManagerConfig().VMs,
+		ProposeBlocks:   true,
 		Logger:          slog.Default(),
 	}
 }
@@ -37,23 +86,48 @@ type JobManager struct {
 	rpc    *rpc.Client
 	logger *slog.Logger
 
-	// Current template
+	// Current template and the coinbase built for it
 	template   *rpc.BlockTemplate
+	work       *blockWork
 	templateMu sync.RWMutex
+	// Serializes installing templates from the watcher and RefreshTemplate
+	applyMu sync.Mutex
+
+	// Pool address scriptPubKey, resolved on first use
+	payoutScript []byte
+	payoutMu     sync.Mutex
+
+	// Last extranonce handed out; starts at a random value so jobs do not
+	// repeat across restarts
+	extraNonce atomic.Uint64
 
 	// Jobs
 	jobs   map[string]*JobData // jobID -> job data
 	jobsMu sync.RWMutex
 
-	// RandomX context
-	rxCtx    *randomx.Context
-	rxMu     sync.Mutex
-	seedHash string
+	// RandomX contexts for share validation
+	seeds *SeedManager
+	// Set while the Verifier is failing and shares are hashed locally
+	verifierDown atomic.Bool
+	// Set once seeds are loaded locally. With a Verifier this waits for the
+	// first remote failure, so a healthy frontend builds no RandomX memory.
+	localSeeds atomic.Bool
 
 	// Submitted share tracking (for duplicate detection)
 	submittedShares   map[string]struct{}
 	submittedSharesMu sync.RWMutex
 
+	// OnTemplate is called whenever the template changes. newBlock is true
+	// when the previous block changed, false for mempool-only updates.
+	OnTemplate func(template *rpc.BlockTemplate, newBlock bool)
+
+	// OnProposalRejected is called when the node rejects the block skeleton
+	// built from a template. Jobs for that template are not broadcast.
+	OnProposalRejected func(template *rpc.BlockTemplate, reason string)
+
+	// New-block pushes (ZMQ, blocknotify), coalesced
+	notify chan string
+
 	// Control
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -64,7 +138,10 @@ type JobManager struct {
 type JobData struct {
 	Job         *Job
 	Template    *rpc.BlockTemplate
+	SessionID   string // Session the job was issued to
+	ExtraNonce  []byte // Written into this job's coinbase
 	HeaderBlob  []byte // Raw header for RandomX hashing
+	Coinbase    []byte // Serialized coinbase committed to by HeaderBlob
 	CreatedAt   time.Time
 	TargetValue uint64 // Target as uint64 for comparison
 }
@@ -74,30 +151,71 @@ func NewJobManager(cfg JobManagerConfig, rpcClient *rpc.Client) *JobManager {
 	if cfg.Logger == nil {
 		cfg.Logger = slog.Default()
 	}
+	if cfg.ExtraNonceSize <= 0 {
+		cfg.ExtraNonceSize = DefaultExtraNonceSize
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &JobManager{
+	seedCfg := DefaultSeedManagerConfig()
+	if cfg.SeedGraceWindow > 0 {
+		seedCfg.GraceWindow = cfg.SeedGraceWindow
+	}
+	if cfg.ValidationVMs > 0 {
+		seedCfg.VMs = cfg.ValidationVMs
+	}
+	seedCfg.FullDataset = cfg.FullDataset
+	seedCfg.DatasetThreads = cfg.DatasetThreads
+	seedCfg.SharedDatasetDir = cfg.SharedDataset
+	if cfg.LargePages {
+		seedCfg.Flags |= randomx.FlagLargePages
+	}
+	seedCfg.Hashers = cfg.Hashers
+	seedCfg.Logger = cfg.Logger
+
+	jm := &JobManager{
 		cfg:             cfg,
 		rpc:             rpcClient,
 		logger:          cfg.Logger.With("component", "job-manager"),
 		jobs:            make(map[string]*JobData),
 		submittedShares: make(map[string]struct{}),
+		seeds:           NewSeedManager(seedCfg),
+		notify:          make(chan string, 1),
 		ctx:             ctx,
 		cancel:          cancel,
 	}
+	var start [8]byte
+	rand.Read(start[:])
+	jm.extraNonce.Store(binary.LittleEndian.Uint64(start[:]))
+	jm.localSeeds.Store(cfg.Verifier == nil)
+	return jm
 }
 
 // Start starts the job manager
 func (jm *JobManager) Start() error {
-	// Initial template fetch
+	// Blocks must pay the pool; an address the node refuses is fatal, while
+	// an unreachable node is retried with the first template
+	if _, err := jm.payoutScriptPubKey(); err != nil {
+		if errors.Is(err, ErrPoolAddress) || !rpc.IsTransient(err) {
+			return err
+		}
+		jm.logger.Warn("Could not look up pool address, will retry", "error", err)
+	}
+
+	// Initial template fetch. A node that is still warming up or syncing
+	// refuses getblocktemplate; keep retrying in the refresh loop instead
+	// of failing startup.
 	if err := jm.RefreshTemplate(); err != nil {
-		return fmt.Errorf("failed to get initial template: %w", err)
+		if !rpc.IsTransient(err) {
+			return fmt.Errorf("failed to get initial template: %w", err)
+		}
+		jm.logger.Warn("Initial template unavailable, will retry", "error", err)
 	}
 
-	// Start template refresh loop
-	jm.wg.Add(1)
+	// Start template refresh and notification loops
+	jm.wg.Add(2)
 	go jm.refreshLoop()
+	go jm.notifyLoop()
 
 	return nil
 }
@@ -108,17 +226,26 @@ func (jm *JobManager) Stop() {
 	jm.wg.Wait()
 
 	// Cleanup RandomX
-	jm.rxMu.Lock()
-	if jm.rxCtx != nil {
-		jm.rxCtx.Close()
-		jm.rxCtx = nil
-	}
-	jm.rxMu.Unlock()
+	jm.seeds.Close()
 }
 
 func (jm *JobManager) refreshLoop() {
 	defer jm.wg.Done()
 
+	if jm.cfg.LongPoll {
+		jm.templateMu.RLock()
+		longPollID := ""
+		if jm.template != nil {
+			longPollID = jm.template.LongPollID
+		}
+		jm.templateMu.RUnlock()
+
+		watcher := NewTemplateWatcher(jm.rpc, jm.cfg.TemplateRefresh, jm.cfg.Logger)
+		watcher.OnTemplate = jm.applyTemplate
+		watcher.Run(jm.ctx, longPollID)
+		return
+	}
+
 	ticker := time.NewTicker(jm.cfg.TemplateRefresh)
 	defer ticker.Stop()
 
@@ -134,32 +261,132 @@ func (jm *JobManager) refreshLoop() {
 	}
 }
 
+// NotifyNewBlock tells the job manager the node has a new tip, e.g. from a
+// ZMQ hashblock message or the -blocknotify hook. The template is refreshed
+// immediately instead of waiting for the long poll or the next poll tick.
+// Notifications arriving while a refresh is pending are coalesced.
+func (jm *JobManager) NotifyNewBlock(hash string) {
+	select {
+	case jm.notify <- hash:
+	default:
+	}
+}
+
+func (jm *JobManager) notifyLoop() {
+	defer jm.wg.Done()
+
+	for {
+		select {
+		case <-jm.ctx.Done():
+			return
+		case hash := <-jm.notify:
+			jm.templateMu.RLock()
+			current := jm.template
+			jm.templateMu.RUnlock()
+			if current != nil && current.PreviousBlockHash == hash {
+				continue // Already working on top of this block
+			}
+
+			jm.logger.Debug("New block notification", "hash", hash)
+			if err := jm.RefreshTemplate(); err != nil {
+				jm.logger.Error("Failed to refresh template", "error", err)
+			}
+		}
+	}
+}
+
 // RefreshTemplate fetches a new block template from the node
 func (jm *JobManager) RefreshTemplate() error {
+	// Hold applyMu across the fetch so a concurrent long poll result cannot
+	// land between fetching this template and installing it
+	jm.applyMu.Lock()
+	defer jm.applyMu.Unlock()
+
 	template, err := jm.rpc.GetBlockTemplate(jm.ctx)
 	if err != nil {
 		return err
 	}
 
-	jm.templateMu.Lock()
-	oldHeight := int64(0)
-	if jm.template != nil {
-		oldHeight = jm.template.Height
+	jm.installTemplate(template)
+	return nil
+}
+
+// applyTemplate installs a template pushed by the long poll watcher
+func (jm *JobManager) applyTemplate(template *rpc.BlockTemplate) {
+	jm.applyMu.Lock()
+	defer jm.applyMu.Unlock()
+	jm.installTemplate(template)
+}
+
+// installTemplate installs a template fetched from the node and notifies
+// OnTemplate if it differs from the current one. Templates below the
+// current height are dropped so a slow fetch cannot move miners back onto
+// an old block. The caller holds applyMu.
+func (jm *JobManager) installTemplate(template *rpc.BlockTemplate) {
+	jm.templateMu.RLock()
+	current := jm.template
+	jm.templateMu.RUnlock()
+	if current != nil && template.Height < current.Height {
+		jm.logger.Debug("Dropping stale template",
+			"height", template.Height,
+			"current", current.Height,
+		)
+		return
 	}
-	jm.template = template
-	jm.templateMu.Unlock()
 
-	// Check if seed changed
-	if template.SeedHash != jm.seedHash {
-		jm.logger.Info("RandomX seed changed",
-			"old", jm.seedHash,
-			"new", template.SeedHash,
+	work, err := jm.buildWork(template)
+	if err != nil {
+		jm.logger.Error("Failed to build coinbase, not broadcasting template",
+			"height", template.Height,
+			"error", err,
 		)
-		if err := jm.updateRandomXSeed(template.SeedHash); err != nil {
-			jm.logger.Error("Failed to update RandomX seed", "error", err)
+		return
+	}
+
+	if jm.cfg.ProposeBlocks {
+		if err := jm.proposeTemplate(template, work); err != nil {
+			var rejected *rpc.BlockRejectedError
+			if errors.As(err, &rejected) {
+				// Keep miners on the previous template rather than hand out
+				// work the node would refuse
+				jm.logger.Error("Node rejected block proposal, not broadcasting template",
+					"height", template.Height,
+					"reason", rejected.Reason,
+				)
+				if jm.OnProposalRejected != nil {
+					jm.OnProposalRejected(template, rejected.Reason)
+				}
+				return
+			}
+			jm.logger.Warn("Block proposal check failed", "height", template.Height, "error", err)
 		}
 	}
 
+	jm.templateMu.Lock()
+	old := jm.template
+	jm.template = template
+	jm.work = work
+	jm.templateMu.Unlock()
+
+	oldHeight := int64(0)
+	if old != nil {
+		oldHeight = old.Height
+	}
+	newBlock := old == nil || old.PreviousBlockHash != template.PreviousBlockHash
+	changed := newBlock ||
+		old.LongPollID != template.LongPollID ||
+		len(old.Transactions) != len(template.Transactions)
+
+	if jm.cfg.Verifier != nil {
+		current, _ := hex.DecodeString(template.SeedHash)
+		next, _ := hex.DecodeString(template.NextSeedHash)
+		jm.cfg.Verifier.SetSeeds(current, next)
+	}
+	// A frontend with a Verifier loads no seeds until the Verifier first fails
+	if jm.localSeeds.Load() {
+		jm.updateSeeds(template)
+	}
+
 	// Log new block
 	if template.Height != oldHeight {
 		jm.logger.Info("New block template",
@@ -172,62 +399,143 @@ func (jm *JobManager) RefreshTemplate() error {
 		jm.cleanOldJobs()
 	}
 
-	return nil
+	if changed && jm.OnTemplate != nil {
+		jm.OnTemplate(template, newBlock)
+	}
 }
 
-func (jm *JobManager) updateRandomXSeed(seedHash string) error {
-	seedBytes, err := hex.DecodeString(seedHash)
-	if err != nil {
-		return fmt.Errorf("invalid seed hash: %w", err)
+// updateSeeds switches the local RandomX contexts to the template's seed
+// and pre-warms the next one. The caller holds applyMu.
+func (jm *JobManager) updateSeeds(template *rpc.BlockTemplate) {
+	// The context is normally pre-warmed from the previous templates'
+	// NextSeedHash, so the switch does not block
+	if current := jm.seeds.Current(); template.SeedHash != current {
+		jm.logger.Info("RandomX seed changed",
+			"old", current,
+			"new", template.SeedHash,
+		)
+		if err := jm.seeds.SetCurrent(template.SeedHash); err != nil {
+			jm.logger.Error("Failed to update RandomX seed", "error", err)
+		}
 	}
+	if template.NextSeedHash != "" && template.NextSeedHash != template.SeedHash {
+		if err := jm.seeds.Prewarm(template.NextSeedHash); err != nil {
+			jm.logger.Warn("Failed to pre-warm next RandomX seed", "error", err)
+		}
+	}
+}
+
+// enableLocalSeeds starts loading seeds locally after the Verifier first
+// fails, building the current template's context before returning
+func (jm *JobManager) enableLocalSeeds() {
+	jm.applyMu.Lock()
+	defer jm.applyMu.Unlock()
+	if jm.localSeeds.Load() {
+		return
+	}
+	jm.localSeeds.Store(true)
 
-	jm.rxMu.Lock()
-	defer jm.rxMu.Unlock()
+	jm.templateMu.RLock()
+	template := jm.template
+	jm.templateMu.RUnlock()
+	if template != nil {
+		jm.logger.Info("Building local RandomX context for share validation fallback")
+		jm.updateSeeds(template)
+	}
+}
 
-	// Close old context
-	if jm.rxCtx != nil {
-		jm.rxCtx.Close()
-		jm.rxCtx = nil
+// proposeTemplate builds the block skeleton a miner would solve for this
+// template and asks the node to validate it
+func (jm *JobManager) proposeTemplate(template *rpc.BlockTemplate, work *blockWork) error {
+	root := merkleRootFromBranch(work.coinbase.txID(), work.branch)
+	block, err := serializeBlock(jm.buildHeaderBlob(template, root), work.coinbase.serialize(), template.Transactions)
+	if err != nil {
+		return err
 	}
+	return jm.rpc.ProposeBlock(jm.ctx, hex.EncodeToString(block))
+}
 
-	// Create new context with recommended flags
-	ctx, err := randomx.NewContext(randomx.FlagDefault)
+// buildWork builds the pool's coinbase for a template and the merkle
+// branch over the template's transactions
+func (jm *JobManager) buildWork(template *rpc.BlockTemplate) (*blockWork, error) {
+	payoutScript, err := jm.payoutScriptPubKey()
+	if err != nil {
+		return nil, err
+	}
+	coinbase, err := buildCoinbase(template, payoutScript, jm.cfg.CoinbaseTag, jm.cfg.ExtraNonceSize)
 	if err != nil {
-		return fmt.Errorf("failed to create context: %w", err)
+		return nil, err
+	}
+	txids, err := templateTxIDs(template.Transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	return &blockWork{
+		coinbase: coinbase,
+		branch:   merkleBranch(txids),
+	}, nil
+}
+
+// payoutScriptPubKey returns the pool address's scriptPubKey, asking the
+// node on first use
+func (jm *JobManager) payoutScriptPubKey() ([]byte, error) {
+	jm.payoutMu.Lock()
+	defer jm.payoutMu.Unlock()
+
+	if jm.payoutScript != nil {
+		return jm.payoutScript, nil
+	}
+	if jm.cfg.PoolAddress == "" {
+		return nil, fmt.Errorf("%w: no pool address configured", ErrPoolAddress)
 	}
 
-	// Initialize cache with seed
-	if err := ctx.InitCache(seedBytes); err != nil {
-		ctx.Close()
-		return fmt.Errorf("failed to init cache: %w", err)
+	info, err := jm.rpc.ValidateAddress(jm.ctx, jm.cfg.PoolAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate pool address: %w", err)
+	}
+	if !info.IsValid {
+		return nil, fmt.Errorf("%w: node rejects %s", ErrPoolAddress, jm.cfg.PoolAddress)
+	}
+	script, err := hex.DecodeString(info.ScriptPubKey)
+	if err != nil || len(script) == 0 {
+		return nil, fmt.Errorf("%w: bad scriptPubKey %q for %s", ErrPoolAddress, info.ScriptPubKey, jm.cfg.PoolAddress)
 	}
 
-	jm.rxCtx = ctx
-	jm.seedHash = seedHash
+	jm.payoutScript = script
+	return script, nil
+}
 
-	jm.logger.Info("RandomX context updated", "seed", seedHash[:16]+"...")
-	return nil
+// SeedManager returns the RandomX seed manager used for share validation
+func (jm *JobManager) SeedManager() *SeedManager {
+	return jm.seeds
 }
 
-// GetCurrentJob returns the current job with specified difficulty
-func (jm *JobManager) GetCurrentJob(difficulty uint64) *Job {
+// GetCurrentJob returns a job on the current template for a session. Each
+// call gets a fresh extranonce, so its blob differs from every other job's.
+// NiceHash proxies split the top nonce byte among their miners, so every
+// sub-range they hand out sits under this job's own extranonce.
+func (jm *JobManager) GetCurrentJob(session *Session, difficulty uint64) *Job {
 	jm.templateMu.RLock()
 	template := jm.template
+	work := jm.work
 	jm.templateMu.RUnlock()
 
 	if template == nil {
 		return nil
 	}
 
-	return jm.createJob(template, difficulty)
+	return jm.createJob(template, work, session, difficulty)
 }
 
-func (jm *JobManager) createJob(template *rpc.BlockTemplate, difficulty uint64) *Job {
+func (jm *JobManager) createJob(template *rpc.BlockTemplate, work *blockWork, session *Session, difficulty uint64) *Job {
 	// Generate unique job ID
 	jobID := jm.generateJobID()
 
-	// Create block header blob for mining
-	headerBlob := jm.buildHeaderBlob(template)
+	// Create block header blob for mining over this job's coinbase
+	extraNonce := jm.nextExtraNonce()
+	coinbase, root := work.withExtraNonce(extraNonce)
+	headerBlob := jm.buildHeaderBlob(template, root)
 
 	job := &Job{
 		JobID:    jobID,
@@ -235,14 +543,17 @@ func (jm *JobManager) createJob(template *rpc.BlockTemplate, difficulty uint64)
 		Target:   DifficultyToCompact(difficulty),
 		Height:   template.Height,
 		SeedHash: template.SeedHash,
-		Algo:     "rx/0",
+		Algo:     AlgoOpenSY,
 	}
 
 	// Store job data
 	jobData := &JobData{
 		Job:         job,
 		Template:    template,
+		SessionID:   session.ID,
+		ExtraNonce:  extraNonce,
 		HeaderBlob:  headerBlob,
+		Coinbase:    coinbase.serialize(),
 		CreatedAt:   time.Now(),
 		TargetValue: difficulty,
 	}
@@ -254,13 +565,22 @@ func (jm *JobManager) createJob(template *rpc.BlockTemplate, difficulty uint64)
 	return job
 }
 
+// nextExtraNonce returns the extranonce for a new job
+func (jm *JobManager) nextExtraNonce() []byte {
+	var counter [8]byte
+	binary.LittleEndian.PutUint64(counter[:], jm.extraNonce.Add(1))
+	extraNonce := make([]byte, jm.cfg.ExtraNonceSize)
+	copy(extraNonce, counter[:])
+	return extraNonce
+}
+
 func (jm *JobManager) generateJobID() string {
 	b := make([]byte, 4)
 	rand.Read(b)
 	return hex.EncodeToString(b)
 }
 
-func (jm *JobManager) buildHeaderBlob(template *rpc.BlockTemplate) []byte {
+func (jm *JobManager) buildHeaderBlob(template *rpc.BlockTemplate, merkleRoot []byte) []byte {
 	// Build 80-byte block header
 	// This follows Bitcoin-style format:
 	// - Version: 4 bytes (little-endian)
@@ -270,7 +590,7 @@ func (jm *JobManager) buildHeaderBlob(template *rpc.BlockTemplate) []byte {
 	// - Bits: 4 bytes
 	// - Nonce: 4 bytes (placeholder, miner fills this)
 
-	header := make([]byte, 80)
+	header := make([]byte, BlobSize)
 
 	// Version
 	binary.LittleEndian.PutUint32(header[0:4], uint32(template.Version))
@@ -281,33 +601,24 @@ func (jm *JobManager) buildHeaderBlob(template *rpc.BlockTemplate) []byte {
 		header[4+i] = prevHash[31-i]
 	}
 
-	// Merkle root would be calculated from transactions
-	// For now, use a placeholder - real implementation needs coinbase + tx merkle
-	merkleRoot := jm.calculateMerkleRoot(template)
+	// Merkle root over the job's coinbase and the template transactions
 	copy(header[36:68], merkleRoot)
 
 	// Timestamp
 	binary.LittleEndian.PutUint32(header[68:72], uint32(template.CurTime))
 
-	// Bits
+	// Bits (the template's hex is big-endian)
 	bits, _ := hex.DecodeString(template.Bits)
-	copy(header[72:76], bits)
+	for i := 0; i < len(bits) && i < 4; i++ {
+		header[72+i] = bits[len(bits)-1-i]
+	}
 
 	// Nonce (placeholder - miner fills this)
-	binary.LittleEndian.PutUint32(header[76:80], 0)
+	binary.LittleEndian.PutUint32(header[NonceOffset:], 0)
 
 	return header
 }
 
-func (jm *JobManager) calculateMerkleRoot(template *rpc.BlockTemplate) []byte {
-	// Simplified: in production, build proper coinbase and merkle tree
-	// For now, return a hash of the coinbase value and height
-	root := make([]byte, 32)
-	binary.LittleEndian.PutUint64(root[0:8], uint64(template.CoinbaseValue))
-	binary.LittleEndian.PutUint64(root[8:16], uint64(template.Height))
-	return root
-}
-
 // GetJob returns a job by ID
 func (jm *JobManager) GetJob(jobID string) *Job {
 	jm.jobsMu.RLock()
@@ -326,7 +637,8 @@ func (jm *JobManager) ValidateShare(session *Session, jobID, nonce, result strin
 	jobData, ok := jm.jobs[jobID]
 	jm.jobsMu.RUnlock()
 
-	if !ok {
+	// Jobs are bound to the session they were issued to
+	if !ok || jobData.SessionID != session.ID {
 		return false, fmt.Errorf("job not found")
 	}
 
@@ -352,18 +664,16 @@ func (jm *JobManager) ValidateShare(session *Session, jobID, nonce, result strin
 		return false, fmt.Errorf("invalid nonce")
 	}
 
-	// Verify the hash
+	// Verify the hash over the exact header this session was sent, with
+	// its extranonce committed in the merkle root
 	header := make([]byte, len(jobData.HeaderBlob))
 	copy(header, jobData.HeaderBlob)
-	copy(header[76:80], nonceBytes) // Insert nonce
+	copy(header[NonceOffset:], nonceBytes) // Insert nonce
 
-	jm.rxMu.Lock()
-	if jm.rxCtx == nil {
-		jm.rxMu.Unlock()
-		return false, fmt.Errorf("RandomX not initialized")
+	computedHash, err := jm.hashShare(jobData.Template.SeedHash, header, resultHash)
+	if errors.Is(err, ErrUnknownSeed) {
+		return false, fmt.Errorf("stale share")
 	}
-	computedHash, err := jm.rxCtx.CalculateHash(header)
-	jm.rxMu.Unlock()
 	if err != nil {
 		return false, fmt.Errorf("hash calculation failed: %w", err)
 	}
@@ -378,25 +688,19 @@ func (jm *JobManager) ValidateShare(session *Session, jobID, nonce, result strin
 		return false, fmt.Errorf("low difficulty")
 	}
 
-	// Check if meets network difficulty (block found!)
-	jm.templateMu.RLock()
-	template := jm.template
-	jm.templateMu.RUnlock()
-
+	// Check if meets network difficulty (block found!) against the
+	// template this job's block would be built from
 	isBlock := false
-	if template != nil {
-		// Parse network target
-		networkTarget, _ := hex.DecodeString(template.Target)
-		if len(networkTarget) == 32 {
-			// Compare hash to network target
-			isBlock = true
-			for i := 31; i >= 0; i-- {
-				if computedHash[i] > networkTarget[31-i] {
-					isBlock = false
-					break
-				} else if computedHash[i] < networkTarget[31-i] {
-					break
-				}
+	networkTarget, _ := hex.DecodeString(jobData.Template.Target)
+	if len(networkTarget) == 32 {
+		// Compare hash to network target
+		isBlock = true
+		for i := 31; i >= 0; i-- {
+			if computedHash[i] > networkTarget[31-i] {
+				isBlock = false
+				break
+			} else if computedHash[i] < networkTarget[31-i] {
+				break
 			}
 		}
 	}
@@ -412,6 +716,31 @@ func (jm *JobManager) ValidateShare(session *Session, jobID, nonce, result strin
 	return isBlock, nil
 }
 
+// hashShare hashes a share header on the Verifier if one is configured,
+// falling back to the local SeedManager when it fails
+func (jm *JobManager) hashShare(seedHash string, header, claimed []byte) ([randomx.HashSize]byte, error) {
+	if jm.cfg.Verifier != nil {
+		seed, err := hex.DecodeString(seedHash)
+		if err == nil {
+			nonce := binary.LittleEndian.Uint32(header[NonceOffset:])
+			hash, err := jm.cfg.Verifier.VerifyShare(jm.ctx, seed, header, nonce, claimed)
+			if err == nil || errors.Is(err, ErrUnknownSeed) {
+				if jm.verifierDown.CompareAndSwap(true, false) {
+					jm.logger.Info("Remote share validation recovered")
+				}
+				return hash, err // ErrUnknownSeed is a stale share, not a validator failure
+			}
+			if jm.verifierDown.CompareAndSwap(false, true) {
+				jm.logger.Warn("Remote share validation failed, hashing locally", "error", err)
+			}
+		}
+		if !jm.localSeeds.Load() {
+			jm.enableLocalSeeds()
+		}
+	}
+	return jm.seeds.Hash(jm.ctx, seedHash, header)
+}
+
 func (jm *JobManager) cleanOldJobs() {
 	jm.jobsMu.Lock()
 	defer jm.jobsMu.Unlock()
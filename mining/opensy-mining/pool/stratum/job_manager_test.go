@@ -0,0 +1,108 @@
+package stratum
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+	"github.com/opensyria/opensy-mining/common/randomx/randomxtest"
+	"github.com/opensyria/opensy-mining/common/rpc"
+	"github.com/opensyria/opensy-mining/common/rpc/rpctest"
+)
+
+func TestStaleTemplateDropped(t *testing.T) {
+	jm, node, _ := newTestJobManager(t)
+
+	node.Mine(5)
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+	jm.templateMu.RLock()
+	old := jm.template
+	jm.templateMu.RUnlock()
+
+	node.Mine(1)
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+
+	// A long poll result for the previous block arriving late is ignored
+	jm.applyTemplate(old)
+	jm.templateMu.RLock()
+	height := jm.template.Height
+	jm.templateMu.RUnlock()
+	if height != 7 {
+		t.Errorf("template height %d after a stale template, want 7", height)
+	}
+}
+
+// failingVerifier is a ShareVerifier whose validators are unreachable
+type failingVerifier struct{}
+
+func (failingVerifier) SetSeeds(_, _ []byte) {}
+
+func (failingVerifier) VerifyShare(context.Context, []byte, []byte, uint32, []byte) ([randomx.HashSize]byte, error) {
+	return [randomx.HashSize]byte{}, errors.New("validators unreachable")
+}
+
+func TestVerifierLoadsSeedsLazily(t *testing.T) {
+	node := rpctest.NewServer(rpctest.DefaultConfig())
+	defer node.Close()
+
+	cfg := DefaultJobManagerConfig()
+	cfg.PoolAddress = testPoolAddress
+	cfg.Hashers = randomxtest.FakeHasherFactory{}
+	cfg.Verifier = failingVerifier{}
+	jm := NewJobManager(cfg, rpc.NewClient(node.URL(), "", ""))
+	defer jm.Stop()
+
+	node.Mine(3)
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+	if seed := jm.seeds.Current(); seed != "" {
+		t.Fatalf("frontend loaded seed %s before the verifier failed", seed)
+	}
+
+	// The first remote failure builds the local fallback for the template
+	session := &Session{ID: "session", Difficulty: 1}
+	job := jm.GetCurrentJob(session, 1000)
+	if _, err := jm.ValidateShare(session, job.JobID, "00000000", hex.EncodeToString(make([]byte, 32))); err == nil || err.Error() != "invalid hash" {
+		t.Errorf("share hashed locally: %v, want invalid hash", err)
+	}
+	if seed := jm.seeds.Current(); seed != job.SeedHash {
+		t.Errorf("local seed %q after fallback, want %s", seed, job.SeedHash)
+	}
+}
+
+func TestBlockCheckUsesJobTemplate(t *testing.T) {
+	jm, node, _ := newTestJobManager(t)
+
+	node.Mine(3)
+	if err := jm.RefreshTemplate(); err != nil {
+		t.Fatalf("RefreshTemplate: %v", err)
+	}
+	session := &Session{ID: "s1", Difficulty: 1}
+	job := jm.GetCurrentJob(session, 1000)
+
+	// The job's template takes any hash; the current one takes none
+	jm.jobs[job.JobID].Template.Target = strings.Repeat("ff", 32)
+	jm.templateMu.Lock()
+	current := *jm.template
+	current.Target = strings.Repeat("00", 32)
+	jm.template = &current
+	jm.templateMu.Unlock()
+
+	blob, _ := hex.DecodeString(job.Blob)
+	nonce := []byte{1, 0, 0, 0}
+	copy(blob[NonceOffset:], nonce)
+	seed, _ := hex.DecodeString(job.SeedHash)
+	hash := randomxtest.FakeHash(seed, blob, 0)
+	isBlock, err := jm.ValidateShare(session, job.JobID, hex.EncodeToString(nonce), hex.EncodeToString(hash[:]))
+	if err != nil || !isBlock {
+		t.Errorf("ValidateShare = %v, %v; want a block under the job's target", isBlock, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strings"
 )
 
 // JSON-RPC message types
@@ -40,13 +41,16 @@ type Error struct {
 
 // Standard error codes
 var (
-	ErrUnknown        = &Error{Code: -1, Message: "Unknown error"}
-	ErrInvalidRequest = &Error{Code: -2, Message: "Invalid request"}
-	ErrJobNotFound    = &Error{Code: -3, Message: "Job not found"}
-	ErrDuplicateShare = &Error{Code: -4, Message: "Duplicate share"}
-	ErrLowDifficulty  = &Error{Code: -5, Message: "Low difficulty share"}
-	ErrUnauthorized   = &Error{Code: -6, Message: "Unauthorized worker"}
-	ErrNotSubscribed  = &Error{Code: -7, Message: "Not subscribed"}
+	ErrUnknown         = &Error{Code: -1, Message: "Unknown error"}
+	ErrInvalidRequest  = &Error{Code: -2, Message: "Invalid request"}
+	ErrJobNotFound     = &Error{Code: -3, Message: "Job not found"}
+	ErrDuplicateShare  = &Error{Code: -4, Message: "Duplicate share"}
+	ErrLowDifficulty   = &Error{Code: -5, Message: "Low difficulty share"}
+	ErrUnauthorized    = &Error{Code: -6, Message: "Unauthorized worker"}
+	ErrNotSubscribed   = &Error{Code: -7, Message: "Not subscribed"}
+	ErrUnsupportedAlgo = &Error{Code: -10, Message: fmt.Sprintf(
+		"Unsupported algorithm: this pool mines %s (RandomX over a %d-byte header, nonce at offset %d)",
+		AlgoOpenSY, BlobSize, NonceOffset)}
 )
 
 // Stratum method names
@@ -59,19 +63,62 @@ const (
 	MethodJob = "job"
 )
 
+// OpenSY job blob layout
+//
+// OpenSY hashes an 80-byte Bitcoin-style block header with RandomX, not a
+// Monero hashing blob, so stock rx/0 miners write their nonce in the wrong
+// place. Jobs advertise AlgoOpenSY and miners must treat the blob as:
+//
+//	offset  size  field
+//	0       4     version (little-endian)
+//	4       32    previous block hash (internal byte order)
+//	36      32    merkle root (internal byte order)
+//	68      4     time (little-endian)
+//	72      4     bits (little-endian)
+//	76      4     nonce (little-endian) - the miner's 32-bit nonce space
+//
+// The RandomX key is the job's seed_hash. Submitted nonces are the 4 nonce
+// bytes as hex, in blob order.
+const (
+	// AlgoOpenSY is the algorithm id advertised in jobs and negotiated at
+	// login: RandomX over the 80-byte OpenSY header
+	AlgoOpenSY = "rx/opensy"
+	// BlobSize is the size of a job blob in bytes
+	BlobSize = 80
+	// NonceOffset is where miners write the 4-byte nonce in a job blob
+	NonceOffset = 76
+)
+
 // LoginParams represents login request parameters
 type LoginParams struct {
-	Login string `json:"login"` // Wallet address or username
-	Pass  string `json:"pass"`  // Password (often "x" or worker name)
-	Agent string `json:"agent"` // Mining software identifier
-	RigID string `json:"rigid"` // Optional rig identifier
+	Login    string             `json:"login"`     // Wallet address or username
+	Pass     string             `json:"pass"`      // Password (often "x" or worker name)
+	Agent    string             `json:"agent"`     // Mining software identifier
+	RigID    string             `json:"rigid"`     // Optional rig identifier
+	Algo     []string           `json:"algo"`      // Algorithms the miner supports (XMRig algo extension)
+	AlgoPerf map[string]float64 `json:"algo-perf"` // Miner's hashrate per algorithm
+}
+
+// SupportsAlgo reports whether the miner can mine algo. Miners that send no
+// algorithm list are assumed to be configured for this pool.
+func (p *LoginParams) SupportsAlgo(algo string) bool {
+	if len(p.Algo) == 0 {
+		return true
+	}
+	for _, a := range p.Algo {
+		if strings.EqualFold(a, algo) {
+			return true
+		}
+	}
+	return false
 }
 
 // LoginResult represents a successful login response
 type LoginResult struct {
-	ID     string `json:"id"`     // Session ID
-	Job    *Job   `json:"job"`    // First job to work on
-	Status string `json:"status"` // "OK"
+	ID         string   `json:"id"`                   // Session ID
+	Job        *Job     `json:"job"`                  // First job to work on
+	Status     string   `json:"status"`               // "OK"
+	Extensions []string `json:"extensions,omitempty"` // Protocol extensions the pool supports
 }
 
 // Job represents a mining job sent to miners
@@ -81,7 +128,7 @@ type Job struct {
 	Target   string `json:"target"`    // Mining target (hex, compact)
 	Height   int64  `json:"height"`    // Block height
 	SeedHash string `json:"seed_hash"` // RandomX seed hash
-	Algo     string `json:"algo"`      // Algorithm (rx/0 for RandomX)
+	Algo     string `json:"algo"`      // Algorithm id (AlgoOpenSY)
 }
 
 // SubmitParams represents share submission parameters
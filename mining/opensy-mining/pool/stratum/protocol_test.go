@@ -101,6 +101,30 @@ func TestParseLoginParams(t *testing.T) {
 	}
 }
 
+func TestLoginAlgoNegotiation(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want bool
+	}{
+		{"no algo list", `{"login":"sy1qtest"}`, true},
+		{"supports opensy", `{"login":"sy1qtest","algo":["rx/0","RX/OpenSY"],"algo-perf":{"rx/opensy":1200.5}}`, true},
+		{"monero only", `{"login":"sy1qtest","algo":["rx/0","rx/wow"]}`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			params, err := ParseLoginParams(json.RawMessage(tc.json))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := params.SupportsAlgo(AlgoOpenSY); got != tc.want {
+				t.Errorf("SupportsAlgo(%s) = %v, want %v", AlgoOpenSY, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestParseSubmitParams(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -139,7 +163,7 @@ func TestJobJSON(t *testing.T) {
 		Target:   "00000000",
 		Height:   12345,
 		SeedHash: "deadbeef",
-		Algo:     "rx/0",
+		Algo:     AlgoOpenSY,
 	}
 
 	data, err := json.Marshal(job)
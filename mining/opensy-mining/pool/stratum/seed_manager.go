@@ -0,0 +1,405 @@
+// Package stratum - seed_manager.go keeps RandomX contexts across seed changes
+package stratum
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+)
+
+// ErrUnknownSeed is returned for seeds that were never loaded or have left
+// the grace window
+var ErrUnknownSeed = errors.New("unknown or expired RandomX seed")
+
+// SeedManagerConfig holds seed manager configuration
+type SeedManagerConfig struct {
+	// GraceWindow is how long the previous seed's context is kept after a
+	// switch so shares for jobs issued before it still validate
+	GraceWindow time.Duration
+	// VMs bounds the RandomX VMs per seed, i.e. how many shares validate
+	// in parallel
+	VMs int
+	// FullDataset builds the ~2 GB RandomX dataset for each seed in the
+	// background and switches validation to it once ready. Light mode is
+	// used until then, and for good if the dataset cannot be allocated.
+	// Up to three seeds can be loaded at once (previous, current and
+	// pre-warmed next), so budget ~2 GB for each.
+	FullDataset    bool
+	DatasetThreads int // Dataset init threads (0 = all CPUs)
+	// SharedDatasetDir maps each seed's dataset from a file in this
+	// directory shared with workers on the same host (see
+	// randomx.OpenSharedDataset); empty allocates privately
+	SharedDatasetDir string
+	Flags            randomx.Flag
+	// Hashers builds the per-seed hashers; nil uses the RandomX library
+	Hashers randomx.HasherFactory
+	Logger  *slog.Logger
+}
+
+// DefaultSeedManagerConfig returns default configuration
+func DefaultSeedManagerConfig() SeedManagerConfig {
+	return SeedManagerConfig{
+		GraceWindow: 5 * time.Minute, // Matches job retention in cleanOldJobs
+		VMs:         runtime.NumCPU(),
+		Flags:       randomx.FlagDefault,
+		Logger:      slog.Default(),
+	}
+}
+
+// seedContext is a RandomX hasher for one seed, possibly still building
+type seedContext struct {
+	hasher   randomx.Hasher
+	full     bool // Validating against the full dataset
+	err      error
+	ready    chan struct{} // Closed once ctx or err is set
+	retireAt time.Time     // Zero while current or pre-warmed
+}
+
+// SeedManager owns the RandomX contexts used for share validation. OpenSY changes the key every randomx.KeyBlockInterval blocks;
+// the manager builds the next seed's context in the background before the
+// switch and keeps the previous one for a grace window so late shares on
+// old jobs still validate.
+type SeedManager struct {
+	cfg    SeedManagerConfig
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	current  string
+	contexts map[string]*seedContext
+
+	// OnVMWait is called with the time each validation waited for a VM
+	OnVMWait func(wait time.Duration)
+}
+
+// NewSeedManager creates a seed manager
+func NewSeedManager(cfg SeedManagerConfig) *SeedManager {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.Hashers == nil {
+		cfg.Hashers = randomx.DefaultHasherFactory()
+	}
+	return &SeedManager{
+		cfg:      cfg,
+		logger:   cfg.Logger.With("component", "seed-manager"),
+		contexts: make(map[string]*seedContext),
+	}
+}
+
+func (m *SeedManager) build(seed []byte, full bool) (randomx.Hasher, error) {
+	return m.cfg.Hashers.NewHasher(seed, randomx.HasherConfig{
+		VMs:              m.cfg.VMs,
+		FullDataset:      full,
+		DatasetThreads:   m.cfg.DatasetThreads,
+		SharedDatasetDir: m.cfg.SharedDatasetDir,
+		Flags:            m.cfg.Flags,
+		OnWait:           m.onVMWait,
+	})
+}
+
+// loadLocked returns the context entry for seedHash, starting a build if there
+// is none. m.mu must be held.
+func (m *SeedManager) loadLocked(seedHash string) (*seedContext, error) {
+	if sc, ok := m.contexts[seedHash]; ok {
+		select {
+		case <-sc.ready:
+			if sc.err == nil {
+				return sc, nil
+			}
+			// Failed build; try again
+		default:
+			return sc, nil
+		}
+	}
+	seed, err := hex.DecodeString(seedHash)
+	if err != nil || len(seed) == 0 {
+		return nil, fmt.Errorf("invalid seed hash %q", seedHash)
+	}
+
+	sc := &seedContext{ready: make(chan struct{})}
+	m.contexts[seedHash] = sc
+	go func() {
+		start := time.Now()
+		sc.hasher, sc.err = m.build(seed, false)
+		close(sc.ready)
+		if sc.err != nil {
+			m.logger.Error("Failed to build RandomX context", "seed", shortSeed(seedHash), "error", sc.err)
+			return
+		}
+		m.logReady(seedHash, sc.hasher, "light", start)
+
+		if m.cfg.FullDataset {
+			m.upgrade(seedHash, sc, seed)
+		}
+	}()
+	return sc, nil
+}
+
+// upgrade builds the full dataset for a seed already validating in light
+// mode and swaps it in. Shares in flight on the light hasher finish before
+// it is released.
+func (m *SeedManager) upgrade(seedHash string, sc *seedContext, seed []byte) {
+	start := time.Now()
+	hasher, err := m.build(seed, true)
+	if err != nil {
+		m.logger.Warn("Full dataset unavailable, validating in light mode",
+			"seed", shortSeed(seedHash),
+			"error", err,
+		)
+		return
+	}
+
+	m.mu.Lock()
+	if m.contexts[seedHash] != sc {
+		m.mu.Unlock()
+		hasher.Close() // Retired while the dataset was building
+		return
+	}
+	old := sc.hasher
+	sc.hasher, sc.full = hasher, true
+	m.mu.Unlock()
+
+	go old.Close()
+	m.logReady(seedHash, hasher, "full", start)
+}
+
+func (m *SeedManager) logReady(seedHash string, hasher randomx.Hasher, mode string, start time.Time) {
+	alloc := hasher.Allocation()
+	m.logger.Info("RandomX context ready",
+		"seed", shortSeed(seedHash),
+		"mode", mode,
+		"large_pages", alloc.LargePages,
+		"memory_mb", alloc.Bytes()>>20,
+		"shared_dataset", alloc.DatasetShared,
+		"duration", time.Since(start),
+	)
+	if alloc.LargePagesRequested && !alloc.LargePages {
+		m.logger.Warn("Large pages unavailable, using normal pages", "seed", shortSeed(seedHash))
+	}
+}
+
+// Prewarm builds the context for an upcoming seed (the template's
+// NextSeedHash) in the background
+func (m *SeedManager) Prewarm(seedHash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// A reorg can change the upcoming seed; drop stale pre-warmed contexts
+	now := time.Now()
+	for other, sc := range m.contexts {
+		if other != seedHash && other != m.current && sc.retireAt.IsZero() {
+			sc.retireAt = now
+		}
+	}
+	m.pruneLocked()
+
+	sc, err := m.loadLocked(seedHash)
+	if err == nil {
+		sc.retireAt = time.Time{}
+	}
+	return err
+}
+
+// SetCurrent switches to seedHash, waiting for its context if it was not
+// pre-warmed. The previous seed stays usable for the grace window.
+func (m *SeedManager) SetCurrent(seedHash string) error {
+	m.mu.Lock()
+	if seedHash == m.current {
+		m.mu.Unlock()
+		return nil
+	}
+	sc, err := m.loadLocked(seedHash)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	<-sc.ready
+	if sc.err != nil {
+		m.mu.Lock()
+		if m.contexts[seedHash] == sc {
+			delete(m.contexts, seedHash) // Retry on the next template
+		}
+		m.mu.Unlock()
+		return sc.err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if prev, ok := m.contexts[m.current]; ok && m.current != seedHash {
+		prev.retireAt = time.Now().Add(m.cfg.GraceWindow)
+	}
+	sc.retireAt = time.Time{}
+	m.current = seedHash
+	m.pruneLocked()
+	return nil
+}
+
+// Current returns the current seed hash
+func (m *SeedManager) Current() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current
+}
+
+// Get returns the ready hasher for seedHash. The hasher is owned by the
+// manager and is closed once the seed leaves the grace window; use Hash to
+// validate shares safely.
+func (m *SeedManager) Get(seedHash string) (randomx.Hasher, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sc, ok := m.contexts[seedHash]
+	if !ok || sc.expired(time.Now()) {
+		return nil, false
+	}
+	select {
+	case <-sc.ready:
+		return sc.hasher, sc.err == nil
+	default:
+		return nil, false // Still building
+	}
+}
+
+// Hash computes the RandomX hash of input under seedHash on a pooled VM,
+// waiting while every VM for the seed is busy
+func (m *SeedManager) Hash(ctx context.Context, seedHash string, input []byte) ([randomx.HashSize]byte, error) {
+	for {
+		hasher, ok := m.Get(seedHash)
+		if !ok {
+			return [randomx.HashSize]byte{}, ErrUnknownSeed
+		}
+		hash, err := hasher.Hash(ctx, input)
+		if errors.Is(err, randomx.ErrPoolClosed) {
+			continue // Upgraded to the dataset or retired while we waited
+		}
+		return hash, err
+	}
+}
+
+// Mode returns "full" or "light" for the current seed, or "" before the
+// first seed is loaded
+func (m *SeedManager) Mode() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sc, ok := m.contexts[m.current]
+	switch {
+	case !ok:
+		return ""
+	case sc.full:
+		return "full"
+	default:
+		return "light"
+	}
+}
+
+// Allocation reports the memory obtained for the current seed
+func (m *SeedManager) Allocation() randomx.AllocationInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sc, ok := m.contexts[m.current]
+	if !ok {
+		return randomx.AllocationInfo{}
+	}
+	select {
+	case <-sc.ready:
+	default:
+		return randomx.AllocationInfo{} // Still building
+	}
+	if sc.hasher == nil {
+		return randomx.AllocationInfo{}
+	}
+	return sc.hasher.Allocation()
+}
+
+// VMStats returns VM pool usage summed over every loaded seed
+func (m *SeedManager) VMStats() randomx.VMPoolStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var total randomx.VMPoolStats
+	for _, sc := range m.contexts {
+		select {
+		case <-sc.ready:
+		default:
+			continue
+		}
+		if sc.hasher == nil {
+			continue
+		}
+		st := sc.hasher.Stats()
+		total.Size += st.Size
+		total.Created += st.Created
+		total.InUse += st.InUse
+		total.Waits += st.Waits
+		total.WaitTime += st.WaitTime
+	}
+	return total
+}
+
+func (m *SeedManager) onVMWait(wait time.Duration) {
+	if m.OnVMWait != nil {
+		m.OnVMWait(wait)
+	}
+}
+
+// Close releases every context
+func (m *SeedManager) Close() {
+	m.mu.Lock()
+	contexts := m.contexts
+	m.contexts = make(map[string]*seedContext)
+	m.current = ""
+	m.mu.Unlock()
+
+	for _, sc := range contexts {
+		<-sc.ready
+		sc.release()
+	}
+}
+
+// pruneLocked releases contexts past their grace window; m.mu must be held.
+// Contexts are closed in the background once in-flight validations return
+// their VMs.
+func (m *SeedManager) pruneLocked() {
+	now := time.Now()
+	for seedHash, sc := range m.contexts {
+		if !sc.expired(now) {
+			continue
+		}
+		select {
+		case <-sc.ready:
+		default:
+			continue // Still building; released on a later prune
+		}
+		delete(m.contexts, seedHash)
+		go sc.release()
+		m.logger.Debug("Released RandomX context", "seed", shortSeed(seedHash))
+	}
+}
+
+// release closes the hasher, waiting for in-flight validations
+func (sc *seedContext) release() {
+	if sc.hasher != nil {
+		sc.hasher.Close()
+	}
+}
+
+func (sc *seedContext) expired(now time.Time) bool {
+	return !sc.retireAt.IsZero() && now.After(sc.retireAt)
+}
+
+func shortSeed(seedHash string) string {
+	if len(seedHash) > 16 {
+		return seedHash[:16] + "..."
+	}
+	return seedHash
+}
@@ -0,0 +1,125 @@
+package stratum
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+	"github.com/opensyria/opensy-mining/common/randomx/randomxtest"
+)
+
+// testHashers wraps the fake factory to count builds and fail dataset
+// allocation on demand
+type testHashers struct {
+	builds      atomic.Int32
+	failDataset atomic.Bool
+}
+
+type testHasher struct {
+	randomx.Hasher
+	full bool
+}
+
+func (f *testHashers) NewHasher(seed []byte, cfg randomx.HasherConfig) (randomx.Hasher, error) {
+	if cfg.FullDataset && f.failDataset.Load() {
+		return nil, randomx.ErrDatasetAllocation
+	}
+	if !cfg.FullDataset {
+		f.builds.Add(1)
+	}
+	h, err := randomxtest.FakeHasherFactory{}.NewHasher(seed, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &testHasher{Hasher: h, full: cfg.FullDataset}, nil
+}
+
+func TestSeedManagerPrewarmAndGrace(t *testing.T) {
+	cfg := DefaultSeedManagerConfig()
+	cfg.GraceWindow = 50 * time.Millisecond
+	hashers := &testHashers{}
+	cfg.Hashers = hashers
+	m := NewSeedManager(cfg)
+	defer m.Close()
+
+	seedA := strings.Repeat("aa", 32)
+	seedB := strings.Repeat("bb", 32)
+
+	if err := m.SetCurrent(seedA); err != nil {
+		t.Fatalf("SetCurrent(A): %v", err)
+	}
+	if err := m.Prewarm(seedB); err != nil {
+		t.Fatalf("Prewarm(B): %v", err)
+	}
+	if err := m.SetCurrent(seedB); err != nil {
+		t.Fatalf("SetCurrent(B): %v", err)
+	}
+	if n := hashers.builds.Load(); n != 2 {
+		t.Errorf("built %d contexts, want 2 (pre-warmed B reused)", n)
+	}
+
+	// Late shares on the previous seed still validate during the grace window
+	if _, err := m.Hash(context.Background(), seedA, make([]byte, 80)); err != nil {
+		t.Errorf("previous seed within grace window: %v", err)
+	}
+
+	time.Sleep(2 * cfg.GraceWindow)
+	if err := m.Prewarm(strings.Repeat("cc", 32)); err != nil { // Triggers pruning
+		t.Fatalf("Prewarm(C): %v", err)
+	}
+	if _, ok := m.Get(seedA); ok {
+		t.Error("previous seed still available after grace window")
+	}
+	if _, err := m.Hash(context.Background(), seedA, make([]byte, 80)); !errors.Is(err, ErrUnknownSeed) {
+		t.Errorf("expired seed: got %v, want ErrUnknownSeed", err)
+	}
+	if _, ok := m.Get(seedB); !ok {
+		t.Error("current seed not available")
+	}
+}
+
+func TestSeedManagerFullDataset(t *testing.T) {
+	cfg := DefaultSeedManagerConfig()
+	cfg.FullDataset = true
+	cfg.DatasetThreads = 1
+	hashers := &testHashers{}
+	cfg.Hashers = hashers
+	m := NewSeedManager(cfg)
+	defer m.Close()
+
+	seed := strings.Repeat("aa", 32)
+	if err := m.SetCurrent(seed); err != nil {
+		t.Fatalf("SetCurrent: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Mode() != "full" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if mode := m.Mode(); mode != "full" {
+		t.Fatalf("mode %q, want full", mode)
+	}
+	if h, ok := m.Get(seed); !ok || !h.(*testHasher).full {
+		t.Error("current hasher has no dataset")
+	}
+	if _, err := m.Hash(context.Background(), seed, make([]byte, 80)); err != nil {
+		t.Errorf("Hash after upgrade: %v", err)
+	}
+
+	// Allocation failure leaves the seed validating in light mode
+	hashers.failDataset.Store(true)
+	next := strings.Repeat("bb", 32)
+	if err := m.SetCurrent(next); err != nil {
+		t.Fatalf("SetCurrent(next): %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if mode := m.Mode(); mode != "light" {
+		t.Errorf("mode %q after failed allocation, want light", mode)
+	}
+	if _, err := m.Hash(context.Background(), next, make([]byte, 80)); err != nil {
+		t.Errorf("Hash in light mode: %v", err)
+	}
+}
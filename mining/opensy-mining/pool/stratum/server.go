@@ -25,7 +25,10 @@ type ServerConfig struct {
 	VardiffRetarget   time.Duration
 	ReadTimeout       time.Duration
 	WriteTimeout      time.Duration
-	Logger            *slog.Logger
+	// NiceHash advertises the nicehash extension at login, letting proxies
+	// split the top nonce byte of each job among their miners
+	NiceHash bool
+	Logger   *slog.Logger
 }
 
 // DefaultServerConfig returns default configuration
@@ -57,11 +60,15 @@ type Server struct {
 	// Job management
 	jobManager *JobManager
 
+	// Set while the node cannot produce usable work; see Pause
+	pauseReason string
+	pauseMu     sync.RWMutex
+
 	// Callbacks for external integration
 	OnMinerConnect    func(s *Session)
 	OnMinerDisconnect func(s *Session)
 	OnShareSubmit     func(s *Session, jobID, nonce, result string, isBlock bool) error
-	OnBlockFound      func(s *Session, height int64, hash string)
+	OnBlockFound      func(s *Session, block *FoundBlock)
 
 	// Control
 	ctx    context.Context
@@ -231,32 +238,77 @@ func (s *Server) handleLogin(session *Session, login, pass, agent, rigID string)
 }
 
 func (s *Server) handleSubmit(session *Session, jobID, nonce, result string) error {
-	// Delegate to job manager for validation
+	// Delegate to job manager for validation. This runs while paused too:
+	// the jobs were valid when sent, and a pause may be a passing node-check
+	// blip that should not throw away a found block.
 	isBlock, err := s.jobManager.ValidateShare(session, jobID, nonce, result)
 	if err != nil {
 		return err
 	}
 
+	// Submit a found block before anything else delays it
+	var found *FoundBlock
+	if isBlock {
+		found, err = s.jobManager.SubmitBlock(jobID, nonce, result)
+		if err != nil {
+			session.logger.Error("Failed to assemble found block", "job", jobID, "error", err)
+		}
+	}
+
 	// Call external handler
+	var shareErr error
 	if s.OnShareSubmit != nil {
-		if err := s.OnShareSubmit(session, jobID, nonce, result, isBlock); err != nil {
-			return err
-		}
+		shareErr = s.OnShareSubmit(session, jobID, nonce, result, isBlock)
 	}
 
-	// Handle block found
-	if isBlock {
-		job := s.jobManager.GetJob(jobID)
-		if job != nil && s.OnBlockFound != nil {
-			s.OnBlockFound(session, job.Height, result)
-		}
+	// The block already went to the node, so record it even if the share
+	// could not be
+	if found != nil && s.OnBlockFound != nil {
+		s.OnBlockFound(session, found)
 	}
 
-	return nil
+	return shareErr
+}
+
+// Pause stops handing out work, e.g. while the node is syncing or has no
+// peers. Logins are rejected with reason and no new jobs are sent until
+// Resume is called; shares on jobs already sent are still validated and
+// found blocks submitted.
+func (s *Server) Pause(reason string) {
+	s.pauseMu.Lock()
+	defer s.pauseMu.Unlock()
+	if s.pauseReason != reason {
+		s.logger.Warn("Pausing job distribution", "reason", reason)
+	}
+	s.pauseReason = reason
+}
+
+// Resume resumes job distribution and sends fresh work to every miner
+func (s *Server) Resume() {
+	s.pauseMu.Lock()
+	wasPaused := s.pauseReason != ""
+	s.pauseReason = ""
+	s.pauseMu.Unlock()
+
+	if wasPaused {
+		s.logger.Info("Resuming job distribution")
+		s.BroadcastJob()
+	}
+}
+
+// PauseReason returns why job distribution is paused, or "" if it is not
+func (s *Server) PauseReason() string {
+	s.pauseMu.RLock()
+	defer s.pauseMu.RUnlock()
+	return s.pauseReason
 }
 
 // BroadcastJob sends a new job to all connected miners
 func (s *Server) BroadcastJob() {
+	if s.PauseReason() != "" {
+		return
+	}
+
 	s.sessionsMu.RLock()
 	defer s.sessionsMu.RUnlock()
 
@@ -265,7 +317,7 @@ func (s *Server) BroadcastJob() {
 			continue
 		}
 
-		job := s.GetCurrentJob(session.Difficulty)
+		job := s.GetCurrentJob(session, session.Difficulty)
 		if job != nil {
 			if err := session.SendJob(job); err != nil {
 				session.logger.Error("Failed to send job", "error", err)
@@ -274,9 +326,13 @@ func (s *Server) BroadcastJob() {
 	}
 }
 
-// GetCurrentJob returns the current job with the specified difficulty
-func (s *Server) GetCurrentJob(difficulty uint64) *Job {
-	return s.jobManager.GetCurrentJob(difficulty)
+// GetCurrentJob returns a new job for a session with the specified
+// difficulty, or nil while job distribution is paused
+func (s *Server) GetCurrentJob(session *Session, difficulty uint64) *Job {
+	if s.PauseReason() != "" {
+		return nil
+	}
+	return s.jobManager.GetCurrentJob(session, difficulty)
 }
 
 // GetSession returns a session by ID
@@ -396,7 +452,7 @@ func (s *Server) adjustSessionDifficulty(session *Session) {
 		)
 
 		// Send new job with updated difficulty
-		job := s.GetCurrentJob(newDiff)
+		job := s.GetCurrentJob(session, newDiff)
 		if job != nil {
 			session.SendJob(job)
 		}
@@ -42,6 +42,10 @@ type Session struct {
 	CurrentJob  *Job
 	LastJobTime time.Time
 
+	// NiceHash sessions leave the top nonce byte to the miner, which is
+	// usually a proxy splitting it among its own miners
+	NiceHash bool
+
 	// Stats
 	SharesValid   atomic.Uint64
 	SharesInvalid atomic.Uint64
@@ -65,7 +69,7 @@ type Session struct {
 
 // NewSession creates a new miner session
 func NewSession(id string, conn net.Conn, server *Server) *Session {
-	return &Session{
+	s := &Session{
 		ID:               id,
 		Conn:             conn,
 		RemoteAddr:       conn.RemoteAddr().String(),
@@ -77,6 +81,8 @@ func NewSession(id string, conn net.Conn, server *Server) *Session {
 		logger:           server.logger.With("session", id, "addr", conn.RemoteAddr()),
 		server:           server,
 	}
+	s.NiceHash = server.cfg.NiceHash
+	return s
 }
 
 // Send sends a JSON-RPC response or notification to the miner
@@ -161,6 +167,13 @@ func (s *Session) handleLogin(req *Request) error {
 		return s.SendResponse(req.ID, nil, ErrInvalidRequest)
 	}
 
+	// Jobs are only valid for miners that hash the OpenSY blob layout; a
+	// plain rx/0 miner would write its nonce at the Monero offset
+	if !params.SupportsAlgo(AlgoOpenSY) {
+		s.logger.Warn("Miner does not support "+AlgoOpenSY, "agent", params.Agent, "algos", params.Algo)
+		return s.SendResponse(req.ID, nil, ErrUnsupportedAlgo)
+	}
+
 	// Store miner info
 	s.mu.Lock()
 	s.Login = params.Login
@@ -187,6 +200,12 @@ func (s *Session) handleLogin(req *Request) error {
 		}
 	}
 
+	// Refuse the login while paused so the miner fails over to a backup
+	// pool and shows the reason
+	if reason := s.server.PauseReason(); reason != "" {
+		return s.SendResponse(req.ID, nil, &Error{Code: -9, Message: "Pool paused: " + reason})
+	}
+
 	s.mu.Lock()
 	s.State = StateAuthorized
 	s.mu.Unlock()
@@ -195,10 +214,11 @@ func (s *Session) handleLogin(req *Request) error {
 		"login", s.Login,
 		"worker", s.WorkerName,
 		"agent", s.Agent,
+		"hashrate", params.AlgoPerf[AlgoOpenSY],
 	)
 
 	// Get initial job
-	job := s.server.GetCurrentJob(s.Difficulty)
+	job := s.server.GetCurrentJob(s, s.Difficulty)
 	if job == nil {
 		return s.SendResponse(req.ID, nil, &Error{Code: -8, Message: "No job available"})
 	}
@@ -208,10 +228,15 @@ func (s *Session) handleLogin(req *Request) error {
 	s.LastJobTime = time.Now()
 	s.mu.Unlock()
 
+	extensions := []string{"algo"}
+	if s.NiceHash {
+		extensions = append(extensions, "nicehash")
+	}
 	return s.SendResponse(req.ID, &LoginResult{
-		ID:     s.ID,
-		Job:    job,
-		Status: "OK",
+		ID:         s.ID,
+		Job:        job,
+		Status:     "OK",
+		Extensions: extensions,
 	}, nil)
 }
 
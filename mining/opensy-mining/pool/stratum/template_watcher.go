@@ -0,0 +1,85 @@
+// Package stratum - template_watcher.go follows block templates via long polling
+package stratum
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+)
+
+// TemplateWatcher keeps a getblocktemplate long poll open against the node
+// and hands every new template to OnTemplate as soon as the node returns it.
+// Nodes that do not return a longpollid are polled at PollInterval instead.
+type TemplateWatcher struct {
+	rpc          *rpc.Client
+	logger       *slog.Logger
+	pollInterval time.Duration
+	retryDelay   time.Duration
+
+	// OnTemplate is called for every template the node returns
+	OnTemplate func(template *rpc.BlockTemplate)
+}
+
+// NewTemplateWatcher creates a template watcher
+func NewTemplateWatcher(rpcClient *rpc.Client, pollInterval time.Duration, logger *slog.Logger) *TemplateWatcher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &TemplateWatcher{
+		rpc:          rpcClient,
+		logger:       logger.With("component", "template-watcher"),
+		pollInterval: pollInterval,
+		retryDelay:   time.Second,
+	}
+}
+
+// Run long polls until ctx is cancelled, starting from longPollID
+func (w *TemplateWatcher) Run(ctx context.Context, longPollID string) {
+	for {
+		template, err := w.rpc.GetBlockTemplateLongPoll(ctx, longPollID)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, rpc.ErrLongPollTimeout) {
+			// Template unchanged, poll again with the same id
+			continue
+		}
+		if err != nil {
+			w.logger.Warn("Long poll failed", "error", err)
+			if !sleepCtx(ctx, w.retryDelay) {
+				return
+			}
+			continue
+		}
+
+		if w.OnTemplate != nil {
+			w.OnTemplate(template)
+		}
+
+		if template.LongPollID == "" {
+			// Node does not support long polling, fall back to interval polling
+			longPollID = ""
+			if !sleepCtx(ctx, w.pollInterval) {
+				return
+			}
+			continue
+		}
+		longPollID = template.LongPollID
+	}
+}
+
+// sleepCtx sleeps for d, returning false if ctx is cancelled first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
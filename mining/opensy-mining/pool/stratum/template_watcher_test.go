@@ -0,0 +1,65 @@
+package stratum
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/rpc"
+	"github.com/opensyria/opensy-mining/common/rpc/rpctest"
+)
+
+func TestTemplateWatcherRun(t *testing.T) {
+	node := rpctest.NewServer(rpctest.DefaultConfig())
+	t.Cleanup(node.Close)
+	cfg := rpc.DefaultClientConfig(node.URL(), "", "")
+	cfg.RetryAttempts = 0
+	cfg.LongPollTimeout = 50 * time.Millisecond
+	client := rpc.NewClientWithConfig(cfg)
+
+	heights := make(chan int64, 10)
+	watcher := NewTemplateWatcher(client, time.Hour, nil)
+	watcher.retryDelay = 10 * time.Millisecond
+	watcher.OnTemplate = func(template *rpc.BlockTemplate) {
+		heights <- template.Height
+	}
+
+	// The first poll fails and is retried
+	node.SetError("getblocktemplate", rpc.CodeInternalError, "boom", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watcher.Run(ctx, "")
+		close(done)
+	}()
+
+	expectHeight := func(want int64) {
+		t.Helper()
+		select {
+		case got := <-heights:
+			if got != want {
+				t.Fatalf("template height %d, want %d", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("no template at height %d", want)
+		}
+	}
+	expectHeight(1)
+
+	// Several long polls time out while the tip is unchanged
+	select {
+	case got := <-heights:
+		t.Fatalf("unchanged template delivered at height %d", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	node.Mine(1)
+	expectHeight(2)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+}
@@ -0,0 +1,48 @@
+// Package validator - auth.go authenticates pool frontends with a shared token
+package validator
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrNoToken is returned when a server or client is configured without the
+// shared token. Validators load whatever seeds a caller declares, so they
+// never serve unauthenticated callers.
+var ErrNoToken = errors.New("validator: no token configured")
+
+// tokenCredentials sends the shared token as a bearer token on every call
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+// authInterceptor rejects calls that do not carry the shared token
+func authInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var auth string
+		if values := md.Get("authorization"); len(values) > 0 {
+			auth = strings.TrimPrefix(values[0], "Bearer ")
+		}
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid validator token")
+		}
+		return handler(ctx, req)
+	}
+}
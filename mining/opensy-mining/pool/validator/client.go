@@ -0,0 +1,352 @@
+// Package validator - client.go batches shares from a Stratum frontend and
+// spreads them across validator servers
+package validator
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+	"github.com/opensyria/opensy-mining/pool/stratum"
+	pb "github.com/opensyria/opensy-mining/pool/validator/proto/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+var (
+	// ErrNoValidators is returned when every validator failed or is backing
+	// off; the JobManager then hashes the share locally
+	ErrNoValidators = errors.New("validator: no validator available")
+	// ErrClosed is returned for shares submitted after Close
+	ErrClosed = errors.New("validator: client closed")
+)
+
+// The Client is what JobManagerConfig.Verifier expects
+var _ stratum.ShareVerifier = (*Client)(nil)
+
+// ClientConfig holds validator client configuration
+type ClientConfig struct {
+	Addrs        []string      // Validator addresses
+	MaxBatch     int           // Shares per Validate call
+	BatchDelay   time.Duration // How long to wait for more shares before sending a batch
+	Timeout      time.Duration // Per Validate call
+	RetryBackoff time.Duration // How long a failing validator is skipped
+	Token        string        // Shared with the validators; required
+	UseTLS       bool
+	TLSInsecure  bool // Skip certificate verification (testing only)
+	Logger       *slog.Logger
+}
+
+// DefaultClientConfig returns default configuration
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		MaxBatch:     64,
+		BatchDelay:   2 * time.Millisecond,
+		Timeout:      5 * time.Second,
+		RetryBackoff: 5 * time.Second,
+		Logger:       slog.Default(),
+	}
+}
+
+// Client sends shares to validator servers in batches. Each batch goes to
+// the healthy validator with the fewest batches in flight; a validator that
+// fails is skipped for RetryBackoff and the batch is retried on the next.
+type Client struct {
+	cfg      ClientConfig
+	logger   *slog.Logger
+	backends []*backend
+	queue    chan *request
+	next     atomic.Uint32 // Round-robin start for ties
+
+	// Seeds declared to the validators with every batch
+	currentSeed []byte
+	nextSeed    []byte
+	seedsMu     sync.RWMutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+type backend struct {
+	addr      string
+	conn      *grpc.ClientConn
+	client    pb.ValidatorClient
+	inflight  atomic.Int32
+	downUntil atomic.Int64 // Unix nanoseconds; skipped until then
+}
+
+type request struct {
+	share *pb.Share
+	done  chan response
+}
+
+type response struct {
+	hash [randomx.HashSize]byte
+	err  error
+}
+
+// NewClient creates a client for the validators in cfg.Addrs. Connections
+// are made lazily, so unreachable validators do not fail construction.
+func NewClient(cfg ClientConfig) (*Client, error) {
+	defaults := DefaultClientConfig()
+	if len(cfg.Addrs) == 0 {
+		return nil, errors.New("validator: no addresses configured")
+	}
+	if cfg.Token == "" {
+		return nil, ErrNoToken
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = defaults.MaxBatch
+	}
+	if cfg.BatchDelay <= 0 {
+		cfg.BatchDelay = defaults.BatchDelay
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaults.Timeout
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaults.RetryBackoff
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                30 * time.Second,
+			Timeout:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithPerRPCCredentials(tokenCredentials{token: cfg.Token, secure: cfg.UseTLS}),
+	}
+	if cfg.UseTLS {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecure}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		cfg:    cfg,
+		logger: cfg.Logger.With("component", "validator-client"),
+		queue:  make(chan *request, cfg.MaxBatch),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, addr := range cfg.Addrs {
+		conn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("validator %s: %w", addr, err)
+		}
+		c.backends = append(c.backends, &backend{
+			addr:   addr,
+			conn:   conn,
+			client: pb.NewValidatorClient(conn),
+		})
+	}
+
+	c.wg.Add(1)
+	go c.batchLoop()
+	return c, nil
+}
+
+// VerifyShare hashes blob with nonce applied on a validator. The claimed
+// result is forwarded for the validator's statistics; callers still compare
+// the returned hash themselves.
+func (c *Client) VerifyShare(ctx context.Context, seed, blob []byte, nonce uint32, claimed []byte) ([randomx.HashSize]byte, error) {
+	req := &request{
+		share: &pb.Share{Seed: seed, Blob: blob, Nonce: nonce, ClaimedResult: claimed},
+		done:  make(chan response, 1),
+	}
+	select {
+	case c.queue <- req:
+	case <-ctx.Done():
+		return [randomx.HashSize]byte{}, ctx.Err()
+	case <-c.ctx.Done():
+		return [randomx.HashSize]byte{}, ErrClosed
+	}
+
+	select {
+	case resp := <-req.done:
+		return resp.hash, resp.err
+	case <-ctx.Done():
+		return [randomx.HashSize]byte{}, ctx.Err()
+	case <-c.ctx.Done():
+		return [randomx.HashSize]byte{}, ErrClosed
+	}
+}
+
+// SetSeeds sets the seeds sent with every batch: the current template's
+// seed, which validators load and make current, and the next one to
+// pre-warm. Validators refuse shares for any other seed.
+func (c *Client) SetSeeds(current, next []byte) {
+	c.seedsMu.Lock()
+	defer c.seedsMu.Unlock()
+	c.currentSeed = append([]byte(nil), current...)
+	c.nextSeed = append([]byte(nil), next...)
+}
+
+// Close fails queued shares with ErrClosed and closes the connections
+func (c *Client) Close() {
+	c.cancel()
+	c.wg.Wait()
+	// Fail shares still queued when batchLoop stopped
+drain:
+	for {
+		select {
+		case req := <-c.queue:
+			req.done <- response{err: ErrClosed}
+		default:
+			break drain
+		}
+	}
+	for _, b := range c.backends {
+		b.conn.Close()
+	}
+}
+
+// batchLoop collects shares until MaxBatch or BatchDelay after the first,
+// then sends the batch without waiting for the reply
+func (c *Client) batchLoop() {
+	defer c.wg.Done()
+
+	for {
+		var batch []*request
+		select {
+		case req := <-c.queue:
+			batch = append(batch, req)
+		case <-c.ctx.Done():
+			return
+		}
+
+		timer := time.NewTimer(c.cfg.BatchDelay)
+	fill:
+		for len(batch) < c.cfg.MaxBatch {
+			select {
+			case req := <-c.queue:
+				batch = append(batch, req)
+			case <-timer.C:
+				break fill
+			case <-c.ctx.Done():
+				timer.Stop()
+				fail(batch, ErrClosed)
+				return
+			}
+		}
+		timer.Stop()
+
+		c.wg.Add(1)
+		go c.send(batch)
+	}
+}
+
+// send tries the batch on each usable validator in turn
+func (c *Client) send(batch []*request) {
+	defer c.wg.Done()
+
+	c.seedsMu.RLock()
+	req := &pb.ValidateRequest{
+		Shares:      make([]*pb.Share, len(batch)),
+		CurrentSeed: c.currentSeed,
+		NextSeed:    c.nextSeed,
+	}
+	c.seedsMu.RUnlock()
+	for i, r := range batch {
+		req.Shares[i] = r.share
+	}
+
+	tried := make(map[*backend]bool, len(c.backends))
+	var lastErr error
+	for b := c.pick(tried); b != nil; b = c.pick(tried) {
+		tried[b] = true
+		results, err := c.call(b, req)
+		if err != nil {
+			lastErr = err
+			b.downUntil.Store(time.Now().Add(c.cfg.RetryBackoff).UnixNano())
+			c.logger.Warn("Validator failed, backing off",
+				"addr", b.addr,
+				"shares", len(batch),
+				"backoff", c.cfg.RetryBackoff,
+				"error", err,
+			)
+			continue
+		}
+
+		for i, r := range batch {
+			r.done <- toResponse(results[i])
+		}
+		return
+	}
+
+	err := ErrNoValidators
+	if lastErr != nil {
+		err = fmt.Errorf("%w: %v", ErrNoValidators, lastErr)
+	}
+	fail(batch, err)
+}
+
+func (c *Client) call(b *backend, req *pb.ValidateRequest) ([]*pb.ShareResult, error) {
+	b.inflight.Add(1)
+	defer b.inflight.Add(-1)
+
+	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.Timeout)
+	defer cancel()
+	resp, err := b.client.Validate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Results) != len(req.Shares) {
+		return nil, fmt.Errorf("got %d results for %d shares", len(resp.Results), len(req.Shares))
+	}
+	return resp.Results, nil
+}
+
+// pick returns the untried, healthy validator with the fewest batches in
+// flight, or nil if there is none
+func (c *Client) pick(tried map[*backend]bool) *backend {
+	now := time.Now().UnixNano()
+	start := int(c.next.Add(1))
+	var best *backend
+	for i := range c.backends {
+		b := c.backends[(start+i)%len(c.backends)]
+		if tried[b] || b.downUntil.Load() > now {
+			continue
+		}
+		if best == nil || b.inflight.Load() < best.inflight.Load() {
+			best = b
+		}
+	}
+	return best
+}
+
+func toResponse(result *pb.ShareResult) response {
+	var resp response
+	switch {
+	case result.Error == stratum.ErrUnknownSeed.Error():
+		resp.err = stratum.ErrUnknownSeed
+	case result.Error != "":
+		resp.err = errors.New(result.Error)
+	case len(result.Hash) != randomx.HashSize:
+		resp.err = fmt.Errorf("validator returned %d-byte hash", len(result.Hash))
+	default:
+		copy(resp.hash[:], result.Hash)
+	}
+	return resp
+}
+
+func fail(batch []*request, err error) {
+	for _, r := range batch {
+		r.done <- response{err: err}
+	}
+}
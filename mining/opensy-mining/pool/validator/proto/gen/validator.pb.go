@@ -0,0 +1,332 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.1
+// source: validator.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Share struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seed          []byte                 `protobuf:"bytes,1,opt,name=seed,proto3" json:"seed,omitempty"`                                        // RandomX key (seed hash)
+	Blob          []byte                 `protobuf:"bytes,2,opt,name=blob,proto3" json:"blob,omitempty"`                                        // Hashing blob (80-byte block header)
+	Nonce         uint32                 `protobuf:"varint,3,opt,name=nonce,proto3" json:"nonce,omitempty"`                                     // Written little-endian at blob[76:80]
+	ClaimedResult []byte                 `protobuf:"bytes,4,opt,name=claimed_result,json=claimedResult,proto3" json:"claimed_result,omitempty"` // Hash the miner reported; empty to only hash
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Share) Reset() {
+	*x = Share{}
+	mi := &file_validator_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Share) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Share) ProtoMessage() {}
+
+func (x *Share) ProtoReflect() protoreflect.Message {
+	mi := &file_validator_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Share.ProtoReflect.Descriptor instead.
+func (*Share) Descriptor() ([]byte, []int) {
+	return file_validator_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Share) GetSeed() []byte {
+	if x != nil {
+		return x.Seed
+	}
+	return nil
+}
+
+func (x *Share) GetBlob() []byte {
+	if x != nil {
+		return x.Blob
+	}
+	return nil
+}
+
+func (x *Share) GetNonce() uint32 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *Share) GetClaimedResult() []byte {
+	if x != nil {
+		return x.ClaimedResult
+	}
+	return nil
+}
+
+type ValidateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Shares        []*Share               `protobuf:"bytes,1,rep,name=shares,proto3" json:"shares,omitempty"`
+	CurrentSeed   []byte                 `protobuf:"bytes,2,opt,name=current_seed,json=currentSeed,proto3" json:"current_seed,omitempty"` // Seed of the pool's current template
+	NextSeed      []byte                 `protobuf:"bytes,3,opt,name=next_seed,json=nextSeed,proto3" json:"next_seed,omitempty"`          // Upcoming seed to pre-warm, if known
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateRequest) Reset() {
+	*x = ValidateRequest{}
+	mi := &file_validator_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateRequest) ProtoMessage() {}
+
+func (x *ValidateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_validator_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateRequest.ProtoReflect.Descriptor instead.
+func (*ValidateRequest) Descriptor() ([]byte, []int) {
+	return file_validator_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ValidateRequest) GetShares() []*Share {
+	if x != nil {
+		return x.Shares
+	}
+	return nil
+}
+
+func (x *ValidateRequest) GetCurrentSeed() []byte {
+	if x != nil {
+		return x.CurrentSeed
+	}
+	return nil
+}
+
+func (x *ValidateRequest) GetNextSeed() []byte {
+	if x != nil {
+		return x.NextSeed
+	}
+	return nil
+}
+
+type ShareResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Hash          []byte                 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`    // Computed RandomX hash
+	Valid         bool                   `protobuf:"varint,2,opt,name=valid,proto3" json:"valid,omitempty"` // hash equals claimed_result
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`  // Set if the share could not be hashed
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShareResult) Reset() {
+	*x = ShareResult{}
+	mi := &file_validator_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShareResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShareResult) ProtoMessage() {}
+
+func (x *ShareResult) ProtoReflect() protoreflect.Message {
+	mi := &file_validator_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShareResult.ProtoReflect.Descriptor instead.
+func (*ShareResult) Descriptor() ([]byte, []int) {
+	return file_validator_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ShareResult) GetHash() []byte {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *ShareResult) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *ShareResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type ValidateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*ShareResult         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateResponse) Reset() {
+	*x = ValidateResponse{}
+	mi := &file_validator_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateResponse) ProtoMessage() {}
+
+func (x *ValidateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_validator_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateResponse.ProtoReflect.Descriptor instead.
+func (*ValidateResponse) Descriptor() ([]byte, []int) {
+	return file_validator_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ValidateResponse) GetResults() []*ShareResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_validator_proto protoreflect.FileDescriptor
+
+const file_validator_proto_rawDesc = "" +
+	"\n" +
+	"\x0fvalidator.proto\x12\tvalidator\"l\n" +
+	"\x05Share\x12\x12\n" +
+	"\x04seed\x18\x01 \x01(\fR\x04seed\x12\x12\n" +
+	"\x04blob\x18\x02 \x01(\fR\x04blob\x12\x14\n" +
+	"\x05nonce\x18\x03 \x01(\rR\x05nonce\x12%\n" +
+	"\x0eclaimed_result\x18\x04 \x01(\fR\rclaimedResult\"{\n" +
+	"\x0fValidateRequest\x12(\n" +
+	"\x06shares\x18\x01 \x03(\v2\x10.validator.ShareR\x06shares\x12!\n" +
+	"\fcurrent_seed\x18\x02 \x01(\fR\vcurrentSeed\x12\x1b\n" +
+	"\tnext_seed\x18\x03 \x01(\fR\bnextSeed\"M\n" +
+	"\vShareResult\x12\x12\n" +
+	"\x04hash\x18\x01 \x01(\fR\x04hash\x12\x14\n" +
+	"\x05valid\x18\x02 \x01(\bR\x05valid\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\"D\n" +
+	"\x10ValidateResponse\x120\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.validator.ShareResultR\aresults2P\n" +
+	"\tValidator\x12C\n" +
+	"\bValidate\x12\x1a.validator.ValidateRequest\x1a\x1b.validator.ValidateResponseB9Z7github.com/opensyria/opensy-mining/pool/validator/protob\x06proto3"
+
+var (
+	file_validator_proto_rawDescOnce sync.Once
+	file_validator_proto_rawDescData []byte
+)
+
+func file_validator_proto_rawDescGZIP() []byte {
+	file_validator_proto_rawDescOnce.Do(func() {
+		file_validator_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_validator_proto_rawDesc), len(file_validator_proto_rawDesc)))
+	})
+	return file_validator_proto_rawDescData
+}
+
+var file_validator_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_validator_proto_goTypes = []any{
+	(*Share)(nil),            // 0: validator.Share
+	(*ValidateRequest)(nil),  // 1: validator.ValidateRequest
+	(*ShareResult)(nil),      // 2: validator.ShareResult
+	(*ValidateResponse)(nil), // 3: validator.ValidateResponse
+}
+var file_validator_proto_depIdxs = []int32{
+	0, // 0: validator.ValidateRequest.shares:type_name -> validator.Share
+	2, // 1: validator.ValidateResponse.results:type_name -> validator.ShareResult
+	1, // 2: validator.Validator.Validate:input_type -> validator.ValidateRequest
+	3, // 3: validator.Validator.Validate:output_type -> validator.ValidateResponse
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_validator_proto_init() }
+func file_validator_proto_init() {
+	if File_validator_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_validator_proto_rawDesc), len(file_validator_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_validator_proto_goTypes,
+		DependencyIndexes: file_validator_proto_depIdxs,
+		MessageInfos:      file_validator_proto_msgTypes,
+	}.Build()
+	File_validator_proto = out.File
+	file_validator_proto_goTypes = nil
+	file_validator_proto_depIdxs = nil
+}
@@ -0,0 +1,266 @@
+// Package validator - server.go serves RandomX share validation over gRPC so
+// Stratum frontends can offload hashing to dedicated machines
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx"
+	"github.com/opensyria/opensy-mining/pool/stratum"
+	pb "github.com/opensyria/opensy-mining/pool/validator/proto/gen"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+// SeedSize is the length of a RandomX seed (a block hash)
+const SeedSize = 32
+
+// ServerConfig holds validator server configuration
+type ServerConfig struct {
+	ListenAddr string
+	// Token must be presented by every pool frontend; required
+	Token    string
+	TLSCert  string // Path to TLS certificate
+	TLSKey   string // Path to TLS private key
+	MaxBatch int    // Largest batch accepted per Validate call
+	// Seeds configures the per-seed RandomX hashers, as on the pool
+	Seeds  stratum.SeedManagerConfig
+	Logger *slog.Logger
+}
+
+// DefaultServerConfig returns default configuration
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		ListenAddr: ":7777",
+		MaxBatch:   1024,
+		Seeds:      stratum.DefaultSeedManagerConfig(),
+		Logger:     slog.Default(),
+	}
+}
+
+// Server validates share batches for pool frontends holding the shared
+// token. It follows the seeds the pool declares with each batch: the current seed is loaded and made
+// current, the next one pre-warmed, and the previous seed stays usable for
+// the grace window. Shares never load a seed; one for any other seed fails
+// with stratum.ErrUnknownSeed.
+type Server struct {
+	pb.UnimplementedValidatorServer
+
+	cfg    ServerConfig
+	logger *slog.Logger
+	seeds  *stratum.SeedManager
+	server *grpc.Server
+	lis    net.Listener
+
+	// Last pre-warmed seed, so each batch does not pre-warm again
+	next   string
+	nextMu sync.Mutex
+
+	// Stats
+	batches atomic.Uint64
+	shares  atomic.Uint64
+	invalid atomic.Uint64
+	failed  atomic.Uint64
+}
+
+// ServerStats is a snapshot of validator activity
+type ServerStats struct {
+	Batches uint64
+	Shares  uint64
+	Invalid uint64 // Hash did not match the claimed result
+	Failed  uint64 // Could not be hashed
+	Mode    string // "light" or "full" for the current seed
+	VMs     randomx.VMPoolStats
+}
+
+// NewServer creates a validator server
+func NewServer(cfg ServerConfig) *Server {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = DefaultServerConfig().MaxBatch
+	}
+	if cfg.Seeds.Logger == nil {
+		cfg.Seeds.Logger = cfg.Logger
+	}
+
+	return &Server{
+		cfg:    cfg,
+		logger: cfg.Logger.With("component", "validator"),
+		seeds:  stratum.NewSeedManager(cfg.Seeds),
+	}
+}
+
+// Start listens and serves in the background
+func (s *Server) Start() error {
+	if s.cfg.Token == "" {
+		return ErrNoToken
+	}
+
+	lis, err := net.Listen("tcp", s.cfg.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+		grpc.UnaryInterceptor(authInterceptor(s.cfg.Token)),
+	}
+	if s.cfg.TLSCert != "" && s.cfg.TLSKey != "" {
+		creds, err := credentials.NewServerTLSFromFile(s.cfg.TLSCert, s.cfg.TLSKey)
+		if err != nil {
+			lis.Close()
+			return fmt.Errorf("failed to load TLS credentials: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+		s.logger.Info("TLS enabled")
+	}
+
+	s.lis = lis
+	s.server = grpc.NewServer(opts...)
+	pb.RegisterValidatorServer(s.server, s)
+
+	s.logger.Info("Starting validator", "addr", lis.Addr())
+	go func() {
+		if err := s.server.Serve(lis); err != nil {
+			s.logger.Error("Validator server error", "err", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the listening address once started
+func (s *Server) Addr() net.Addr {
+	if s.lis == nil {
+		return nil
+	}
+	return s.lis.Addr()
+}
+
+// Stop finishes in-flight batches, then releases the RandomX hashers
+func (s *Server) Stop() {
+	if s.server != nil {
+		s.server.GracefulStop()
+	}
+	s.seeds.Close()
+	s.logger.Info("Validator stopped")
+}
+
+// Stats returns a snapshot of validator activity
+func (s *Server) Stats() ServerStats {
+	return ServerStats{
+		Batches: s.batches.Load(),
+		Shares:  s.shares.Load(),
+		Invalid: s.invalid.Load(),
+		Failed:  s.failed.Load(),
+		Mode:    s.seeds.Mode(),
+		VMs:     s.seeds.VMStats(),
+	}
+}
+
+// Validate hashes every share in the batch in parallel; the seed's VM pool
+// bounds how many run at once
+func (s *Server) Validate(ctx context.Context, req *pb.ValidateRequest) (*pb.ValidateResponse, error) {
+	if len(req.Shares) > s.cfg.MaxBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "batch of %d shares exceeds limit of %d", len(req.Shares), s.cfg.MaxBatch)
+	}
+	if err := s.followSeeds(req.CurrentSeed, req.NextSeed); err != nil {
+		return nil, err
+	}
+	s.batches.Add(1)
+	s.shares.Add(uint64(len(req.Shares)))
+
+	results := make([]*pb.ShareResult, len(req.Shares))
+	var wg sync.WaitGroup
+	for i, share := range req.Shares {
+		wg.Add(1)
+		go func(i int, share *pb.Share) {
+			defer wg.Done()
+			results[i] = s.validate(ctx, share)
+		}(i, share)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	return &pb.ValidateResponse{Results: results}, nil
+}
+
+// followSeeds switches to the pool's current seed, waiting for its context
+// if needed, and pre-warms the next one
+func (s *Server) followSeeds(current, next []byte) error {
+	for _, seed := range [][]byte{current, next} {
+		if len(seed) != 0 && len(seed) != SeedSize {
+			return status.Errorf(codes.InvalidArgument, "seed is %d bytes, want %d", len(seed), SeedSize)
+		}
+	}
+
+	if len(current) != 0 {
+		if seedHash := hex.EncodeToString(current); seedHash != s.seeds.Current() {
+			s.logger.Info("Pool seed changed", "seed", seedHash)
+			if err := s.seeds.SetCurrent(seedHash); err != nil {
+				return status.Errorf(codes.Unavailable, "load seed: %v", err)
+			}
+		}
+	}
+
+	if len(next) != 0 && !bytes.Equal(next, current) {
+		seedHash := hex.EncodeToString(next)
+		s.nextMu.Lock()
+		defer s.nextMu.Unlock()
+		if seedHash != s.next {
+			if err := s.seeds.Prewarm(seedHash); err != nil {
+				s.logger.Warn("Failed to pre-warm next seed", "error", err)
+			} else {
+				s.next = seedHash
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) validate(ctx context.Context, share *pb.Share) *pb.ShareResult {
+	hash, err := s.hashShare(ctx, share)
+	if err != nil {
+		s.failed.Add(1)
+		return &pb.ShareResult{Error: err.Error()}
+	}
+
+	valid := len(share.ClaimedResult) == 0 || bytes.Equal(hash[:], share.ClaimedResult)
+	if !valid {
+		s.invalid.Add(1)
+	}
+	return &pb.ShareResult{Hash: hash[:], Valid: valid}
+}
+
+func (s *Server) hashShare(ctx context.Context, share *pb.Share) ([randomx.HashSize]byte, error) {
+	if len(share.Seed) != SeedSize {
+		return [randomx.HashSize]byte{}, fmt.Errorf("seed is %d bytes, want %d", len(share.Seed), SeedSize)
+	}
+	if len(share.Blob) < stratum.NonceOffset+4 {
+		return [randomx.HashSize]byte{}, fmt.Errorf("blob is %d bytes, need at least %d", len(share.Blob), stratum.NonceOffset+4)
+	}
+
+	input := make([]byte, len(share.Blob))
+	copy(input, share.Blob)
+	binary.LittleEndian.PutUint32(input[stratum.NonceOffset:], share.Nonce)
+
+	return s.seeds.Hash(ctx, hex.EncodeToString(share.Seed), input)
+}
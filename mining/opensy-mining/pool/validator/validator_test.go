@@ -0,0 +1,169 @@
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opensyria/opensy-mining/common/randomx/randomxtest"
+	"github.com/opensyria/opensy-mining/pool/stratum"
+)
+
+const testToken = "pool-secret"
+
+func startServer(t *testing.T) *Server {
+	t.Helper()
+	cfg := DefaultServerConfig()
+	cfg.ListenAddr = "127.0.0.1:0"
+	cfg.Token = testToken
+	cfg.Seeds.Hashers = randomxtest.FakeHasherFactory{}
+	cfg.Seeds.VMs = 2
+	srv := NewServer(cfg)
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return srv
+}
+
+func TestClientValidatesBatches(t *testing.T) {
+	srv := startServer(t)
+	defer srv.Stop()
+
+	cfg := DefaultClientConfig()
+	// The first validator is down; batches must move on to the live one
+	cfg.Addrs = []string{"127.0.0.1:1", srv.Addr().String()}
+	cfg.BatchDelay = 20 * time.Millisecond
+	cfg.RetryBackoff = time.Minute
+	cfg.Token = testToken
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	seed := bytes.Repeat([]byte{0x5e}, SeedSize)
+	client.SetSeeds(seed, nil)
+	blob := make([]byte, 80)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for nonce := uint32(0); nonce < 40; nonce++ {
+		wg.Add(1)
+		go func(nonce uint32) {
+			defer wg.Done()
+			input := append([]byte(nil), blob...)
+			binary.LittleEndian.PutUint32(input[stratum.NonceOffset:], nonce)
+			want := randomxtest.FakeHash(seed, input, 0)
+
+			claimed := want[:]
+			if nonce == 0 {
+				claimed = make([]byte, 32) // Counted invalid, still hashed
+			}
+			got, err := client.VerifyShare(ctx, seed, blob, nonce, claimed)
+			if err != nil {
+				t.Errorf("nonce %d: %v", nonce, err)
+				return
+			}
+			if got != want {
+				t.Errorf("nonce %d: hash %x, want %x", nonce, got, want)
+			}
+		}(nonce)
+	}
+	wg.Wait()
+
+	stats := srv.Stats()
+	if stats.Shares != 40 || stats.Invalid != 1 || stats.Failed != 0 {
+		t.Errorf("stats = %+v, want 40 shares with 1 invalid", stats)
+	}
+	if stats.Batches >= 40 {
+		t.Errorf("%d batches for 40 concurrent shares, want them batched", stats.Batches)
+	}
+
+	// Malformed shares fail individually
+	if _, err := client.VerifyShare(ctx, seed, blob[:40], 1, nil); err == nil {
+		t.Error("short blob validated")
+	}
+	if _, err := client.VerifyShare(ctx, seed[:8], blob, 1, nil); err == nil {
+		t.Error("short seed validated")
+	}
+
+	// A share cannot load a seed the pool did not declare, nor make it current
+	other := bytes.Repeat([]byte{0x01}, SeedSize)
+	if _, err := client.VerifyShare(ctx, other, blob, 1, nil); !errors.Is(err, stratum.ErrUnknownSeed) {
+		t.Errorf("undeclared seed: %v, want ErrUnknownSeed", err)
+	}
+	if current := srv.seeds.Current(); current != hex.EncodeToString(seed) {
+		t.Errorf("current seed %s after an undeclared share, want the pool's", current)
+	}
+
+	srv.Stop()
+	if _, err := client.VerifyShare(ctx, seed, blob, 1, nil); !errors.Is(err, ErrNoValidators) {
+		t.Errorf("with every validator down got %v, want ErrNoValidators", err)
+	}
+}
+
+func TestClientCloseFailsQueuedShares(t *testing.T) {
+	// No batchLoop, so shares stay in the queue until Close
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{queue: make(chan *request, 4), ctx: ctx, cancel: cancel}
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := client.VerifyShare(context.Background(), make([]byte, SeedSize), make([]byte, 80), 0, nil)
+			errs <- err
+		}()
+	}
+	for len(client.queue) < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	client.Close()
+
+	for i := 0; i < 4; i++ {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, ErrClosed) {
+				t.Errorf("share %d: %v, want ErrClosed", i, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("VerifyShare still blocked after Close")
+		}
+	}
+}
+
+func TestServerRequiresToken(t *testing.T) {
+	srv := startServer(t)
+	defer srv.Stop()
+
+	cfg := DefaultClientConfig()
+	cfg.Addrs = []string{srv.Addr().String()}
+	cfg.Token = "guess"
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	// A caller without the token can neither hash nor switch seeds
+	seed := bytes.Repeat([]byte{0x5e}, SeedSize)
+	client.SetSeeds(seed, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := client.VerifyShare(ctx, seed, make([]byte, 80), 1, nil); !errors.Is(err, ErrNoValidators) || !strings.Contains(err.Error(), "Unauthenticated") {
+		t.Errorf("wrong token: %v, want an unauthenticated failure", err)
+	}
+	if current := srv.seeds.Current(); current != "" {
+		t.Errorf("unauthenticated caller set seed %s", current)
+	}
+
+	if err := NewServer(DefaultServerConfig()).Start(); !errors.Is(err, ErrNoToken) {
+		t.Errorf("Start without a token: %v, want ErrNoToken", err)
+	}
+}
@@ -87,6 +87,9 @@ run_pool() {
     NODE_URL="${NODE_URL:-http://localhost:9632}"
     NODE_USER="${NODE_USER:-}"
     NODE_PASS="${NODE_PASS:-}"
+    NODE_COOKIE="${NODE_COOKIE:-}"
+    NETWORK="${NETWORK:-main}"
+    POOL_ADDRESS="${POOL_ADDRESS:-}"
     DB_HOST="${DB_HOST:-localhost}"
     DB_PORT="${DB_PORT:-5432}"
     DB_USER="${DB_USER:-opensy}"
@@ -100,7 +103,8 @@ run_pool() {
     echo -e "${YELLOW}Configuration:${NC}"
     echo "  Stratum:      $STRATUM_ADDR"
     echo "  Metrics:      $METRICS_ADDR"
-    echo "  Node:         $NODE_URL"
+    echo "  Node:         $NODE_URL ($NETWORK)"
+    echo "  Pool address: ${POOL_ADDRESS:-<unset>}"
     echo "  Database:     $DB_USER@$DB_HOST:$DB_PORT/$DB_NAME"
     echo "  Redis:        $REDIS_ADDR"
     echo "  Difficulty:   $INIT_DIFF (min: $MIN_DIFF, max: $MAX_DIFF)"
@@ -112,6 +116,9 @@ run_pool() {
         --node-url="$NODE_URL" \
         --node-user="$NODE_USER" \
         --node-pass="$NODE_PASS" \
+        --node-cookie="$NODE_COOKIE" \
+        --network="$NETWORK" \
+        --pool-address="$POOL_ADDRESS" \
         --db-host="$DB_HOST" \
         --db-port="$DB_PORT" \
         --db-user="$DB_USER" \
@@ -169,7 +176,7 @@ show_help() {
     echo "  $0 start"
     echo ""
     echo "  # Connect XMRig to the pool"
-    echo "  xmrig -o 127.0.0.1:3333 -u <WALLET> -p worker1 -a rx/0"
+    echo "  xmrig -o 127.0.0.1:3333 -u <WALLET> -p worker1 -a rx/opensy"
     echo ""
     echo "  # Check pool stats"
     echo "  curl http://localhost:8080/api/stats"